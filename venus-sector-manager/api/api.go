@@ -5,11 +5,12 @@ import (
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
-	"github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/filecoin-project/venus/venus-shared/actors/builtin"
 	"github.com/filecoin-project/venus/venus-shared/types"
 
+	"github.com/ipfs/go-cid"
+
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
 )
 
@@ -30,6 +31,8 @@ type SealerAPI interface {
 
 	PollPreCommitState(context.Context, abi.SectorID) (PollPreCommitStateResp, error)
 
+	AllocatePersistStore(context.Context, abi.SectorID) (*PersistStoreAllocation, error)
+
 	SubmitPersisted(context.Context, abi.SectorID, string) (bool, error)
 
 	WaitSeed(context.Context, abi.SectorID) (WaitSeedResp, error)
@@ -38,7 +41,7 @@ type SealerAPI interface {
 
 	PollProofState(context.Context, abi.SectorID) (PollProofStateResp, error)
 
-	ListSectors(context.Context, SectorWorkerState) ([]*SectorState, error)
+	ListSectors(context.Context, SectorWorkerState, SectorListQuery) (SectorListPage, error)
 
 	ReportState(context.Context, abi.SectorID, ReportStateReq) (Meta, error)
 
@@ -46,9 +49,71 @@ type SealerAPI interface {
 
 	ReportAborted(context.Context, abi.SectorID, string) (Meta, error)
 
-	CheckProvable(context.Context, abi.RegisteredPoStProof, []storage.SectorRef, bool) (map[abi.SectorNumber]string, error)
+	CheckProvable(context.Context, abi.ActorID, []builtin.ExtendedSectorInfo, bool) (map[abi.SectorNumber]string, error)
 
 	SimulateWdPoSt(context.Context, address.Address, []builtin.ExtendedSectorInfo, abi.PoStRandomness) error
+
+	// snapup
+	AllocateSnapUpSector(context.Context, AllocateSnapUpSpec) (*AllocatedSnapUpSector, error)
+
+	SubmitSnapUpProof(context.Context, abi.SectorID, SnapUpOnChainInfo, bool) (SubmitProofResp, error)
+
+	PollSnapUpProofState(context.Context, abi.SectorID) (PollProofStateResp, error)
+
+	ExtendSectors(context.Context, abi.ActorID, ExtendSectorsSpec) ([]SectorExtensionMessage, error)
+
+	TerminateSectors(context.Context, abi.ActorID, TerminateSectorsSpec) ([]SectorTerminationMessage, error)
+
+	ImportSectors(context.Context, []SectorImportInfo, bool) ([]SectorImportResult, error)
+
+	InspectSectorNumber(context.Context, abi.ActorID, bool) (SectorNumberInfo, error)
+
+	ResetSectorNumber(context.Context, abi.ActorID, uint64) (Meta, error)
+
+	SectorEvents(context.Context, SectorEventFilter) (<-chan SectorEvent, error)
+
+	SectorHistory(context.Context, abi.SectorID) ([]SectorStateRecord, error)
+
+	RestoreSector(context.Context, abi.SectorID) (Meta, error)
+
+	// workers
+	RegisterWorker(context.Context, WorkerInfo) (Meta, error)
+
+	WorkerHeartbeat(context.Context, string, WorkerResources) (Meta, error)
+
+	ListWorkers(context.Context) ([]WorkerStatus, error)
+
+	ListStuckSectors(context.Context) ([]StuckSector, error)
+
+	CheckDeals(context.Context) (DealReconcileReport, error)
+
+	ListPersistStores(context.Context) ([]PersistStoreStatus, error)
+
+	// storage
+	MoveStorage(context.Context, StorageMoveSpec) (StorageMoveJob, error)
+
+	ListStorageMoveJobs(context.Context) ([]StorageMoveJob, error)
+
+	CancelStorageMove(context.Context, string) (Meta, error)
+
+	ListStorageSectors(context.Context, string) (StorageSectors, error)
+
+	ReindexStorage(context.Context, StorageReindexSpec) (StorageReindexReport, error)
+
+	AuditStorage(context.Context, bool) (StorageAuditReport, error)
+
+	CollectStorage(context.Context, bool) (StorageGCReport, error)
+
+	ListStorageGCRecords(context.Context, int) ([]StorageGCRecord, error)
+
+	// unseal
+	UnsealPiece(context.Context, cid.Cid) (UnsealJob, error)
+
+	ListUnsealJobs(context.Context) ([]UnsealJob, error)
+
+	AcquireUnsealJob(context.Context, string) (*UnsealJob, error)
+
+	ReportUnsealProgress(context.Context, string, UnsealProgress) (Meta, error)
 }
 
 type RandomnessAPI interface {
@@ -64,11 +129,92 @@ type MinerInfoAPI interface {
 
 type SectorManager interface {
 	Allocate(context.Context, []abi.ActorID, []abi.RegisteredSealProof) (*AllocatedSector, error)
+	// Acquire & Release keep the in-flight sectors counted when the sectors are moved in or out of the online store
+	Acquire(context.Context, abi.SectorID)
+	Release(context.Context, abi.SectorID)
+}
+
+type SnapUpSectorManager interface {
+	Allocate(context.Context, AllocateSectorSpec) (*SnapUpCandidate, error)
+	Release(context.Context, *SnapUpCandidate) error
+}
+
+type SectorExtensionManager interface {
+	Extend(context.Context, abi.ActorID, ExtendSectorsSpec) ([]SectorExtensionMessage, error)
+}
+
+type SectorTerminationManager interface {
+	Terminate(context.Context, abi.ActorID, TerminateSectorsSpec) ([]SectorTerminationMessage, error)
+}
+
+type SectorImporter interface {
+	Import(context.Context, []SectorImportInfo, bool) ([]SectorImportResult, error)
+}
+
+type SectorEventHub interface {
+	Publish(context.Context, SectorEvent) error
+	Subscribe(context.Context, SectorEventFilter) (<-chan SectorEvent, error)
+}
+
+type WorkerRegistry interface {
+	Register(context.Context, WorkerInfo) error
+	Heartbeat(context.Context, string, WorkerResources) error
+	List(context.Context) ([]WorkerStatus, error)
+}
+
+// PersistStorePlacer chooses the persist stores for the sectors, and keeps the space reserved until they are persisted
+type PersistStorePlacer interface {
+	Allocate(context.Context, abi.SectorID, uint64) (PersistStoreAllocation, error)
+	Release(context.Context, abi.SectorID) error
+	List(context.Context) ([]PersistStoreStatus, error)
+}
+
+// StorageMover runs the jobs moving the sectors between the persist stores
+type StorageMover interface {
+	Start(context.Context, StorageMoveSpec) (StorageMoveJob, error)
+	List(context.Context) ([]StorageMoveJob, error)
+	Cancel(context.Context, string) error
+	// Reindex repairs the index according to the files in the persist stores, it won't run along with the move jobs
+	Reindex(context.Context, StorageReindexSpec) (StorageReindexReport, error)
+}
+
+type StorageAuditor interface {
+	// Audit returns the latest report, a new audit will be run if refresh is set, or no report is available
+	Audit(ctx context.Context, refresh bool) (StorageAuditReport, error)
+}
+
+type StorageCollector interface {
+	// Collect deletes the files of the eligible candidates, nothing will be deleted or recorded in a dry run
+	Collect(ctx context.Context, dryRun bool) (StorageGCReport, error)
+	// Records returns the latest records of the collections, the newest first, limit <= 0 for all
+	Records(ctx context.Context, limit int) ([]StorageGCRecord, error)
+}
+
+type UnsealManager interface {
+	// Locate finds the piece in the unsealed files, nil is returned if the piece is not in any of the finalized sectors.
+	// With unseal set, an unseal job is created if the piece is sealed without an unsealed copy,
+	// and the piece index is rebuilt on a miss; otherwise only the indexed pieces are looked up.
+	Locate(ctx context.Context, c cid.Cid, unseal bool) (*UnsealedPiece, error)
+	// Create returns the active unseal job of the sector holding the piece, a new one is created if there isn't any
+	Create(context.Context, cid.Cid) (UnsealJob, error)
+	// Acquire assigns a pending job to the worker, nil if there is none
+	Acquire(context.Context, string) (*UnsealJob, error)
+	Report(context.Context, string, UnsealProgress) error
+	List(context.Context) ([]UnsealJob, error)
+}
+
+type StuckSectorDetector interface {
+	Check(context.Context) ([]StuckSector, error)
 }
 
 type DealManager interface {
 	Acquire(context.Context, abi.SectorID, *uint) (Deals, error)
 	Release(context.Context, abi.SectorID, Deals) error
+	Reacquire(context.Context, abi.SectorID, Deals) error
+}
+
+type DealReconciler interface {
+	Check(context.Context) (DealReconcileReport, error)
 }
 
 type CommitmentManager interface {
@@ -77,27 +223,52 @@ type CommitmentManager interface {
 
 	SubmitProof(context.Context, abi.SectorID, ProofInfo, bool) (SubmitProofResp, error)
 	ProofState(context.Context, abi.SectorID) (PollProofStateResp, error)
+
+	SubmitReplicaUpdate(context.Context, abi.SectorID, ReplicaUpdateInfo, bool) (SubmitProofResp, error)
+	ReplicaUpdateState(context.Context, abi.SectorID) (PollProofStateResp, error)
 }
 
 type SectorNumberAllocator interface {
 	Next(context.Context, abi.ActorID, uint64, func(uint64) bool) (uint64, bool, error)
+	Advance(context.Context, abi.ActorID, uint64) (uint64, error)
+	Reset(context.Context, abi.ActorID, uint64) error
+	Inspect(context.Context, abi.ActorID, bool) (SectorNumberInfo, error)
 }
 
 type SectorStateManager interface {
 	Init(context.Context, abi.SectorID, abi.RegisteredSealProof) error
 	Load(context.Context, abi.SectorID) (*SectorState, error)
 	Update(context.Context, abi.SectorID, ...interface{}) error
+	UpdateOffline(context.Context, abi.SectorID, ...interface{}) error
 	Finalize(context.Context, abi.SectorID, func(*SectorState) error) error
+	Restore(context.Context, abi.SectorID, func(*SectorState) error) error
+	Import(context.Context, SectorState, bool) (bool, error)
+	AppendHistory(context.Context, abi.SectorID, ReportStateReq) error
+	History(context.Context, abi.SectorID) ([]SectorStateRecord, error)
 	All(ctx context.Context, ws SectorWorkerState) ([]*SectorState, error)
+	List(ctx context.Context, ws SectorWorkerState, query SectorListQuery) (SectorListPage, error)
+	ForEach(ctx context.Context, ws SectorWorkerState, fn func(SectorState) error) error
+	// Numbers returns the numbers of the sectors of the given miner, without decoding the states
+	Numbers(ctx context.Context, ws SectorWorkerState, mid abi.ActorID) ([]abi.SectorNumber, error)
 }
 
-type SectorIndexer interface {
+type SectorTypedIndexer interface {
 	Find(context.Context, abi.SectorID) (string, bool, error)
 	Update(context.Context, abi.SectorID, string) error
+	// ForEach iterates over all the indexed sectors, the indexer should not be updated in the callback
+	ForEach(context.Context, func(abi.SectorID, string) error) error
+	// Sectors returns the sectors indexed to the given instance
+	Sectors(context.Context, string) ([]abi.SectorID, error)
+}
+
+type SectorIndexer interface {
+	SectorTypedIndexer
+	// Upgrade returns the indexer for the files generated by snapup
+	Upgrade() SectorTypedIndexer
 	StoreMgr() objstore.Manager
 }
 
 type SectorTracker interface {
-	Provable(context.Context, abi.RegisteredPoStProof, []storage.SectorRef, bool) (map[abi.SectorNumber]string, error)
+	Provable(context.Context, abi.ActorID, []builtin.ExtendedSectorInfo, bool) (map[abi.SectorNumber]string, error)
 	PubToPrivate(context.Context, abi.ActorID, []builtin.ExtendedSectorInfo) (SortedPrivateSectorInfo, error)
 }
@@ -5,39 +5,106 @@ import (
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
-	"github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/filecoin-project/venus/venus-shared/actors/builtin"
+
+	"github.com/ipfs/go-cid"
 )
 
+// SealerClient is also used as the permissioned proxy on the server side,
+// the perm tags should be kept in sync with the methods of SealerAPI
 type SealerClient struct {
-	AllocateSector func(context.Context, AllocateSectorSpec) (*AllocatedSector, error)
+	AllocateSector func(context.Context, AllocateSectorSpec) (*AllocatedSector, error) `perm:"worker"`
+
+	AcquireDeals func(context.Context, abi.SectorID, AcquireDealsSpec) (Deals, error) `perm:"worker"`
+
+	AssignTicket func(context.Context, abi.SectorID) (Ticket, error) `perm:"worker"`
+
+	SubmitPreCommit func(context.Context, AllocatedSector, PreCommitOnChainInfo, bool) (SubmitPreCommitResp, error) `perm:"worker"`
+
+	PollPreCommitState func(context.Context, abi.SectorID) (PollPreCommitStateResp, error) `perm:"worker"`
+
+	AllocatePersistStore func(context.Context, abi.SectorID) (*PersistStoreAllocation, error) `perm:"worker"`
+
+	SubmitPersisted func(context.Context, abi.SectorID, string) (bool, error) `perm:"worker"`
+
+	WaitSeed func(context.Context, abi.SectorID) (WaitSeedResp, error) `perm:"worker"`
+
+	SubmitProof func(context.Context, abi.SectorID, ProofOnChainInfo, bool) (SubmitProofResp, error) `perm:"worker"`
+
+	PollProofState func(context.Context, abi.SectorID) (PollProofStateResp, error) `perm:"worker"`
+
+	ListSectors func(context.Context, SectorWorkerState, SectorListQuery) (SectorListPage, error) `perm:"read"`
+
+	ReportState func(context.Context, abi.SectorID, ReportStateReq) (Meta, error) `perm:"worker"`
+
+	ReportFinalized func(context.Context, abi.SectorID) (Meta, error) `perm:"worker"`
+
+	ReportAborted func(context.Context, abi.SectorID, string) (Meta, error) `perm:"worker"`
+
+	CheckProvable func(context.Context, abi.ActorID, []builtin.ExtendedSectorInfo, bool) (map[abi.SectorNumber]string, error) `perm:"read"`
+
+	SimulateWdPoSt func(context.Context, address.Address, []builtin.ExtendedSectorInfo, abi.PoStRandomness) error `perm:"admin"`
+
+	// snapup
+	AllocateSnapUpSector func(context.Context, AllocateSnapUpSpec) (*AllocatedSnapUpSector, error) `perm:"worker"`
+
+	SubmitSnapUpProof func(context.Context, abi.SectorID, SnapUpOnChainInfo, bool) (SubmitProofResp, error) `perm:"worker"`
+
+	PollSnapUpProofState func(context.Context, abi.SectorID) (PollProofStateResp, error) `perm:"worker"`
+
+	ExtendSectors func(context.Context, abi.ActorID, ExtendSectorsSpec) ([]SectorExtensionMessage, error) `perm:"sign"`
+
+	TerminateSectors func(context.Context, abi.ActorID, TerminateSectorsSpec) ([]SectorTerminationMessage, error) `perm:"sign"`
+
+	ImportSectors func(context.Context, []SectorImportInfo, bool) ([]SectorImportResult, error) `perm:"admin"`
+
+	InspectSectorNumber func(context.Context, abi.ActorID, bool) (SectorNumberInfo, error) `perm:"read"`
+
+	ResetSectorNumber func(context.Context, abi.ActorID, uint64) (Meta, error) `perm:"admin"`
+
+	SectorEvents func(context.Context, SectorEventFilter) (<-chan SectorEvent, error) `perm:"read"`
+
+	SectorHistory func(context.Context, abi.SectorID) ([]SectorStateRecord, error) `perm:"read"`
+
+	RestoreSector func(context.Context, abi.SectorID) (Meta, error) `perm:"admin"`
+
+	// workers
+	RegisterWorker func(context.Context, WorkerInfo) (Meta, error) `perm:"worker"`
+
+	WorkerHeartbeat func(context.Context, string, WorkerResources) (Meta, error) `perm:"worker"`
+
+	ListWorkers func(context.Context) ([]WorkerStatus, error) `perm:"read"`
+
+	ListStuckSectors func(context.Context) ([]StuckSector, error) `perm:"read"`
 
-	AcquireDeals func(context.Context, abi.SectorID, AcquireDealsSpec) (Deals, error)
+	CheckDeals func(context.Context) (DealReconcileReport, error) `perm:"read"`
 
-	AssignTicket func(context.Context, abi.SectorID) (Ticket, error)
+	ListPersistStores func(context.Context) ([]PersistStoreStatus, error) `perm:"read"`
 
-	SubmitPreCommit func(context.Context, AllocatedSector, PreCommitOnChainInfo, bool) (SubmitPreCommitResp, error)
+	// storage
+	MoveStorage func(context.Context, StorageMoveSpec) (StorageMoveJob, error) `perm:"admin"`
 
-	PollPreCommitState func(context.Context, abi.SectorID) (PollPreCommitStateResp, error)
+	ListStorageMoveJobs func(context.Context) ([]StorageMoveJob, error) `perm:"read"`
 
-	SubmitPersisted func(context.Context, abi.SectorID, string) (bool, error)
+	CancelStorageMove func(context.Context, string) (Meta, error) `perm:"admin"`
 
-	WaitSeed func(context.Context, abi.SectorID) (WaitSeedResp, error)
+	ListStorageSectors func(context.Context, string) (StorageSectors, error) `perm:"read"`
 
-	SubmitProof func(context.Context, abi.SectorID, ProofOnChainInfo, bool) (SubmitProofResp, error)
+	ReindexStorage func(context.Context, StorageReindexSpec) (StorageReindexReport, error) `perm:"admin"`
 
-	PollProofState func(context.Context, abi.SectorID) (PollProofStateResp, error)
+	AuditStorage func(context.Context, bool) (StorageAuditReport, error) `perm:"read"`
 
-	ListSectors func(context.Context, SectorWorkerState) ([]*SectorState, error)
+	CollectStorage func(context.Context, bool) (StorageGCReport, error) `perm:"admin"`
 
-	ReportState func(context.Context, abi.SectorID, ReportStateReq) (Meta, error)
+	ListStorageGCRecords func(context.Context, int) ([]StorageGCRecord, error) `perm:"read"`
 
-	ReportFinalized func(context.Context, abi.SectorID) (Meta, error)
+	// unseal
+	UnsealPiece func(context.Context, cid.Cid) (UnsealJob, error) `perm:"admin"`
 
-	ReportAborted func(context.Context, abi.SectorID, string) (Meta, error)
+	ListUnsealJobs func(context.Context) ([]UnsealJob, error) `perm:"read"`
 
-	CheckProvable func(context.Context, abi.RegisteredPoStProof, []storage.SectorRef, bool) (map[abi.SectorNumber]string, error)
+	AcquireUnsealJob func(context.Context, string) (*UnsealJob, error) `perm:"worker"`
 
-	SimulateWdPoSt func(context.Context, address.Address, []builtin.ExtendedSectorInfo, abi.PoStRandomness) error
+	ReportUnsealProgress func(context.Context, string, UnsealProgress) (Meta, error) `perm:"worker"`
 }
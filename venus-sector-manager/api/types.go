@@ -176,6 +176,13 @@ type ReportStateReq struct {
 	Failure     *SectorFailure
 }
 
+// SectorStateRecord is an entry in the state history of a sector
+type SectorStateRecord struct {
+	// unix timestamp on the sector-manager side
+	Time  int64
+	State ReportStateReq
+}
+
 type WorkerIdentifier struct {
 	Instance string
 	Location string
@@ -197,7 +204,234 @@ type WindowPoStRandomness struct {
 	Rand  abi.Randomness
 }
 
+type AllocateSnapUpSpec struct {
+	Sector AllocateSectorSpec
+	Deals  AcquireDealsSpec
+}
+
+type SectorPublicInfo struct {
+	CommR      [32]byte
+	SealedCID  cid.Cid
+	Activation abi.ChainEpoch
+	Expiration abi.ChainEpoch
+}
+
+type SectorPrivateInfo struct {
+	AccessInstance string
+}
+
+type SnapUpCandidate struct {
+	Sector  AllocatedSector
+	Public  SectorPublicInfo
+	Private SectorPrivateInfo
+}
+
+type AllocatedSnapUpSector struct {
+	Sector  AllocatedSector
+	Pieces  Deals
+	Public  SectorPublicInfo
+	Private SectorPrivateInfo
+}
+
+type SnapUpOnChainInfo struct {
+	CommR          [32]byte
+	CommD          [32]byte
+	AccessInstance string
+	Proof          []byte
+}
+
+func (si SnapUpOnChainInfo) IntoReplicaUpdateInfo() (ReplicaUpdateInfo, error) {
+	commR, err := commcid.ReplicaCommitmentV1ToCID(si.CommR[:])
+	if err != nil {
+		return ReplicaUpdateInfo{}, err
+	}
+
+	commD, err := commcid.DataCommitmentV1ToCID(si.CommD[:])
+	if err != nil {
+		return ReplicaUpdateInfo{}, err
+	}
+
+	return ReplicaUpdateInfo{
+		NewSealedCID:   commR,
+		NewUnsealedCID: commD,
+		Proof:          si.Proof,
+	}, nil
+}
+
+type ReplicaUpdateInfo struct {
+	NewSealedCID   cid.Cid
+	NewUnsealedCID cid.Cid
+	Proof          []byte
+}
+
+type UpgradeMessageInfo struct {
+	UpgradeCid *cid.Cid
+	NeedSend   bool
+}
+
+type ExtendSectorsSpec struct {
+	// sectors expiring within this number of epochs will be extended, 0 for the configured value
+	Within abi.ChainEpoch
+	// target lifetime counted from the current head, 0 for the configured value
+	Extension abi.ChainEpoch
+	DryRun    bool
+}
+
+type SectorExtension struct {
+	Deadline      uint64
+	Partition     uint64
+	Sectors       []abi.SectorNumber
+	NewExpiration abi.ChainEpoch
+}
+
+type SectorExtensionMessage struct {
+	Extensions []SectorExtension
+	MessageID  string
+}
+
+type TerminateSectorsSpec struct {
+	Sectors []abi.SectorNumber
+	// sender of the message, the worker address will be used if not set
+	From   address.Address
+	DryRun bool
+}
+
+type SectorTermination struct {
+	Deadline  uint64
+	Partition uint64
+	Sectors   []abi.SectorNumber
+}
+
+type SectorTerminationMessage struct {
+	Terminations []SectorTermination
+	// estimated by executing the message on the current head
+	Fee abi.TokenAmount
+	// the sector states are marked as terminated once the message lands
+	MessageID string
+}
+
+type TerminateInfo struct {
+	Terminated   bool
+	TerminateCid *cid.Cid
+	TerminatedAt abi.ChainEpoch
+}
+
+// SectorImportInfo describes a sector sealed by another deployment, such as lotus-miner or venus-sealer,
+// the missing fields will be filled with the on-chain info
+type SectorImportInfo struct {
+	ID         abi.SectorID
+	SectorType abi.RegisteredSealProof
+	// name of the persist store instance which holds the sealed & cache files
+	AccessInstance string
+
+	// optional
+	Ticket       *Ticket
+	Seed         *Seed
+	CommD        *cid.Cid
+	PreCommitCid *cid.Cid
+	CommitCid    *cid.Cid
+}
+
+type SectorImportResult struct {
+	ID       abi.SectorID
+	Imported bool
+	Reason   string
+}
+
+type SectorNumberInfo struct {
+	Current     uint64
+	Initialized bool
+	// numbers allocated on chain or used by local sector states
+	Taken    uint64
+	MaxTaken uint64
+}
+
+type SectorEventType string
+
+const (
+	SectorEventAllocated          SectorEventType = "allocated"
+	SectorEventDealsAcquired      SectorEventType = "deals_acquired"
+	SectorEventTicketAssigned     SectorEventType = "ticket_assigned"
+	SectorEventPreCommitSubmitted SectorEventType = "precommit_submitted"
+	SectorEventProofSubmitted     SectorEventType = "proof_submitted"
+	SectorEventStateReported      SectorEventType = "state_reported"
+	SectorEventFinalized          SectorEventType = "finalized"
+	SectorEventAborted            SectorEventType = "aborted"
+	SectorEventRestored           SectorEventType = "restored"
+)
+
+type SectorEvent struct {
+	// assigned by the event hub, increases monotonically
+	Seq  uint64
+	Time int64
+	Type SectorEventType
+	ID   abi.SectorID
+	// only for SectorEventStateReported
+	State *ReportStateReq `json:",omitempty"`
+	// extra info, such as the abort reason or the message id
+	Detail string `json:",omitempty"`
+}
+
+type SectorEventFilter struct {
+	// empty for all miners
+	Miners []abi.ActorID
+	// empty for all event types
+	Types []SectorEventType
+	// only events with a greater seq will be delivered, used for resuming after reconnection
+	Since uint64
+}
+
+func (f SectorEventFilter) Match(evt SectorEvent) bool {
+	if len(f.Miners) > 0 {
+		matched := false
+		for _, mid := range f.Miners {
+			if mid == evt.ID.Miner {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.Types) > 0 {
+		matched := false
+		for _, typ := range f.Types {
+			if typ == evt.Type {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
 type ActorIdent struct {
 	ID   abi.ActorID
 	Addr address.Address
 }
+
+type DealMismatch struct {
+	DealID abi.DealID
+	// nil if the deal is not held by any local sector
+	Sector *abi.SectorID
+	// online, landed or aborted
+	SectorState  string
+	MarketStatus string
+	// status the deal should be in, empty if it could not be decided, such as a deal without sector
+	Expected string
+}
+
+type DealReconcileReport struct {
+	Time int64
+	// number of the deals held by the local sectors
+	Checked    int
+	Mismatches []DealMismatch
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// PersistStoreAllocation is the recommended persist store for a sector, with the space reserved for it
+type PersistStoreAllocation struct {
+	Instance string
+	Reserved uint64
+}
+
+// PersistStoreStatus describes the capacity & the placement options of a persist store
+type PersistStoreStatus struct {
+	Instance string
+	Weight   uint
+	ReadOnly bool
+	Draining bool
+	// the capacity of the non-local stores is unknown, they are never chosen in the placement
+	SpaceUnknown bool
+
+	Total    uint64
+	Free     uint64
+	Reserved uint64
+	// sectors holding the reservations
+	Reservations []abi.SectorID
+}
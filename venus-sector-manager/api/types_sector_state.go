@@ -1,11 +1,17 @@
 package api
 
 import (
+	"time"
+
 	"github.com/filecoin-project/go-state-types/abi"
 )
 
 type Finalized bool
 
+type Upgraded bool
+
+type Imported bool
+
 type SectorState struct {
 	ID         abi.SectorID
 	SectorType abi.RegisteredSealProof
@@ -22,6 +28,19 @@ type SectorState struct {
 	LatestState *ReportStateReq
 	Finalized   Finalized
 	AbortReason string
+	Imported    Imported
+
+	// for snapup
+	Upgraded           Upgraded
+	UpgradePublic      *SectorPublicInfo
+	UpgradedInfo       *ReplicaUpdateInfo
+	UpgradeMessageInfo UpgradeMessageInfo
+
+	// for termination
+	TerminateInfo TerminateInfo
+
+	// unix timestamp of the latest modification, set by the state manager
+	UpdatedAt int64
 }
 
 func (s SectorState) DealIDs() []abi.DealID {
@@ -35,6 +54,135 @@ func (s SectorState) DealIDs() []abi.DealID {
 type SectorWorkerState string
 
 const (
-	WorkerOnline SectorWorkerState = "online"
+	WorkerOnline  SectorWorkerState = "online"
 	WorkerOffline SectorWorkerState = "offline"
 )
+
+// SectorListQuery filters the sector states, the zero values mean no limit
+type SectorListQuery struct {
+	Miner abi.ActorID
+	// inclusive range of the sector numbers
+	MinNumber abi.SectorNumber
+	MaxNumber abi.SectorNumber
+	// the current state reported by the worker
+	State          string
+	WorkerInstance string
+	HasFailure     *bool
+	Aborted        *bool
+	// finalized without being aborted
+	Finalized *bool
+	// unix timestamp
+	UpdatedSince int64
+
+	// cursor returned by the previous page, empty for the first page.
+	// The cursor is opaque, and the sectors are listed in the numeric order of the miner & sector numbers.
+	Cursor string
+	// max number of the sectors in a page
+	Limit int
+}
+
+type SectorListPage struct {
+	Sectors []*SectorState
+	// opaque cursor for the next page, empty if there is no more sectors
+	Cursor string
+}
+
+func (q SectorListQuery) MatchID(sid abi.SectorID) bool {
+	if q.Miner != 0 && sid.Miner != q.Miner {
+		return false
+	}
+
+	if sid.Number < q.MinNumber {
+		return false
+	}
+
+	if q.MaxNumber != 0 && sid.Number > q.MaxNumber {
+		return false
+	}
+
+	return true
+}
+
+func (q SectorListQuery) Match(state *SectorState) bool {
+	if !q.MatchID(state.ID) {
+		return false
+	}
+
+	if q.State != "" && (state.LatestState == nil || state.LatestState.StateChange.Next != q.State) {
+		return false
+	}
+
+	if q.WorkerInstance != "" && (state.LatestState == nil || state.LatestState.Worker.Instance != q.WorkerInstance) {
+		return false
+	}
+
+	if q.HasFailure != nil {
+		failed := state.LatestState != nil && state.LatestState.Failure != nil
+		if failed != *q.HasFailure {
+			return false
+		}
+	}
+
+	aborted := state.AbortReason != ""
+	if q.Aborted != nil && aborted != *q.Aborted {
+		return false
+	}
+
+	if q.Finalized != nil && (bool(state.Finalized) && !aborted) != *q.Finalized {
+		return false
+	}
+
+	if q.UpdatedSince != 0 && state.UpdatedAt < q.UpdatedSince {
+		return false
+	}
+
+	return true
+}
+
+// phases while waiting for the messages, used along with the worker states for the stuck detection
+const (
+	SectorPhaseAllocated         = "Allocated"
+	SectorPhasePreCommitWaitSend = "PreCommitWaitSend"
+	SectorPhasePreCommitPending  = "PreCommitPending"
+	SectorPhaseCommitWaitSend    = "CommitWaitSend"
+	SectorPhaseCommitPending     = "CommitPending"
+)
+
+// Phase returns the message phase if the sector is waiting for one, or the latest worker state
+func (s SectorState) Phase() string {
+	if s.Proof != nil {
+		if s.MessageInfo.CommitCid != nil {
+			return SectorPhaseCommitPending
+		}
+
+		if s.MessageInfo.NeedSend {
+			return SectorPhaseCommitWaitSend
+		}
+	}
+
+	if s.Pre != nil && s.Seed == nil {
+		if s.MessageInfo.PreCommitCid != nil {
+			return SectorPhasePreCommitPending
+		}
+
+		if s.MessageInfo.NeedSend {
+			return SectorPhasePreCommitWaitSend
+		}
+	}
+
+	if s.LatestState == nil || s.LatestState.StateChange.Next == "" {
+		return SectorPhaseAllocated
+	}
+
+	return s.LatestState.StateChange.Next
+}
+
+// StuckSector is an online sector without progress in its phase for longer than the timeout
+type StuckSector struct {
+	ID     abi.SectorID
+	Phase  string
+	Worker WorkerIdentifier
+	// unix timestamp of the latest update
+	Since   int64
+	Timeout time.Duration
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+type StorageMoveSpec struct {
+	From string
+	To   string
+	// 0 for all the miners
+	Miner abi.ActorID
+	// empty for all the sectors of the miner in the source store, only used along with Miner
+	Sectors []abi.SectorNumber
+	// compare the sha256 checksums of the copies, in addition to the sizes
+	Checksum bool
+	// max bytes per second read from the source store, 0 for the configured default
+	RateLimit uint64
+}
+
+type StorageMoveJobState string
+
+const (
+	StorageMoveJobRunning  StorageMoveJobState = "running"
+	StorageMoveJobDone     StorageMoveJobState = "done"
+	StorageMoveJobFailed   StorageMoveJobState = "failed"
+	StorageMoveJobCanceled StorageMoveJobState = "canceled"
+)
+
+type StorageMoveFailure struct {
+	ID      abi.SectorID
+	Upgrade bool
+	Error   string
+}
+
+// StorageMoveJob is a snapshot of the progress of a move job
+type StorageMoveJob struct {
+	ID    string
+	Spec  StorageMoveSpec
+	State StorageMoveJobState
+
+	Total int
+	// sectors copied and indexed in the destination store
+	Moved    int
+	Failures []StorageMoveFailure
+	// sectors whose source files are deleted
+	Cleaned     int
+	BytesCopied uint64
+	Current     *abi.SectorID
+
+	StartedAt  int64
+	FinishedAt int64
+	Error      string
+}
+
+// StorageSectors are the sectors indexed to a persist store
+type StorageSectors struct {
+	Instance string
+	Sealed   []abi.SectorID
+	// sectors whose snapup replicas are in the store
+	Upgraded []abi.SectorID
+}
+
+type StorageReindexSpec struct {
+	// names of the persist stores to be scanned, empty for all
+	Stores []string
+	// only report the differences, without updating the index
+	DryRun bool
+}
+
+type StorageReindexEntry struct {
+	ID      abi.SectorID
+	Upgrade bool
+	// scanned stores holding the sealed (or update) file
+	Instances []string
+	// the indexed instance before reindexing, empty if not indexed
+	Indexed string
+}
+
+type StorageReindexReport struct {
+	DryRun bool
+	// stores scanned successfully
+	Scanned []string
+	// stores failed to be scanned, along with the errors
+	ScanErrors map[string]string
+	// number of the sectors found in the scanned stores
+	Found int
+
+	// not indexed before, indexed to the only store holding the files
+	Added []StorageReindexEntry
+	// indexed to a store without the files, re-indexed to the only store holding the files
+	Fixed []StorageReindexEntry
+	// found in more than one store, the index is left as it is
+	Duplicates []StorageReindexEntry
+	// cache dir found without the sealed file, or the opposite
+	Incomplete []StorageReindexEntry
+	// indexed to a scanned store, but not found in any of the scanned stores
+	Missing []StorageReindexEntry
+}
+
+type StorageAuditIssueKind string
+
+const (
+	// on chain, but the sealed (or update) file is not found in any persist store
+	StorageAuditNoFiles StorageAuditIssueKind = "no-files"
+	// files found in the persist stores, but the sector is neither on chain nor being sealed
+	StorageAuditOrphan StorageAuditIssueKind = "orphan"
+	// on chain, and the files are found, but the sector is not indexed
+	StorageAuditNotIndexed StorageAuditIssueKind = "not-indexed"
+	// on chain, but indexed to a store without the files, while they are found in other stores
+	StorageAuditWrongStore StorageAuditIssueKind = "wrong-store"
+)
+
+const (
+	StorageAuditChainLive       = "live"
+	StorageAuditChainFaulty     = "faulty"
+	StorageAuditChainRecovering = "recovering"
+)
+
+type StorageAuditIssue struct {
+	Kind    StorageAuditIssueKind
+	ID      abi.SectorID
+	Upgrade bool
+	// live, faulty or recovering, empty if not on chain
+	ChainState string
+	// empty if not indexed
+	Indexed string
+	// scanned stores holding the sealed (or update) file
+	Instances []string
+}
+
+type StorageAuditReport struct {
+	Time   int64
+	Epoch  abi.ChainEpoch
+	Miners []abi.ActorID
+	// stores scanned successfully, sectors indexed to the other stores are not checked for the files
+	Scanned    []string
+	ScanErrors map[string]string
+	// number of the live sectors on chain
+	OnChain int
+	Issues  []StorageAuditIssue
+}
+
+const (
+	// aborted during sealing
+	StorageGCReasonAborted = "aborted"
+	// terminated by the termination manager
+	StorageGCReasonTerminated = "terminated"
+	// no longer on chain, expired or terminated by other tools
+	StorageGCReasonRemoved = "removed"
+)
+
+// StorageGCCandidate is a sector whose files are to be collected once the grace period passes
+type StorageGCCandidate struct {
+	ID     abi.SectorID
+	Reason string
+	// unix timestamps
+	FirstSeen  int64
+	EligibleAt int64
+}
+
+// StorageGCRecord is an entry of the audit log of the collections
+type StorageGCRecord struct {
+	Time   int64
+	ID     abi.SectorID
+	Reason string
+	// object paths deleted, grouped by instance
+	Deleted map[string][]string
+	Errors  []string
+}
+
+type StorageGCReport struct {
+	Time   int64
+	DryRun bool
+	// candidates still in the grace period
+	Pending []StorageGCCandidate
+	// sectors collected in this round, or to be collected if it is a dry run
+	Collected []StorageGCRecord
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+type UnsealJobState string
+
+const (
+	UnsealJobPending UnsealJobState = "pending"
+	UnsealJobRunning UnsealJobState = "running"
+	UnsealJobDone    UnsealJobState = "done"
+	UnsealJobFailed  UnsealJobState = "failed"
+)
+
+// UnsealJob asks a worker to unseal the whole sector, using the sealed file & cache dir in the Instance,
+// and to write the unsealed file into the same instance, at util.SectorPath(util.SectorPathTypeUnsealed, Sector).
+// The unsealed file won't be served until the job is reported done.
+type UnsealJob struct {
+	ID         string
+	Sector     abi.SectorID
+	SectorType abi.RegisteredSealProof
+	Ticket     Ticket
+	CommD      cid.Cid
+	CommR      cid.Cid
+	Instance   string
+	// pieces requested by the retrievals
+	Pieces []cid.Cid
+
+	State  UnsealJobState
+	Worker string
+	// 0-100
+	Progress uint
+	Error    string
+	// unix timestamps
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+type UnsealProgress struct {
+	Worker string
+	// 0-100
+	Progress uint
+	Done     bool
+	// set if the job failed
+	Error string
+}
+
+// UnsealedPiece is the location of a piece in the unsealed file of a sector
+type UnsealedPiece struct {
+	Sector abi.SectorID
+	// nil if the unsealed file is not available yet
+	Store objstore.Store
+	Path  string
+	// offset & size of the piece in the unsealed file
+	Offset abi.PaddedPieceSize
+	Size   abi.PaddedPieceSize
+	// size of the data to be served, no larger than Size.Unpadded()
+	DataSize int64
+	// the job producing the unsealed file, set if Store is nil
+	Job *UnsealJob
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// WorkerInfo is provided by the venus-worker instances on registering
+type WorkerInfo struct {
+	// key of the worker in the registry, used in the heartbeats
+	Name string
+	// WorkerIdentifier.Instance in the states reported by the worker, used for matching the in-flight sectors,
+	// the same as Name if empty
+	Instance string
+	Version  string
+	// address for reaching the worker, may be empty
+	Dest string
+
+	Capability WorkerCapability
+}
+
+type WorkerCapability struct {
+	// empty for all miners
+	AllowedMiners []abi.ActorID
+	// empty for all proof types
+	AllowedProofTypes []abi.RegisteredSealProof
+	EnableDeals       bool
+	// names of the processors available, such as pc1, pc2, c2
+	Processors []string
+}
+
+// WorkerResources is carried by each heartbeat
+type WorkerResources struct {
+	Threads int
+	Running int
+	Paused  int
+
+	MemTotal     uint64
+	MemAvailable uint64
+
+	// number of the available bytes in each sealing store
+	StoreAvailable map[string]uint64 `json:",omitempty"`
+}
+
+type WorkerStatus struct {
+	Info      WorkerInfo
+	Resources WorkerResources
+
+	// unix timestamps on the sector-manager side
+	RegisteredAt  int64
+	LastHeartbeat int64
+
+	// no heartbeat received within the offline timeout
+	Offline bool
+
+	// online sectors whose latest state is reported by this worker
+	Sectors []abi.SectorID
+}
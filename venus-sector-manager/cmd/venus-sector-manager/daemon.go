@@ -12,6 +12,8 @@ import (
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/dep"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/confmgr"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/rpcauth"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/tlsutil"
 )
 
 var daemonCmd = &cli.Command{
@@ -66,6 +68,8 @@ var daemonRunCmd = &cli.Command{
 		defer gcancel()
 
 		var node api.SealerAPI
+		var scfg *modules.SafeConfig
+		var signer *rpcauth.Signer
 		stopper, err := dix.New(
 			gctx,
 			internal.DepsFromCLICtx(cctx),
@@ -79,12 +83,22 @@ var daemonRunCmd = &cli.Command{
 				cctx.Bool("miner"),
 				dep.Miner(),
 			),
-			dep.Sealer(&node),
+			dep.Sealer(&node, &scfg, &signer),
 		)
 		if err != nil {
 			return fmt.Errorf("construct sealer api: %w", err)
 		}
 
-		return serveSealerAPI(gctx, stopper, node, cctx.String("listen"))
+		scfg.Lock()
+		tcfg := scfg.Common.TLS.Server
+		scfg.Unlock()
+
+		tlsConf, err := tlsutil.ServerConfig(tcfg.CertFile, tcfg.KeyFile, tcfg.ClientCAFile)
+		if err != nil {
+			stopper(context.Background()) // nolint: errcheck
+			return fmt.Errorf("construct server tls config: %w", err)
+		}
+
+		return serveSealerAPI(gctx, stopper, node, cctx.String("listen"), signer, tlsConf)
 	},
 }
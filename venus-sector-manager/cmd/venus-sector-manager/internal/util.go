@@ -13,6 +13,8 @@ var UtilCmd = &cli.Command{
 		utilMinerCmd,
 		utilSealerCmd,
 		utilMarketCmd,
+		utilAuthCmd,
+		utilStorageCmd,
 	},
 	Before: func(cctx *cli.Context) error {
 		logging.SetupForSub(logSubSystem)
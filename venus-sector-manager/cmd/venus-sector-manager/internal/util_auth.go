@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/rpcauth"
+)
+
+var utilAuthCmd = &cli.Command{
+	Name: "auth",
+	Subcommands: []*cli.Command{
+		utilAuthCreateTokenCmd,
+	},
+}
+
+var utilAuthCreateTokenCmd = &cli.Command{
+	Name:  "create-token",
+	Usage: "Create a token for the sealer rpc, signed with the secret in the home dir",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "perm",
+			Usage: "permission of the token, one of read, worker, sign, admin; each one implies the lower ones",
+			Value: string(rpcauth.PermWorker),
+		},
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "name of the token holder, only for identification",
+		},
+		&cli.DurationFlag{
+			Name:  "expire",
+			Usage: "valid duration of the token, 0 for never expire",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		home, err := HomeFromCLICtx(cctx)
+		if err != nil {
+			return err
+		}
+
+		signer, err := rpcauth.LoadSigner(home.Dir())
+		if err != nil {
+			return fmt.Errorf("load signer, the daemon should be run with Common.API.EnableAuth at least once: %w", err)
+		}
+
+		payload := rpcauth.Payload{
+			Name: cctx.String("name"),
+			Perm: auth.Permission(cctx.String("perm")),
+		}
+
+		if expire := cctx.Duration("expire"); expire > 0 {
+			payload.ExpirationTime = jwt.NumericDate(time.Now().Add(expire))
+		}
+
+		token, err := signer.Sign(payload)
+		if err != nil {
+			return fmt.Errorf("create token: %w", err)
+		}
+
+		fmt.Fprintln(os.Stdout, token)
+		return nil
+	},
+}
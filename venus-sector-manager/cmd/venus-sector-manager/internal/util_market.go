@@ -2,6 +2,9 @@ package internal
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
@@ -14,6 +17,7 @@ var utilMarketCmd = &cli.Command{
 	Flags: []cli.Flag{},
 	Subcommands: []*cli.Command{
 		utilMarketReleaseDealsCmd,
+		utilMarketDealsReportCmd,
 	},
 }
 
@@ -57,3 +61,47 @@ var utilMarketReleaseDealsCmd = &cli.Command{
 		return nil
 	},
 }
+
+var utilMarketDealsReportCmd = &cli.Command{
+	Name:  "deals-report",
+	Usage: "Compare the deals held by the local sectors with the statuses in venus-market",
+	Description: `Mismatches with an expected status will be fixed by the reconciler in the daemon automatically,
+deals assigned in venus-market without any local sector are only reported, and could be released with the release-deals command.`,
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		report, err := cli.CheckDeals(gctx)
+		if err != nil {
+			return RPCCallError("CheckDeals", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "Checked at %s, %d deals held by local sectors, %d mismatches\n", time.Unix(report.Time, 0).Format(time.RFC3339), report.Checked, len(report.Mismatches))
+		if len(report.Mismatches) == 0 {
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "Deal\tSector\tSector State\tMarket Status\tExpected")
+		for _, m := range report.Mismatches {
+			sector, expected := "-", "-"
+			if m.Sector != nil {
+				sector = fmt.Sprintf("m-%d-s-%d", m.Sector.Miner, m.Sector.Number)
+			}
+
+			if m.Expected != "" {
+				expected = m.Expected
+			}
+
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", m.DealID, sector, m.SectorState, m.MarketStatus, expected)
+		}
+
+		return nil
+	},
+}
@@ -5,13 +5,19 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/dtynn/dix"
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
 	"github.com/urfave/cli/v2"
 
+	"github.com/filecoin-project/venus/venus-shared/types"
+
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/dep"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
 )
 
 func extractSealerClient(cctx *cli.Context) (api.SealerClient, context.Context, stopper, error) {
@@ -47,6 +53,8 @@ var utilSealerCmd = &cli.Command{
 		utilSealerSectorsCmd,
 		utilSealerProvingCmd,
 		utilSealerActorCmd,
+		utilSealerWorkersCmd,
+		utilSealerPersistStoresCmd,
 	},
 }
 
@@ -55,7 +63,15 @@ var utilSealerSectorsCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		utilSealerSectorsWorkerStatesCmd,
 		utilSealerSectorsAbortCmd,
+		utilSealerSectorsRestoreCmd,
 		utilSealerSectorsListCmd,
+		utilSealerSectorsExtendCmd,
+		utilSealerSectorsTerminateCmd,
+		utilSealerSectorsImportCmd,
+		utilSealerSectorsNumberCmd,
+		utilSealerSectorsEventsCmd,
+		utilSealerSectorsHistoryCmd,
+		utilSealerSectorsStuckCmd,
 	},
 }
 
@@ -69,11 +85,13 @@ var utilSealerSectorsWorkerStatesCmd = &cli.Command{
 
 		defer stop()
 
-		states, err := cli.ListSectors(gctx, api.WorkerOnline)
+		page, err := cli.ListSectors(gctx, api.WorkerOnline, api.SectorListQuery{})
 		if err != nil {
 			return err
 		}
 
+		states := page.Sectors
+
 		fmt.Fprintf(os.Stdout, "Sectors(%d):\n", len(states))
 		for _, state := range states {
 			fmt.Fprintf(os.Stdout, "m-%d-s-%d:\n", state.ID.Miner, state.ID.Number)
@@ -142,10 +160,37 @@ var utilSealerSectorsAbortCmd = &cli.Command{
 	},
 }
 
-var utilSealerSectorsListCmd = &cli.Command{
-	Name: "list",
-	Usage: "Print sector data in completed state",
+var utilSealerSectorsRestoreCmd = &cli.Command{
+	Name:      "restore",
+	Usage:     "Move a finalized or aborted sector back to the online store",
+	ArgsUsage: "<miner actor id> <sector number>",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually perform the action",
+			Value: false,
+		},
+	},
 	Action: func(cctx *cli.Context) error {
+		if count := cctx.Args().Len(); count < 2 {
+			return fmt.Errorf("both miner actor id & sector number are required, only %d args provided", count)
+		}
+
+		miner, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid miner actor id: %w", err)
+		}
+
+		sectorNum, err := strconv.ParseUint(cctx.Args().Get(1), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid sector number: %w", err)
+		}
+
+		if !cctx.Bool("really-do-it") {
+			fmt.Println("Pass --really-do-it to actually execute this action")
+			return nil
+		}
+
 		cli, gctx, stop, err := extractSealerClient(cctx)
 		if err != nil {
 			return err
@@ -153,12 +198,135 @@ var utilSealerSectorsListCmd = &cli.Command{
 
 		defer stop()
 
-		states, err := cli.ListSectors(gctx, api.WorkerOffline)
+		_, err = cli.RestoreSector(gctx, abi.SectorID{
+			Miner:  abi.ActorID(miner),
+			Number: abi.SectorNumber(sectorNum),
+		})
+		if err != nil {
+			return fmt.Errorf("restore sector failed: %w", err)
+		}
+
+		return nil
+	},
+}
+
+var utilSealerSectorsListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "Print sector data in completed state",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "online",
+			Usage: "list the sectors being sealed, instead of the completed ones",
+		},
+		&cli.Uint64Flag{
+			Name:  "miner",
+			Usage: "only list the sectors of the given miner",
+		},
+		&cli.Uint64Flag{
+			Name:  "min-number",
+			Usage: "only list the sectors whose numbers are not less than the given one",
+		},
+		&cli.Uint64Flag{
+			Name:  "max-number",
+			Usage: "only list the sectors whose numbers are not greater than the given one",
+		},
+		&cli.StringFlag{
+			Name:  "state",
+			Usage: "only list the sectors in the given state reported by the workers",
+		},
+		&cli.StringFlag{
+			Name:  "worker",
+			Usage: "only list the sectors handled by the given worker instance",
+		},
+		&cli.BoolFlag{
+			Name:  "has-failure",
+			Usage: "only list the sectors with (or without, if set to false) a failure reported",
+		},
+		&cli.BoolFlag{
+			Name:  "aborted",
+			Usage: "only list the aborted sectors (or the other ones, if set to false)",
+		},
+		&cli.BoolFlag{
+			Name:  "finalized",
+			Usage: "only list the successfully finalized sectors (or the other ones, if set to false)",
+		},
+		&cli.DurationFlag{
+			Name:  "updated-within",
+			Usage: "only list the sectors updated within the given duration, e.g. 24h",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "number of the sectors fetched in each page",
+			Value: 100,
+		},
+		&cli.StringFlag{
+			Name:  "cursor",
+			Usage: "opaque cursor printed by the previous page, sectors are listed in the order of the miner & sector numbers",
+		},
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "fetch all the pages",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ws := api.WorkerOffline
+		if cctx.Bool("online") {
+			ws = api.WorkerOnline
+		}
+
+		query := api.SectorListQuery{
+			Miner:          abi.ActorID(cctx.Uint64("miner")),
+			MinNumber:      abi.SectorNumber(cctx.Uint64("min-number")),
+			MaxNumber:      abi.SectorNumber(cctx.Uint64("max-number")),
+			State:          cctx.String("state"),
+			WorkerInstance: cctx.String("worker"),
+			Cursor:         cctx.String("cursor"),
+			Limit:          cctx.Int("limit"),
+		}
+
+		for name, target := range map[string]**bool{
+			"has-failure": &query.HasFailure,
+			"aborted":     &query.Aborted,
+			"finalized":   &query.Finalized,
+		} {
+			if cctx.IsSet(name) {
+				val := cctx.Bool(name)
+				*target = &val
+			}
+		}
+
+		if within := cctx.Duration("updated-within"); within > 0 {
+			query.UpdatedSince = time.Now().Add(-within).Unix()
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
 		if err != nil {
 			return err
 		}
 
+		defer stop()
+
+		var states []*api.SectorState
+		for {
+			page, err := cli.ListSectors(gctx, ws, query)
+			if err != nil {
+				return err
+			}
+
+			states = append(states, page.Sectors...)
+			query.Cursor = page.Cursor
+			if query.Cursor == "" || !cctx.Bool("all") {
+				break
+			}
+		}
+
 		fmt.Fprintf(os.Stdout, "Sectors(%d):\n", len(states))
+		defer func() {
+			if query.Cursor != "" && !cctx.Bool("all") {
+				fmt.Fprintf(os.Stdout, "More sectors available, pass --cursor=%s for the next page\n", query.Cursor)
+			}
+		}()
+
 		for _, state := range states {
 			fmt.Fprintf(os.Stdout, "m-%d-s-%d:\n", state.ID.Miner, state.ID.Number)
 			if state.LatestState == nil {
@@ -196,9 +364,505 @@ var utilSealerSectorsListCmd = &cli.Command{
 
 			fmt.Fprintf(os.Stdout, "\tFinalized: %v\n", state.Finalized)
 
+			if state.TerminateInfo.Terminated {
+				fmt.Fprintln(os.Stdout, "\tTerminateInfo:")
+				fmt.Fprintf(os.Stdout, "\t\tMessage: %s\n", state.TerminateInfo.TerminateCid)
+				fmt.Fprintf(os.Stdout, "\t\tHeight: %d\n", state.TerminateInfo.TerminatedAt)
+			}
+
 			fmt.Fprintln(os.Stdout, "")
 		}
 
 		return nil
 	},
 }
+
+var utilSealerSectorsExtendCmd = &cli.Command{
+	Name:      "extend",
+	Usage:     "Extend the expirations of the sectors which are about to expire",
+	ArgsUsage: "<miner actor id>",
+	Flags: []cli.Flag{
+		&cli.Uint64Flag{
+			Name:  "within",
+			Usage: "extend sectors expiring within this number of epochs, use the configured window if not set",
+		},
+		&cli.Uint64Flag{
+			Name:  "extension",
+			Usage: "target lifetime in epochs counted from the current head, use the configured value if not set",
+		},
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually send transaction performing the action",
+			Value: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if count := cctx.Args().Len(); count < 1 {
+			return fmt.Errorf("miner actor id is required")
+		}
+
+		miner, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid miner actor id: %w", err)
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		spec := api.ExtendSectorsSpec{
+			Within:    abi.ChainEpoch(cctx.Uint64("within")),
+			Extension: abi.ChainEpoch(cctx.Uint64("extension")),
+			DryRun:    true,
+		}
+
+		msgs, err := cli.ExtendSectors(gctx, abi.ActorID(miner), spec)
+		if err != nil {
+			return fmt.Errorf("estimate extension: %w", err)
+		}
+
+		printExtensionMessages(msgs)
+		if len(msgs) == 0 {
+			return nil
+		}
+
+		if !cctx.Bool("really-do-it") {
+			fmt.Println("Pass --really-do-it to actually execute this action")
+			return nil
+		}
+
+		spec.DryRun = false
+		msgs, err = cli.ExtendSectors(gctx, abi.ActorID(miner), spec)
+		printExtensionMessages(msgs)
+		if err != nil {
+			return fmt.Errorf("extend sectors: %w", err)
+		}
+
+		return nil
+	},
+}
+
+func printExtensionMessages(msgs []api.SectorExtensionMessage) {
+	fmt.Fprintf(os.Stdout, "Messages(%d):\n", len(msgs))
+	for i, msg := range msgs {
+		msgID := msg.MessageID
+		if msgID == "" {
+			msgID = "NULL"
+		}
+
+		fmt.Fprintf(os.Stdout, "#%d %s:\n", i, msgID)
+		for _, ext := range msg.Extensions {
+			fmt.Fprintf(os.Stdout, "\tDeadline: %d, Partition: %d, NewExpiration: %d, Sectors(%d): %v\n", ext.Deadline, ext.Partition, ext.NewExpiration, len(ext.Sectors), ext.Sectors)
+		}
+	}
+}
+
+var utilSealerSectorsTerminateCmd = &cli.Command{
+	Name:      "terminate",
+	Usage:     "Terminate the given sectors on chain",
+	ArgsUsage: "<miner actor id> <sector number>...",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "specify the address to send the terminate message from, use the worker address if not set",
+		},
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually send transaction performing the action",
+			Value: false,
+		},
+		&cli.BoolFlag{
+			Name:  "wait",
+			Usage: "wait for the messages to land and print the receipts",
+			Value: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if count := cctx.Args().Len(); count < 2 {
+			return fmt.Errorf("both miner actor id & sector numbers are required, only %d args provided", count)
+		}
+
+		miner, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid miner actor id: %w", err)
+		}
+
+		spec := api.TerminateSectorsSpec{
+			DryRun: true,
+		}
+
+		for _, arg := range cctx.Args().Slice()[1:] {
+			num, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid sector number %q: %w", arg, err)
+			}
+
+			spec.Sectors = append(spec.Sectors, abi.SectorNumber(num))
+		}
+
+		if from := cctx.String("from"); from != "" {
+			addr, err := address.NewFromString(from)
+			if err != nil {
+				return fmt.Errorf("invalid from address: %w", err)
+			}
+
+			spec.From = addr
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		msgs, err := cli.TerminateSectors(gctx, abi.ActorID(miner), spec)
+		if err != nil {
+			return fmt.Errorf("estimate termination: %w", err)
+		}
+
+		total := big.Zero()
+		for i, msg := range msgs {
+			total = big.Add(total, msg.Fee)
+			fmt.Fprintf(os.Stdout, "#%d estimated fee: %s\n", i, types.FIL(msg.Fee))
+			for _, term := range msg.Terminations {
+				fmt.Fprintf(os.Stdout, "\tDeadline: %d, Partition: %d, Sectors(%d): %v\n", term.Deadline, term.Partition, len(term.Sectors), term.Sectors)
+			}
+		}
+
+		fmt.Fprintf(os.Stdout, "Estimated termination fee: %s\n", types.FIL(total))
+
+		if !cctx.Bool("really-do-it") {
+			fmt.Println("Pass --really-do-it to actually execute this action")
+			return nil
+		}
+
+		spec.DryRun = false
+		msgs, err = cli.TerminateSectors(gctx, abi.ActorID(miner), spec)
+		var msgIDs []string
+		for i, msg := range msgs {
+			if msg.MessageID == "" {
+				continue
+			}
+
+			msgIDs = append(msgIDs, msg.MessageID)
+			fmt.Fprintf(os.Stdout, "#%d message: %s\n", i, msg.MessageID)
+		}
+
+		if err != nil {
+			return fmt.Errorf("terminate sectors: %w", err)
+		}
+
+		if !cctx.Bool("wait") {
+			fmt.Println("messages pushed, the sectors will be marked as terminated once the messages land")
+			return nil
+		}
+
+		return waitMessages(cctx, msgIDs)
+	},
+}
+
+// waitMessages polls the states of the messages until all of them land on chain or fail, and prints the receipts
+func waitMessages(cctx *cli.Context, msgIDs []string) error {
+	if len(msgIDs) == 0 {
+		return nil
+	}
+
+	mapi, gctx, stop, err := extractAPI(cctx)
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	failed := 0
+	for _, msgID := range msgIDs {
+		fmt.Fprintf(os.Stdout, "waiting for message %s\n", msgID)
+
+	WAIT_RET:
+		for {
+			ret, err := mapi.Messager.GetMessageByUid(gctx, msgID)
+			if err != nil {
+				fmt.Fprintf(os.Stdout, "\tGetMessageByUid: %s\n", err)
+			} else {
+				switch ret.State {
+				case messager.MessageState.OnChainMsg:
+					fmt.Fprintf(os.Stdout, "\tSigned: %s, Height: %d, ExitCode: %s(%d)\n", ret.SignedCid, ret.Height, ret.Receipt.ExitCode, ret.Receipt.ExitCode)
+					if ret.Receipt.ExitCode != 0 {
+						failed++
+					}
+
+					break WAIT_RET
+
+				case messager.MessageState.FailedMsg, messager.MessageState.ReplacedMsg:
+					fmt.Fprintf(os.Stdout, "\tState: %s\n", messager.MessageStateToString(ret.State))
+					failed++
+					break WAIT_RET
+				}
+			}
+
+			select {
+			case <-gctx.Done():
+				return gctx.Err()
+
+			case <-time.After(30 * time.Second):
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages failed", failed, len(msgIDs))
+	}
+
+	fmt.Println("messages landed, the sectors will be marked as terminated once the confidence is reached")
+	return nil
+}
+
+var utilSealerSectorsNumberCmd = &cli.Command{
+	Name:  "number",
+	Usage: "Manage the sector number counters",
+	Subcommands: []*cli.Command{
+		utilSealerSectorsNumberInspectCmd,
+		utilSealerSectorsNumberResetCmd,
+	},
+}
+
+var utilSealerSectorsNumberInspectCmd = &cli.Command{
+	Name:      "inspect",
+	Usage:     "Print the sector number counter of the miner",
+	ArgsUsage: "<miner actor id>",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "refresh",
+			Usage: "reload the allocated sectors on chain and the local sector states",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if count := cctx.Args().Len(); count < 1 {
+			return fmt.Errorf("miner actor id is required")
+		}
+
+		miner, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid miner actor id: %w", err)
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		info, err := cli.InspectSectorNumber(gctx, abi.ActorID(miner), cctx.Bool("refresh"))
+		if err != nil {
+			return fmt.Errorf("inspect sector number: %w", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "Current: %d\n", info.Current)
+		fmt.Fprintf(os.Stdout, "Initialized: %v\n", info.Initialized)
+		fmt.Fprintf(os.Stdout, "Taken: %d\n", info.Taken)
+		fmt.Fprintf(os.Stdout, "MaxTaken: %d\n", info.MaxTaken)
+		if info.Current < info.MaxTaken {
+			fmt.Fprintf(os.Stdout, "the counter is behind, taken numbers will be skipped during allocation\n")
+		}
+
+		return nil
+	},
+}
+
+var utilSealerSectorsNumberResetCmd = &cli.Command{
+	Name:      "reset",
+	Usage:     "Set the sector number counter of the miner",
+	ArgsUsage: "<miner actor id> <number>",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "really-do-it",
+			Usage: "Actually perform the action",
+			Value: false,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if count := cctx.Args().Len(); count < 2 {
+			return fmt.Errorf("both miner actor id & number are required, only %d args provided", count)
+		}
+
+		miner, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid miner actor id: %w", err)
+		}
+
+		num, err := strconv.ParseUint(cctx.Args().Get(1), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number: %w", err)
+		}
+
+		if !cctx.Bool("really-do-it") {
+			fmt.Println("Pass --really-do-it to actually execute this action")
+			return nil
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		if _, err := cli.ResetSectorNumber(gctx, abi.ActorID(miner), num); err != nil {
+			return fmt.Errorf("reset sector number: %w", err)
+		}
+
+		return nil
+	},
+}
+
+var utilSealerSectorsEventsCmd = &cli.Command{
+	Name:  "events",
+	Usage: "Follow the sector events",
+	Flags: []cli.Flag{
+		&cli.Int64SliceFlag{
+			Name:  "miner",
+			Usage: "only print events of the given miners",
+		},
+		&cli.StringSliceFlag{
+			Name:  "type",
+			Usage: "only print events of the given types, e.g. allocated, state_reported, aborted",
+		},
+		&cli.Uint64Flag{
+			Name:  "since",
+			Usage: "replay the stored events after the given seq",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		filter := api.SectorEventFilter{
+			Since: cctx.Uint64("since"),
+		}
+
+		for _, mid := range cctx.Int64Slice("miner") {
+			filter.Miners = append(filter.Miners, abi.ActorID(mid))
+		}
+
+		for _, typ := range cctx.StringSlice("type") {
+			filter.Types = append(filter.Types, api.SectorEventType(typ))
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		events, err := cli.SectorEvents(gctx, filter)
+		if err != nil {
+			return fmt.Errorf("subscribe sector events: %w", err)
+		}
+
+		for evt := range events {
+			fmt.Fprintf(os.Stdout, "#%d\t%s\tm-%d-s-%d\t%s", evt.Seq, time.Unix(evt.Time, 0).Format(time.RFC3339), evt.ID.Miner, evt.ID.Number, evt.Type)
+			if evt.State != nil {
+				fmt.Fprintf(os.Stdout, "\t%s => %s (%s)", evt.State.StateChange.Prev, evt.State.StateChange.Next, evt.State.StateChange.Event)
+				if evt.State.Failure != nil {
+					fmt.Fprintf(os.Stdout, "\t%s: %s", evt.State.Failure.Level, evt.State.Failure.Desc)
+				}
+			}
+
+			if evt.Detail != "" {
+				fmt.Fprintf(os.Stdout, "\t%s", evt.Detail)
+			}
+
+			fmt.Fprintln(os.Stdout)
+		}
+
+		return nil
+	},
+}
+
+var utilSealerSectorsHistoryCmd = &cli.Command{
+	Name:      "history",
+	Usage:     "Print the reported state history of the sector",
+	ArgsUsage: "<miner actor id> <sector number>",
+	Action: func(cctx *cli.Context) error {
+		if count := cctx.Args().Len(); count < 2 {
+			return fmt.Errorf("both miner actor id & sector number are required, only %d args provided", count)
+		}
+
+		miner, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid miner actor id: %w", err)
+		}
+
+		sectorNum, err := strconv.ParseUint(cctx.Args().Get(1), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid sector number: %w", err)
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		records, err := cli.SectorHistory(gctx, abi.SectorID{
+			Miner:  abi.ActorID(miner),
+			Number: abi.SectorNumber(sectorNum),
+		})
+		if err != nil {
+			return fmt.Errorf("get sector history: %w", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "m-%d-s-%d, %d records:\n", miner, sectorNum, len(records))
+		var prev int64
+		for _, record := range records {
+			elapsed := ""
+			if prev != 0 {
+				elapsed = fmt.Sprintf(" (+%s)", time.Duration(record.Time-prev)*time.Second)
+			}
+
+			prev = record.Time
+			fmt.Fprintf(os.Stdout, "%s%s\n", time.Unix(record.Time, 0).Format(time.RFC3339), elapsed)
+			fmt.Fprintf(os.Stdout, "\tWorker: %s @ %s\n", record.State.Worker.Instance, record.State.Worker.Location)
+			fmt.Fprintf(os.Stdout, "\tState: %s => %s (%s)\n", record.State.StateChange.Prev, record.State.StateChange.Next, record.State.StateChange.Event)
+			if record.State.Failure != nil {
+				fmt.Fprintf(os.Stdout, "\tFailure: %s: %s\n", record.State.Failure.Level, record.State.Failure.Desc)
+			}
+		}
+
+		return nil
+	},
+}
+
+var utilSealerSectorsStuckCmd = &cli.Command{
+	Name:  "stuck",
+	Usage: "Print the online sectors exceeding the timeout of their phases",
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		stuck, err := cli.ListStuckSectors(gctx)
+		if err != nil {
+			return fmt.Errorf("list stuck sectors: %w", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "Stuck sectors(%d):\n", len(stuck))
+		now := time.Now()
+		for _, item := range stuck {
+			since := time.Unix(item.Since, 0)
+			fmt.Fprintf(os.Stdout, "m-%d-s-%d:\n", item.ID.Miner, item.ID.Number)
+			fmt.Fprintf(os.Stdout, "\tPhase: %s\n", item.Phase)
+			fmt.Fprintf(os.Stdout, "\tWorker: %s @ %s\n", item.Worker.Instance, item.Worker.Location)
+			fmt.Fprintf(os.Stdout, "\tSince: %s (%s ago, timeout %s)\n", since.Format(time.RFC3339), now.Sub(since).Truncate(time.Second), item.Timeout)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,275 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+)
+
+const importBatchSize = 256
+
+var utilSealerSectorsImportCmd = &cli.Command{
+	Name:      "import",
+	Usage:     "Import sectors sealed by lotus-miner / venus-sealer",
+	ArgsUsage: "<exported file>",
+	Description: `The exported file could be a json array of SectorImportInfo, a csv file with a header line,
+or a lotus-miner / venus-sealer datastore dump.
+Supported csv columns: miner, number, instance, seal_proof, comm_d, ticket_epoch, ticket, seed_epoch, seed, precommit_cid, commit_cid.
+The datastore dump is the output of 'lotus-shed datastore list --repo-type=miner --get-enc=hex metadata /sectors',
+it should be imported with the format set to 'lotus' and the miner actor id given, only the proved sectors are imported.
+The sealed & cache files should already be placed in the persist store instance, and will be checked before importing.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "format of the exported file, json, csv or lotus, guessed from the file extension if not set",
+		},
+		&cli.Uint64Flag{
+			Name:  "miner",
+			Usage: "miner actor id of the sectors in the datastore dump",
+		},
+		&cli.StringFlag{
+			Name:  "instance",
+			Usage: "name of the persist store instance, used for the sectors without one in the exported file",
+		},
+		&cli.BoolFlag{
+			Name:  "override",
+			Usage: "override the existing sector states in the offline store",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() < 1 {
+			return fmt.Errorf("exported file is required")
+		}
+
+		fpath := cctx.Args().First()
+		format := cctx.String("format")
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fpath)), ".")
+		}
+
+		f, err := os.Open(fpath)
+		if err != nil {
+			return fmt.Errorf("open exported file: %w", err)
+		}
+
+		defer f.Close()
+
+		var infos []api.SectorImportInfo
+		switch format {
+		case "json":
+			if err := json.NewDecoder(f).Decode(&infos); err != nil {
+				return fmt.Errorf("decode json: %w", err)
+			}
+
+		case "csv":
+			infos, err = readSectorImportCSV(f)
+			if err != nil {
+				return fmt.Errorf("decode csv: %w", err)
+			}
+
+		case "lotus":
+			if !cctx.IsSet("miner") {
+				return fmt.Errorf("miner actor id is required for the datastore dump")
+			}
+
+			var skipped map[abi.SectorNumber]string
+			infos, skipped, err = readSectorImportLotusDump(f, abi.ActorID(cctx.Uint64("miner")))
+			if err != nil {
+				return fmt.Errorf("decode datastore dump: %w", err)
+			}
+
+			for num, state := range skipped {
+				fmt.Fprintf(os.Stdout, "s-%d: skipped in state %s\n", num, state)
+			}
+
+		default:
+			return fmt.Errorf("unsupported format %q", format)
+		}
+
+		if ins := cctx.String("instance"); ins != "" {
+			for i := range infos {
+				if infos[i].AccessInstance == "" {
+					infos[i].AccessInstance = ins
+				}
+			}
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		imported := 0
+		for start := 0; start < len(infos); start += importBatchSize {
+			end := start + importBatchSize
+			if end > len(infos) {
+				end = len(infos)
+			}
+
+			results, err := cli.ImportSectors(gctx, infos[start:end], cctx.Bool("override"))
+			if err != nil {
+				return fmt.Errorf("import sectors: %w", err)
+			}
+
+			for _, res := range results {
+				if res.Imported {
+					imported++
+					continue
+				}
+
+				fmt.Fprintf(os.Stdout, "m-%d-s-%d: %s\n", res.ID.Miner, res.ID.Number, res.Reason)
+			}
+		}
+
+		fmt.Fprintf(os.Stdout, "%d/%d sectors imported\n", imported, len(infos))
+		return nil
+	},
+}
+
+func readSectorImportCSV(r io.Reader) ([]api.SectorImportInfo, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"miner", "number"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("column %s is required", required)
+		}
+	}
+
+	var infos []api.SectorImportInfo
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("read line %d: %w", line, err)
+		}
+
+		field := func(name string) string {
+			idx, ok := cols[name]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+
+			return strings.TrimSpace(record[idx])
+		}
+
+		info, err := parseSectorImportRecord(field)
+		if err != nil {
+			return nil, fmt.Errorf("parse line %d: %w", line, err)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+func parseSectorImportRecord(field func(string) string) (api.SectorImportInfo, error) {
+	var info api.SectorImportInfo
+
+	miner, err := strconv.ParseUint(field("miner"), 10, 64)
+	if err != nil {
+		return info, fmt.Errorf("invalid miner actor id: %w", err)
+	}
+
+	num, err := strconv.ParseUint(field("number"), 10, 64)
+	if err != nil {
+		return info, fmt.Errorf("invalid sector number: %w", err)
+	}
+
+	info.ID = abi.SectorID{Miner: abi.ActorID(miner), Number: abi.SectorNumber(num)}
+	info.AccessInstance = field("instance")
+
+	if s := field("seal_proof"); s != "" {
+		proof, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return info, fmt.Errorf("invalid seal proof type: %w", err)
+		}
+
+		info.SectorType = abi.RegisteredSealProof(proof)
+	}
+
+	cids := []struct {
+		name   string
+		target **cid.Cid
+	}{
+		{"comm_d", &info.CommD},
+		{"precommit_cid", &info.PreCommitCid},
+		{"commit_cid", &info.CommitCid},
+	}
+
+	for _, c := range cids {
+		s := field(c.name)
+		if s == "" {
+			continue
+		}
+
+		parsed, err := cid.Decode(s)
+		if err != nil {
+			return info, fmt.Errorf("invalid %s: %w", c.name, err)
+		}
+
+		*c.target = &parsed
+	}
+
+	if s := field("ticket"); s != "" {
+		epoch, value, err := parseRandomnessColumns(field("ticket_epoch"), s)
+		if err != nil {
+			return info, fmt.Errorf("invalid ticket: %w", err)
+		}
+
+		info.Ticket = &api.Ticket{Ticket: value, Epoch: epoch}
+	}
+
+	if s := field("seed"); s != "" {
+		epoch, value, err := parseRandomnessColumns(field("seed_epoch"), s)
+		if err != nil {
+			return info, fmt.Errorf("invalid seed: %w", err)
+		}
+
+		info.Seed = &api.Seed{Seed: value, Epoch: epoch}
+	}
+
+	return info, nil
+}
+
+func parseRandomnessColumns(epochStr, valueStr string) (abi.ChainEpoch, abi.Randomness, error) {
+	epoch, err := strconv.ParseInt(epochStr, 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid epoch: %w", err)
+	}
+
+	value, err := hex.DecodeString(valueStr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid hex value: %w", err)
+	}
+
+	return abi.ChainEpoch(epoch), value, nil
+}
@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+)
+
+// lotus sector states in which the sector has been proved on chain, the others are skipped
+var lotusProvedStates = map[string]struct{}{
+	"Proving":               {},
+	"Available":             {},
+	"FinalizeSector":        {},
+	"UpdateActivating":      {},
+	"ReleaseSectorKey":      {},
+	"FinalizeReplicaUpdate": {},
+}
+
+// lotusSectorInfo holds the fields we need from the SectorInfo of lotus-miner / venus-sealer
+type lotusSectorInfo struct {
+	State            string
+	SectorNumber     abi.SectorNumber
+	SectorType       abi.RegisteredSealProof
+	TicketValue      []byte
+	TicketEpoch      abi.ChainEpoch
+	SeedValue        []byte
+	SeedEpoch        abi.ChainEpoch
+	CommD            *cid.Cid
+	PreCommitMessage *cid.Cid
+	CommitMessage    *cid.Cid
+}
+
+// readSectorImportLotusDump reads the output of
+// `lotus-shed datastore list --repo-type=miner --get-enc=hex metadata /sectors`,
+// in which every key line is followed by a tab-indented line of the hex encoded cbor value.
+// The sectors not yet proved are skipped, and reported with their states.
+func readSectorImportLotusDump(r io.Reader, miner abi.ActorID) ([]api.SectorImportInfo, map[abi.SectorNumber]string, error) {
+	scanner := bufio.NewScanner(r)
+	// the values may be large for the sectors with many pieces or logs
+	scanner.Buffer(make([]byte, 0, 1<<20), 64<<20)
+
+	var infos []api.SectorImportInfo
+	skipped := map[abi.SectorNumber]string{}
+
+	key := ""
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(text, "\t") {
+			key = strings.TrimSpace(text)
+			continue
+		}
+
+		if key == "" {
+			return nil, nil, fmt.Errorf("line %d: value without a key", line)
+		}
+
+		b, err := hex.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: decode hex value of %s: %w", line, key, err)
+		}
+
+		sinfo, err := decodeLotusSectorInfo(b)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: decode sector info of %s: %w", line, key, err)
+		}
+
+		key = ""
+
+		if _, ok := lotusProvedStates[sinfo.State]; !ok {
+			skipped[sinfo.SectorNumber] = sinfo.State
+			continue
+		}
+
+		info := api.SectorImportInfo{
+			ID:           abi.SectorID{Miner: miner, Number: sinfo.SectorNumber},
+			SectorType:   sinfo.SectorType,
+			CommD:        sinfo.CommD,
+			PreCommitCid: sinfo.PreCommitMessage,
+			CommitCid:    sinfo.CommitMessage,
+		}
+
+		if len(sinfo.TicketValue) > 0 {
+			info.Ticket = &api.Ticket{Ticket: sinfo.TicketValue, Epoch: sinfo.TicketEpoch}
+		}
+
+		if len(sinfo.SeedValue) > 0 {
+			info.Seed = &api.Seed{Seed: sinfo.SeedValue, Epoch: sinfo.SeedEpoch}
+		}
+
+		infos = append(infos, info)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return infos, skipped, nil
+}
+
+// decodeLotusSectorInfo walks through the cbor map of the SectorInfo, the fields not needed are skipped,
+// so that the differences between the versions of lotus-miner and venus-sealer don't matter.
+func decodeLotusSectorInfo(b []byte) (lotusSectorInfo, error) {
+	var info lotusSectorInfo
+	br := bufio.NewReader(bytes.NewReader(b))
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return info, err
+	}
+
+	if maj != cbg.MajMap {
+		return info, fmt.Errorf("expected a cbor map")
+	}
+
+	for i := uint64(0); i < extra; i++ {
+		name, err := cbg.ReadStringBuf(br, scratch)
+		if err != nil {
+			return info, fmt.Errorf("read field name: %w", err)
+		}
+
+		switch name {
+		case "State":
+			info.State, err = cbg.ReadStringBuf(br, scratch)
+
+		case "SectorNumber":
+			var n int64
+			n, err = readCborInt(br, scratch)
+			info.SectorNumber = abi.SectorNumber(n)
+
+		case "SectorType":
+			var n int64
+			n, err = readCborInt(br, scratch)
+			info.SectorType = abi.RegisteredSealProof(n)
+
+		case "TicketValue":
+			info.TicketValue, err = cbg.ReadByteArray(br, cbg.ByteArrayMaxLen)
+
+		case "TicketEpoch":
+			var n int64
+			n, err = readCborInt(br, scratch)
+			info.TicketEpoch = abi.ChainEpoch(n)
+
+		case "SeedValue":
+			info.SeedValue, err = cbg.ReadByteArray(br, cbg.ByteArrayMaxLen)
+
+		case "SeedEpoch":
+			var n int64
+			n, err = readCborInt(br, scratch)
+			info.SeedEpoch = abi.ChainEpoch(n)
+
+		case "CommD":
+			info.CommD, err = readCborOptionalCid(br)
+
+		case "PreCommitMessage":
+			info.PreCommitMessage, err = readCborOptionalCid(br)
+
+		case "CommitMessage":
+			info.CommitMessage, err = readCborOptionalCid(br)
+
+		default:
+			var skip cbg.Deferred
+			err = skip.UnmarshalCBOR(br)
+		}
+
+		if err != nil {
+			return info, fmt.Errorf("read field %s: %w", name, err)
+		}
+	}
+
+	return info, nil
+}
+
+func readCborInt(br *bufio.Reader, scratch []byte) (int64, error) {
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return 0, err
+	}
+
+	switch maj {
+	case cbg.MajUnsignedInt:
+		return int64(extra), nil
+
+	case cbg.MajNegativeInt:
+		return -1 - int64(extra), nil
+
+	default:
+		return 0, fmt.Errorf("expected an integer, got major type %d", maj)
+	}
+}
+
+func readCborOptionalCid(br *bufio.Reader) (*cid.Cid, error) {
+	b, err := br.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if b[0] == cbg.CborNull[0] {
+		_, err := br.ReadByte()
+		return nil, err
+	}
+
+	c, err := cbg.ReadCid(br)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/go-units"
+	"github.com/urfave/cli/v2"
+)
+
+var utilSealerPersistStoresCmd = &cli.Command{
+	Name:  "persist-stores",
+	Usage: "List the persist stores, with their capacities and reservations, the capacities of the non-local ones are unknown",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "sectors",
+			Usage: "print the sectors holding the reservations",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		stores, err := cli.ListPersistStores(gctx)
+		if err != nil {
+			return RPCCallError("ListPersistStores", err)
+		}
+
+		showSectors := cctx.Bool("sectors")
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "Instance\tWeight\tFlags\tTotal\tFree\tReserved\tAvailable\tReservations")
+		for _, st := range stores {
+			var flags []string
+			if st.ReadOnly {
+				flags = append(flags, "readonly")
+			}
+
+			if st.Draining {
+				flags = append(flags, "draining")
+			}
+
+			flagStr := "-"
+			if len(flags) > 0 {
+				flagStr = strings.Join(flags, ",")
+			}
+
+			weight := st.Weight
+			if weight == 0 {
+				weight = 1
+			}
+
+			if st.SpaceUnknown {
+				_, _ = fmt.Fprintf(tw, "%s\t-\tremote\tunknown\tunknown\t-\t-\t-\n", st.Instance)
+				continue
+			}
+
+			var available uint64
+			if st.Free > st.Reserved {
+				available = st.Free - st.Reserved
+			}
+
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%d\n",
+				st.Instance, weight, flagStr,
+				units.BytesSize(float64(st.Total)), units.BytesSize(float64(st.Free)),
+				units.BytesSize(float64(st.Reserved)), units.BytesSize(float64(available)),
+				len(st.Reservations),
+			)
+
+			if showSectors {
+				for _, sid := range st.Reservations {
+					_, _ = fmt.Fprintf(tw, "\tm-%d-s-%d\t\t\t\t\t\t\n", sid.Miner, sid.Number)
+				}
+			}
+		}
+
+		return nil
+	},
+}
@@ -15,7 +15,6 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
-	"github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/filecoin-project/venus/pkg/chain"
 	"github.com/filecoin-project/venus/venus-shared/actors/builtin"
@@ -466,11 +465,6 @@ var utilSealerProvingCheckProvableCmd = &cli.Command{
 			return err
 		}
 
-		info, err := api.Chain.StateMinerInfo(ctx, maddr, types.EmptyTSK)
-		if err != nil {
-			return err
-		}
-
 		partitions, err := api.Chain.StateMinerPartitions(ctx, maddr, dlIdx, types.EmptyTSK)
 		if err != nil {
 			return err
@@ -494,7 +488,7 @@ var utilSealerProvingCheckProvableCmd = &cli.Command{
 				return err
 			}
 
-			var tocheck []storage.SectorRef
+			var tocheck []builtin.ExtendedSectorInfo
 			for _, info := range sectorInfos {
 				si := abi.SectorID{
 					Miner:  abi.ActorID(mid),
@@ -508,13 +502,15 @@ var utilSealerProvingCheckProvableCmd = &cli.Command{
 				}
 
 				sectors[info.SectorNumber] = struct{}{}
-				tocheck = append(tocheck, storage.SectorRef{
-					ProofType: info.SealProof,
-					ID:        si,
+				tocheck = append(tocheck, builtin.ExtendedSectorInfo{
+					SealProof:    info.SealProof,
+					SectorNumber: info.SectorNumber,
+					SealedCID:    info.SealedCID,
+					SectorKey:    info.SectorKeyCID,
 				})
 			}
 
-			bad, err := sapi.CheckProvable(sctx, info.WindowPoStProofType, tocheck, cctx.Bool("slow"))
+			bad, err := sapi.CheckProvable(sctx, abi.ActorID(mid), tocheck, cctx.Bool("slow"))
 			if err != nil {
 				return err
 			}
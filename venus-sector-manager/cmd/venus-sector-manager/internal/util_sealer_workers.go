@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/urfave/cli/v2"
+)
+
+var utilSealerWorkersCmd = &cli.Command{
+	Name:  "workers",
+	Usage: "List the registered workers, with their latest heartbeats and in-flight sectors",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "offline",
+			Usage: "only show the workers missing heartbeats",
+		},
+		&cli.BoolFlag{
+			Name:  "sectors",
+			Usage: "print the in-flight sectors of each worker",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		workers, err := cli.ListWorkers(gctx)
+		if err != nil {
+			return RPCCallError("ListWorkers", err)
+		}
+
+		onlyOffline := cctx.Bool("offline")
+		showSectors := cctx.Bool("sectors")
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "Name\tVersion\tStatus\tLast Heartbeat\tThreads\tRunning\tPaused\tMem Available\tSectors")
+		for _, w := range workers {
+			if onlyOffline && !w.Offline {
+				continue
+			}
+
+			status := "online"
+			if w.Offline {
+				status = "offline"
+			}
+
+			last := "-"
+			if w.LastHeartbeat > 0 {
+				last = time.Unix(w.LastHeartbeat, 0).Format(time.RFC3339)
+			}
+
+			mem := "-"
+			if w.Resources.MemTotal > 0 {
+				mem = fmt.Sprintf("%s/%s", units.BytesSize(float64(w.Resources.MemAvailable)), units.BytesSize(float64(w.Resources.MemTotal)))
+			}
+
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%d\n",
+				w.Info.Name, w.Info.Version, status, last,
+				w.Resources.Threads, w.Resources.Running, w.Resources.Paused,
+				mem, len(w.Sectors),
+			)
+
+			if showSectors {
+				for _, sid := range w.Sectors {
+					_, _ = fmt.Fprintf(tw, "\tm-%d-s-%d\t\t\t\t\t\t\t\n", sid.Miner, sid.Number)
+				}
+			}
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,685 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+)
+
+var utilStorageCmd = &cli.Command{
+	Name:  "storage",
+	Usage: "Manage the sector files in the persist stores",
+	Flags: []cli.Flag{
+		SealerListenFlag,
+	},
+	Subcommands: []*cli.Command{
+		utilStorageMoveCmd,
+		utilStorageJobsCmd,
+		utilStorageCancelCmd,
+		utilStorageSectorsCmd,
+		utilStorageReindexCmd,
+		utilStorageAuditCmd,
+		utilStorageGCCmd,
+		utilStorageGCLogCmd,
+		utilStorageUnsealCmd,
+		utilStorageUnsealJobsCmd,
+	},
+}
+
+var utilStorageMoveCmd = &cli.Command{
+	Name:  "move",
+	Usage: "Move the sealed & cache files of sectors from one persist store to another, and update the index",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "from",
+			Usage:    "name of the source persist store",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "to",
+			Usage:    "name of the destination persist store",
+			Required: true,
+		},
+		&cli.Uint64Flag{
+			Name:  "miner",
+			Usage: "only move the sectors of the given miner",
+		},
+		&cli.Int64SliceFlag{
+			Name:  "sectors",
+			Usage: "only move the given sectors of the miner",
+		},
+		&cli.BoolFlag{
+			Name:  "checksum",
+			Usage: "verify the sha256 checksums of the copies, in addition to the sizes",
+		},
+		&cli.StringFlag{
+			Name:  "rate-limit",
+			Usage: "max bytes per second read from the source store, e.g. 200MiB, use the configured value if not set",
+		},
+		&cli.BoolFlag{
+			Name:  "wait",
+			Usage: "wait for the job to finish, printing the progress",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		spec := api.StorageMoveSpec{
+			From:     cctx.String("from"),
+			To:       cctx.String("to"),
+			Miner:    abi.ActorID(cctx.Uint64("miner")),
+			Checksum: cctx.Bool("checksum"),
+		}
+
+		for _, num := range cctx.Int64Slice("sectors") {
+			if num < 0 {
+				return fmt.Errorf("invalid sector number %d", num)
+			}
+
+			spec.Sectors = append(spec.Sectors, abi.SectorNumber(num))
+		}
+
+		if len(spec.Sectors) > 0 && spec.Miner == 0 {
+			return fmt.Errorf("--miner is required along with --sectors")
+		}
+
+		if s := cctx.String("rate-limit"); s != "" {
+			limit, err := units.RAMInBytes(s)
+			if err != nil {
+				return fmt.Errorf("parse rate limit %q: %w", s, err)
+			}
+
+			if limit <= 0 {
+				return fmt.Errorf("rate limit should be positive")
+			}
+
+			spec.RateLimit = uint64(limit)
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		job, err := cli.MoveStorage(gctx, spec)
+		if err != nil {
+			return RPCCallError("MoveStorage", err)
+		}
+
+		fmt.Printf("job %s started, %d sectors to be moved from %s to %s\n", job.ID, job.Total, spec.From, spec.To)
+		if !cctx.Bool("wait") {
+			return nil
+		}
+
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-gctx.Done():
+				return gctx.Err()
+
+			case <-ticker.C:
+			}
+
+			jobs, err := cli.ListStorageMoveJobs(gctx)
+			if err != nil {
+				return RPCCallError("ListStorageMoveJobs", err)
+			}
+
+			var current *api.StorageMoveJob
+			for i := range jobs {
+				if jobs[i].ID == job.ID {
+					current = &jobs[i]
+					break
+				}
+			}
+
+			if current == nil {
+				return fmt.Errorf("job %s not found", job.ID)
+			}
+
+			fmt.Printf("%s: moved %d/%d, failed %d, cleaned %d, copied %s\n",
+				current.State, current.Moved, current.Total, len(current.Failures), current.Cleaned,
+				units.BytesSize(float64(current.BytesCopied)),
+			)
+
+			if current.State != api.StorageMoveJobRunning {
+				for _, f := range current.Failures {
+					fmt.Printf("\tm-%d-s-%d (upgrade=%v): %s\n", f.ID.Miner, f.ID.Number, f.Upgrade, f.Error)
+				}
+
+				if current.Error != "" {
+					return fmt.Errorf("job %s %s: %s", current.ID, current.State, current.Error)
+				}
+
+				return nil
+			}
+		}
+	},
+}
+
+var utilStorageJobsCmd = &cli.Command{
+	Name:  "jobs",
+	Usage: "List the move jobs, the latest first",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "failures",
+			Usage: "print the failed sectors",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		jobs, err := cli.ListStorageMoveJobs(gctx)
+		if err != nil {
+			return RPCCallError("ListStorageMoveJobs", err)
+		}
+
+		showFailures := cctx.Bool("failures")
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "ID\tFrom\tTo\tState\tMoved\tFailed\tCleaned\tCopied\tCurrent\tStarted\tElapsed\tError")
+		for _, job := range jobs {
+			current := "-"
+			if job.Current != nil {
+				current = fmt.Sprintf("m-%d-s-%d", job.Current.Miner, job.Current.Number)
+			}
+
+			started := time.Unix(job.StartedAt, 0)
+			finished := time.Now()
+			if job.FinishedAt > 0 {
+				finished = time.Unix(job.FinishedAt, 0)
+			}
+
+			errStr := "-"
+			if job.Error != "" {
+				errStr = job.Error
+			}
+
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d/%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+				job.ID, job.Spec.From, job.Spec.To, job.State,
+				job.Moved, job.Total, len(job.Failures), job.Cleaned,
+				units.BytesSize(float64(job.BytesCopied)), current,
+				started.Format(time.RFC3339), finished.Sub(started).Truncate(time.Second), errStr,
+			)
+
+			if showFailures {
+				for _, f := range job.Failures {
+					_, _ = fmt.Fprintf(tw, "\tm-%d-s-%d\tupgrade=%v\t%s\t\t\t\t\t\t\t\t\n", f.ID.Miner, f.ID.Number, f.Upgrade, f.Error)
+				}
+			}
+		}
+
+		return nil
+	},
+}
+
+var utilStorageCancelCmd = &cli.Command{
+	Name:      "cancel",
+	Usage:     "Cancel a running move job, the source files of the moved sectors will be kept",
+	ArgsUsage: "<job id>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() < 1 {
+			return fmt.Errorf("job id is required")
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		id := cctx.Args().First()
+		if _, err := cli.CancelStorageMove(gctx, id); err != nil {
+			return RPCCallError("CancelStorageMove", err)
+		}
+
+		fmt.Printf("job %s canceled\n", id)
+		return nil
+	},
+}
+
+var utilStorageSectorsCmd = &cli.Command{
+	Name:      "sectors",
+	Usage:     "List the sectors indexed to the given persist store",
+	ArgsUsage: "<store name>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() < 1 {
+			return fmt.Errorf("store name is required")
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		sectors, err := cli.ListStorageSectors(gctx, cctx.Args().First())
+		if err != nil {
+			return RPCCallError("ListStorageSectors", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "Sector\tType")
+		for _, sid := range sectors.Sealed {
+			_, _ = fmt.Fprintf(tw, "m-%d-s-%d\tsealed\n", sid.Miner, sid.Number)
+		}
+
+		for _, sid := range sectors.Upgraded {
+			_, _ = fmt.Fprintf(tw, "m-%d-s-%d\tupdate\n", sid.Miner, sid.Number)
+		}
+
+		return nil
+	},
+}
+
+var utilStorageReindexCmd = &cli.Command{
+	Name:  "reindex",
+	Usage: "Scan the persist stores for the sector files, and repair the index accordingly",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "store",
+			Usage: "names of the persist stores to be scanned, all of them if not set",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only print the differences, without updating the index",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		report, err := cli.ReindexStorage(gctx, api.StorageReindexSpec{
+			Stores: cctx.StringSlice("store"),
+			DryRun: cctx.Bool("dry-run"),
+		})
+		if err != nil {
+			return RPCCallError("ReindexStorage", err)
+		}
+
+		fmt.Printf("scanned: %s\n", strings.Join(report.Scanned, ", "))
+		if len(report.ScanErrors) > 0 {
+			names := make([]string, 0, len(report.ScanErrors))
+			for name := range report.ScanErrors {
+				names = append(names, name)
+			}
+
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("failed to scan %s: %s\n", name, report.ScanErrors[name])
+			}
+		}
+
+		fmt.Printf("found %d sectors, added %d, fixed %d, duplicates %d, incomplete %d, missing %d\n",
+			report.Found, len(report.Added), len(report.Fixed), len(report.Duplicates), len(report.Incomplete), len(report.Missing))
+
+		if report.DryRun {
+			fmt.Println("dry run, the index is not updated")
+		}
+
+		groups := []struct {
+			kind    string
+			entries []api.StorageReindexEntry
+		}{
+			{"added", report.Added},
+			{"fixed", report.Fixed},
+			{"duplicate", report.Duplicates},
+			{"incomplete", report.Incomplete},
+			{"missing", report.Missing},
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "Kind\tSector\tType\tFound In\tIndexed")
+		for _, g := range groups {
+			for _, entry := range g.entries {
+				typ := "sealed"
+				if entry.Upgrade {
+					typ = "update"
+				}
+
+				foundIn := "-"
+				if len(entry.Instances) > 0 {
+					foundIn = strings.Join(entry.Instances, ",")
+				}
+
+				indexed := "-"
+				if entry.Indexed != "" {
+					indexed = entry.Indexed
+				}
+
+				_, _ = fmt.Fprintf(tw, "%s\tm-%d-s-%d\t%s\t%s\t%s\n", g.kind, entry.ID.Miner, entry.ID.Number, typ, foundIn, indexed)
+			}
+		}
+
+		return nil
+	},
+}
+
+var utilStorageAuditCmd = &cli.Command{
+	Name:  "audit",
+	Usage: "Compare the on-chain sectors, the index and the files in the persist stores",
+	Description: `Issues:
+  no-files: on chain, but the sealed (or update) file is not found in any persist store
+  not-indexed: on chain, and the files are found, but the sector is not indexed
+  wrong-store: on chain, but indexed to a store without the files, while they are found in other stores
+  orphan: files found in the persist stores, but the sector is neither on chain nor being sealed
+
+The latest report of the periodic audit is printed, unless --refresh is set.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "refresh",
+			Usage: "run a new audit, instead of printing the latest report",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the report in json",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		report, err := cli.AuditStorage(gctx, cctx.Bool("refresh"))
+		if err != nil {
+			return RPCCallError("AuditStorage", err)
+		}
+
+		if cctx.Bool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "\t")
+			return enc.Encode(report)
+		}
+
+		fmt.Fprintf(os.Stdout, "Audited at %s (epoch %d), %d sectors on chain, %d stores scanned, %d issues\n",
+			time.Unix(report.Time, 0).Format(time.RFC3339), report.Epoch, report.OnChain, len(report.Scanned), len(report.Issues))
+
+		if len(report.ScanErrors) > 0 {
+			names := make([]string, 0, len(report.ScanErrors))
+			for name := range report.ScanErrors {
+				names = append(names, name)
+			}
+
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(os.Stdout, "failed to scan %s: %s\n", name, report.ScanErrors[name])
+			}
+		}
+
+		if len(report.Issues) == 0 {
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "Issue\tSector\tType\tOn Chain\tIndexed\tFound In")
+		for _, issue := range report.Issues {
+			typ := "sealed"
+			if issue.Upgrade {
+				typ = "update"
+			}
+
+			chainState, indexed, foundIn := "-", "-", "-"
+			if issue.ChainState != "" {
+				chainState = issue.ChainState
+			}
+
+			if issue.Indexed != "" {
+				indexed = issue.Indexed
+			}
+
+			if len(issue.Instances) > 0 {
+				foundIn = strings.Join(issue.Instances, ",")
+			}
+
+			_, _ = fmt.Fprintf(tw, "%s\tm-%d-s-%d\t%s\t%s\t%s\t%s\n", issue.Kind, issue.ID.Miner, issue.ID.Number, typ, chainState, indexed, foundIn)
+		}
+
+		return nil
+	},
+}
+
+var utilStorageGCCmd = &cli.Command{
+	Name:  "gc",
+	Usage: "Delete the files of the aborted sectors, and of the sectors no longer on chain",
+	Description: `The offline sectors which are aborted, terminated, or no longer live on chain become candidates,
+their files will be deleted from the persist stores pointed by the index once the grace period passes,
+and they are double-checked against the chain before that.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "only print the candidates and the files to be deleted",
+		},
+		&cli.BoolFlag{
+			Name:  "files",
+			Usage: "print the files deleted",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		report, err := cli.CollectStorage(gctx, cctx.Bool("dry-run"))
+		if err != nil {
+			return RPCCallError("CollectStorage", err)
+		}
+
+		if report.DryRun {
+			fmt.Println("dry run, nothing is deleted")
+		}
+
+		fmt.Printf("%d sectors collected, %d pending\n", len(report.Collected), len(report.Pending))
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "Sector\tReason\tState\tFirst Seen\tEligible At\tDeleted\tErrors")
+		for _, cand := range report.Pending {
+			_, _ = fmt.Fprintf(tw, "m-%d-s-%d\t%s\tpending\t%s\t%s\t-\t-\n",
+				cand.ID.Miner, cand.ID.Number, cand.Reason,
+				time.Unix(cand.FirstSeen, 0).Format(time.RFC3339), time.Unix(cand.EligibleAt, 0).Format(time.RFC3339),
+			)
+		}
+
+		printGCRecords(tw, report.Collected, "collected", cctx.Bool("files"))
+		return nil
+	},
+}
+
+var utilStorageGCLogCmd = &cli.Command{
+	Name:  "gc-log",
+	Usage: "Print the records of the collections, the newest first",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "limit",
+			Value: 100,
+			Usage: "max number of the records, 0 for all",
+		},
+		&cli.BoolFlag{
+			Name:  "files",
+			Usage: "print the files deleted",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		records, err := cli.ListStorageGCRecords(gctx, cctx.Int("limit"))
+		if err != nil {
+			return RPCCallError("ListStorageGCRecords", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "Sector\tReason\tTime\tDeleted\tErrors")
+		for _, record := range records {
+			deleted, errStr := summarizeGCRecord(record)
+
+			_, _ = fmt.Fprintf(tw, "m-%d-s-%d\t%s\t%s\t%d\t%s\n", record.ID.Miner, record.ID.Number, record.Reason,
+				time.Unix(record.Time, 0).Format(time.RFC3339), deleted, errStr)
+
+			if cctx.Bool("files") {
+				printGCFiles(tw, record, "\t\t\t\n")
+			}
+		}
+
+		return nil
+	},
+}
+
+var utilStorageUnsealCmd = &cli.Command{
+	Name:      "unseal",
+	Usage:     "Create an unseal job for the sector holding the piece, the active job of the sector is returned if there is one",
+	ArgsUsage: "<piece cid>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() < 1 {
+			return fmt.Errorf("piece cid is required")
+		}
+
+		pieceCid, err := cid.Decode(cctx.Args().First())
+		if err != nil {
+			return fmt.Errorf("parse piece cid: %w", err)
+		}
+
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		job, err := cli.UnsealPiece(gctx, pieceCid)
+		if err != nil {
+			return RPCCallError("UnsealPiece", err)
+		}
+
+		fmt.Printf("piece %s will be unsealed from m-%d-s-%d in job %s, state %s\n", pieceCid, job.Sector.Miner, job.Sector.Number, job.ID, job.State)
+		return nil
+	},
+}
+
+var utilStorageUnsealJobsCmd = &cli.Command{
+	Name:  "unseal-jobs",
+	Usage: "List the unseal jobs, the latest first",
+	Action: func(cctx *cli.Context) error {
+		cli, gctx, stop, err := extractSealerClient(cctx)
+		if err != nil {
+			return err
+		}
+
+		defer stop()
+
+		jobs, err := cli.ListUnsealJobs(gctx)
+		if err != nil {
+			return RPCCallError("ListUnsealJobs", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		defer tw.Flush()
+
+		_, _ = fmt.Fprintln(tw, "ID\tSector\tInstance\tPieces\tState\tWorker\tProgress\tCreated\tUpdated\tError")
+		for i := len(jobs) - 1; i >= 0; i-- {
+			job := jobs[i]
+
+			worker := "-"
+			if job.Worker != "" {
+				worker = job.Worker
+			}
+
+			errStr := "-"
+			if job.Error != "" {
+				errStr = job.Error
+			}
+
+			_, _ = fmt.Fprintf(tw, "%s\tm-%d-s-%d\t%s\t%d\t%s\t%s\t%d%%\t%s\t%s\t%s\n",
+				job.ID, job.Sector.Miner, job.Sector.Number, job.Instance, len(job.Pieces), job.State, worker, job.Progress,
+				time.Unix(job.CreatedAt, 0).Format(time.RFC3339), time.Unix(job.UpdatedAt, 0).Format(time.RFC3339), errStr,
+			)
+		}
+
+		return nil
+	},
+}
+
+func printGCRecords(tw *tabwriter.Writer, records []api.StorageGCRecord, state string, files bool) {
+	for _, record := range records {
+		deleted, errStr := summarizeGCRecord(record)
+
+		_, _ = fmt.Fprintf(tw, "m-%d-s-%d\t%s\t%s\t-\t-\t%d\t%s\n", record.ID.Miner, record.ID.Number, record.Reason, state, deleted, errStr)
+		if files {
+			printGCFiles(tw, record, "\t\t\t\t\t\n")
+		}
+	}
+}
+
+// summarizeGCRecord returns the number of the deleted objects, and the joined errors
+func summarizeGCRecord(record api.StorageGCRecord) (int, string) {
+	deleted := 0
+	for _, paths := range record.Deleted {
+		deleted += len(paths)
+	}
+
+	errStr := "-"
+	if len(record.Errors) > 0 {
+		errStr = strings.Join(record.Errors, "; ")
+	}
+
+	return deleted, errStr
+}
+
+func printGCFiles(tw *tabwriter.Writer, record api.StorageGCRecord, tail string) {
+	instances := make([]string, 0, len(record.Deleted))
+	for instance := range record.Deleted {
+		instances = append(instances, instance)
+	}
+
+	sort.Strings(instances)
+	for _, instance := range instances {
+		for _, p := range record.Deleted[instance] {
+			_, _ = fmt.Fprintf(tw, "\t%s:%s%s", instance, p, tail)
+		}
+	}
+}
@@ -2,27 +2,44 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 
 	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-jsonrpc/auth"
 
 	"github.com/dtynn/dix"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/sealer"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/rpcauth"
 )
 
-func serveSealerAPI(ctx context.Context, stopper dix.StopFunc, node api.SealerAPI, addr string) error {
-	mux, err := buildRPCServer(node)
+// serveSealerAPI checks the tokens with the given signer, all the permissions will be granted if it is nil.
+// The server listens with TLS if tlsConf is not nil.
+func serveSealerAPI(ctx context.Context, stopper dix.StopFunc, node api.SealerAPI, addr string, signer *rpcauth.Signer, tlsConf *tls.Config) error {
+	var hdl interface{} = sealer.Metriced(node)
+	if signer != nil {
+		var proxy api.SealerClient
+		auth.PermissionedProxy(rpcauth.AllPermissions, rpcauth.DefaultPermissions, hdl, &proxy)
+		hdl = &proxy
+	}
+
+	mux, err := buildRPCServer(hdl, signer)
 	if err != nil {
 		return fmt.Errorf("construct rpc server: %w", err)
 	}
 
+	mux.Handle("/metrics", metrics.Handler())
+
 	// register piece store proxy
 
 	httpServer := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Addr:      addr,
+		Handler:   mux,
+		TLSConfig: tlsConf,
 		BaseContext: func(net.Listener) context.Context {
 			return ctx
 		},
@@ -30,9 +47,17 @@ func serveSealerAPI(ctx context.Context, stopper dix.StopFunc, node api.SealerAP
 
 	errCh := make(chan error, 1)
 	go func() {
-		log.Infof("trying to listen on %s", httpServer.Addr)
+		log.Infow("trying to listen", "addr", httpServer.Addr, "tls", tlsConf != nil)
+
+		var err error
+		if tlsConf != nil {
+			// certificates are already loaded in the tls config
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
 
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err != nil && err != http.ErrServerClosed {
 			errCh <- fmt.Errorf("http server error: %w", err)
 		}
 	}()
@@ -58,9 +83,19 @@ func serveSealerAPI(ctx context.Context, stopper dix.StopFunc, node api.SealerAP
 	return nil
 }
 
-func buildRPCServer(hdl interface{}, opts ...jsonrpc.ServerOption) (*http.ServeMux, error) {
+func buildRPCServer(hdl interface{}, signer *rpcauth.Signer, opts ...jsonrpc.ServerOption) (*http.ServeMux, error) {
 	server := jsonrpc.NewServer(opts...)
 	server.Register("Venus", hdl)
-	http.Handle("/rpc/v0", server)
+
+	if signer == nil {
+		http.Handle("/rpc/v0", server)
+		return http.DefaultServeMux, nil
+	}
+
+	http.Handle("/rpc/v0", &auth.Handler{
+		Verify: signer.Verify,
+		Next:   server.ServeHTTP,
+	})
+
 	return http.DefaultServeMux, nil
 }
@@ -12,6 +12,7 @@ const (
 	ignoredInvoke dix.Invoke = iota // nolint:deadcode,varcheck
 	StartPoSter
 	StartMiner
+	StartStuckWatcher
 
 	// InvokePopulate should always be the last Invoke
 	InvokePopulate
@@ -24,4 +25,5 @@ const (
 
 const (
 	HttpEndpointPiecestore = "/piecestore/"
+	HttpEndpointObjstore   = "/objstore/"
 )
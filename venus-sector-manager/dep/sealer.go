@@ -16,6 +16,7 @@ import (
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/confmgr"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/market"
 	messager "github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/rpcauth"
 )
 
 type GlobalContext context.Context
@@ -45,6 +46,7 @@ func Product() dix.Option {
 		dix.Override(new(confmgr.ConfigManager), BuildLocalConfigManager),
 		dix.Override(new(*modules.Config), ProvideConfig),
 		dix.Override(new(*modules.SafeConfig), ProvideSafeConfig),
+		dix.Override(new(ClientTLSConfig), BuildClientTLSConfig),
 		dix.Override(new(api.SectorManager), BuildLocalSectorManager),
 		dix.Override(new(api.SectorStateManager), BuildLocalSectorStateManager),
 		dix.Override(new(OnlineMetaStore), BuildOnlineMetaStore),
@@ -52,6 +54,13 @@ func Product() dix.Option {
 		dix.Override(new(api.SectorNumberAllocator), BuildSectorNumberAllocator),
 		dix.Override(new(api.RandomnessAPI), randomness.New),
 		dix.Override(new(api.SectorTracker), BuildSectorTracker),
+		dix.Override(new(api.SnapUpSectorManager), BuildSnapUpManager),
+		dix.Override(new(api.SectorExtensionManager), BuildSectorExtensionManager),
+		dix.Override(new(api.SectorTerminationManager), BuildSectorTerminationManager),
+		dix.Override(new(api.SectorImporter), BuildSectorImporter),
+		dix.Override(new(api.SectorEventHub), BuildSectorEventHub),
+		dix.Override(new(api.WorkerRegistry), BuildWorkerRegistry),
+		dix.Override(new(api.StuckSectorDetector), BuildStuckSectorDetector),
 		dix.Override(new(api.Prover), prover.Prover),
 		dix.Override(new(api.Verifier), prover.Verifier),
 		dix.Override(new(api.MinerInfoAPI), BuildMinerInfoAPI),
@@ -59,9 +68,15 @@ func Product() dix.Option {
 		dix.Override(new(api.CommitmentManager), BuildCommitmentManager),
 		dix.Override(new(messager.API), BuildMessagerClient),
 		dix.Override(new(chain.API), BuildChainClient),
-		dix.Override(new(PersistedObjectStoreManager), BuildPersistedFileStoreMgr),
+		dix.Override(new(*rpcauth.Signer), ProvideRPCSigner),
+		dix.Override(new(PersistedObjectStoreManager), BuildPersistedObjectStoreMgr),
+		dix.Override(new(api.PersistStorePlacer), BuildPersistStorePlacer),
 		dix.Override(new(SectorIndexMetaStore), BuildSectorIndexMetaStore),
 		dix.Override(new(api.SectorIndexer), BuildSectorIndexer),
+		dix.Override(new(api.StorageMover), BuildStorageMover),
+		dix.Override(new(api.StorageAuditor), BuildStorageAuditor),
+		dix.Override(new(api.StorageCollector), BuildStorageCollector),
+		dix.Override(new(api.UnsealManager), BuildUnsealManager),
 		dix.Override(ConstructMarketAPIRelated, BuildMarketAPIRelated),
 	)
 }
@@ -70,6 +85,7 @@ func Sealer(target ...interface{}) dix.Option {
 	return dix.Options(
 		dix.Override(new(*sealer.Sealer), sealer.New),
 		dix.Override(new(api.SealerAPI), dix.From(new(*sealer.Sealer))),
+		dix.Override(StartStuckWatcher, RunStuckWatcher),
 		dix.If(len(target) > 0, dix.Populate(InvokePopulate, target...)),
 	)
 }
@@ -82,6 +98,7 @@ func API(target ...interface{}) dix.Option {
 		dix.Override(new(confmgr.ConfigManager), BuildLocalConfigManager),
 		dix.Override(new(*modules.Config), ProvideConfig),
 		dix.Override(new(*modules.SafeConfig), ProvideSafeConfig),
+		dix.Override(new(ClientTLSConfig), BuildClientTLSConfig),
 		dix.Override(new(chain.API), BuildChainClient),
 		dix.Override(new(api.MinerInfoAPI), BuildMinerInfoAPI),
 		dix.Override(new(messager.API), BuildMessagerClient),
@@ -91,7 +108,14 @@ func API(target ...interface{}) dix.Option {
 }
 
 func SealerClient(s *api.SealerClient) dix.Option {
+	cfgmu := &sync.RWMutex{}
 	return dix.Options(
+		dix.Override(new(confmgr.WLocker), cfgmu),
+		dix.Override(new(confmgr.RLocker), cfgmu.RLocker()),
+		dix.Override(new(confmgr.ConfigManager), BuildLocalConfigManager),
+		dix.Override(new(*modules.Config), ProvideConfig),
+		dix.Override(new(*modules.SafeConfig), ProvideSafeConfig),
+		dix.Override(new(ClientTLSConfig), BuildClientTLSConfig),
 		dix.Override(new(api.SealerClient), BuildSealerClient),
 		dix.Populate(InvokePopulate, s),
 	)
@@ -3,15 +3,19 @@ package dep
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"go.uber.org/fx"
 
 	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-jsonrpc/auth"
 	vapi "github.com/filecoin-project/venus/venus-shared/api"
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
@@ -20,6 +24,9 @@ import (
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/impl/dealmgr"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/impl/mock"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/impl/sectors"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/impl/storage"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/impl/workers"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/sealer"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/confmgr"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/homedir"
@@ -28,7 +35,11 @@ import (
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore/filestore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore/httpstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore/s3store"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/piecestore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/rpcauth"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/tlsutil"
 )
 
 type (
@@ -37,10 +48,16 @@ type (
 	PersistedObjectStoreManager objstore.Manager
 	SectorIndexMetaStore        kvstore.KVStore
 	ListenAddress               string
+	ClientTLSConfig             *tls.Config
 )
 
-func BuildLocalSectorManager(cfg *modules.Config, locker confmgr.RLocker, mapi api.MinerInfoAPI, numAlloc api.SectorNumberAllocator) (api.SectorManager, error) {
-	return sectors.NewManager(cfg, locker, mapi, numAlloc)
+func BuildLocalSectorManager(cfg *modules.Config, locker confmgr.RLocker, mapi api.MinerInfoAPI, numAlloc api.SectorNumberAllocator, stmgr api.SectorStateManager, meta OnlineMetaStore) (api.SectorManager, error) {
+	quota, err := kvstore.NewWrappedKVStore([]byte("sector-quota"), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return sectors.NewManager(cfg, locker, mapi, numAlloc, stmgr, quota)
 }
 
 func BuildLocalConfigManager(gctx GlobalContext, lc fx.Lifecycle, home *homedir.Home) (confmgr.ConfigManager, error) {
@@ -89,6 +106,21 @@ func ProvideConfig(gctx GlobalContext, lc fx.Lifecycle, cfgmgr confmgr.ConfigMan
 	return &cfg, nil
 }
 
+// BuildClientTLSConfig builds the config for the clients, the http clients get it through tlsutil.HTTPClient,
+// and the jsonrpc clients through tlsutil.DialURL
+func BuildClientTLSConfig(scfg *modules.SafeConfig) (ClientTLSConfig, error) {
+	scfg.Lock()
+	tcfg := scfg.Common.TLS.Client
+	scfg.Unlock()
+
+	conf, err := tlsutil.ClientConfig(tcfg.CAFile, tcfg.CertFile, tcfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("construct client tls config: %w", err)
+	}
+
+	return conf, nil
+}
+
 func ProvideSafeConfig(cfg *modules.Config, locker confmgr.RLocker) (*modules.SafeConfig, error) {
 	return &modules.SafeConfig{
 		Config: cfg,
@@ -136,16 +168,92 @@ func BuildOfflineMetaStore(gctx GlobalContext, lc fx.Lifecycle, home *homedir.Ho
 	return store, nil
 }
 
-func BuildSectorNumberAllocator(meta OnlineMetaStore) (api.SectorNumberAllocator, error) {
+func BuildSectorNumberAllocator(
+	gctx GlobalContext,
+	lc fx.Lifecycle,
+	meta OnlineMetaStore,
+	scfg *modules.SafeConfig,
+	capi chain.API,
+	stmgr api.SectorStateManager,
+) (api.SectorNumberAllocator, error) {
 	store, err := kvstore.NewWrappedKVStore([]byte("sector-number"), meta)
 	if err != nil {
 		return nil, err
 	}
 
-	return sectors.NewNumerAllocator(store)
+	alloc, err := sectors.NewNumerAllocator(store, scfg, capi, stmgr)
+	if err != nil {
+		return nil, err
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go alloc.Load(gctx)
+			return nil
+		},
+	})
+
+	return alloc, nil
+}
+
+func BuildSectorEventHub(gctx GlobalContext, meta OnlineMetaStore) (api.SectorEventHub, error) {
+	store, err := kvstore.NewWrappedKVStore([]byte("sector-events"), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return sectors.NewEventHub(gctx, store)
+}
+
+func BuildWorkerRegistry(gctx GlobalContext, lc fx.Lifecycle, meta OnlineMetaStore, scfg *modules.SafeConfig, state api.SectorStateManager) (api.WorkerRegistry, error) {
+	store, err := kvstore.NewWrappedKVStore([]byte("workers"), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	reg, err := workers.NewRegistry(gctx, store, scfg, state)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go reg.Run(runCtx)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return reg, nil
+}
+
+func BuildStuckSectorDetector(scfg *modules.SafeConfig, state api.SectorStateManager) (api.StuckSectorDetector, error) {
+	return sectors.NewStuckDetector(scfg, state), nil
 }
 
-func BuildLocalSectorStateManager(online OnlineMetaStore, offline OfflineMetaStore) (api.SectorStateManager, error) {
+func RunStuckWatcher(gctx GlobalContext, lc fx.Lifecycle, s *sealer.Sealer, scfg *modules.SafeConfig) error {
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go s.RunStuckWatcher(runCtx, scfg)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return nil
+}
+
+func BuildLocalSectorStateManager(gctx GlobalContext, lc fx.Lifecycle, online OnlineMetaStore, offline OfflineMetaStore) (api.SectorStateManager, error) {
 	onlineStore, err := kvstore.NewWrappedKVStore([]byte("sector-states"), online)
 	if err != nil {
 		return nil, err
@@ -156,15 +264,43 @@ func BuildLocalSectorStateManager(online OnlineMetaStore, offline OfflineMetaSto
 		return nil, err
 	}
 
-	return sectors.NewStateManager(onlineStore, offlineStore)
+	onlineHistory, err := kvstore.NewWrappedKVStore([]byte("sector-history"), online)
+	if err != nil {
+		return nil, err
+	}
+
+	offlineHistory, err := kvstore.NewWrappedKVStore([]byte("sector-history-offline"), offline)
+	if err != nil {
+		return nil, err
+	}
+
+	stmgr, err := sectors.NewStateManager(onlineStore, offlineStore, onlineHistory, offlineHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go sectors.RunStateMetrics(runCtx, stmgr, time.Minute)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return stmgr, nil
 }
 
-func BuildMessagerClient(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.Config, locker confmgr.RLocker) (messager.API, error) {
+func BuildMessagerClient(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.Config, locker confmgr.RLocker, tlsConf ClientTLSConfig) (messager.API, error) {
 	locker.Lock()
 	api, token := scfg.Common.API.Messager, scfg.Common.API.Token
 	locker.Unlock()
 
-	mcli, mcloser, err := messager.New(gctx, api, token)
+	mcli, mcloser, err := messager.New(gctx, api, token, tlsConf)
 	if err != nil {
 		return nil, err
 	}
@@ -179,28 +315,50 @@ func BuildMessagerClient(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.Conf
 	return mcli, nil
 }
 
-func BuildSealerClient(gctx GlobalContext, lc fx.Lifecycle, listen ListenAddress) (api.SealerClient, error) {
+// BuildSealerClient dials the sealer api at the listen address
+func BuildSealerClient(gctx GlobalContext, lc fx.Lifecycle, home *homedir.Home, scfg *modules.SafeConfig, listen ListenAddress, tlsConf ClientTLSConfig) (api.SealerClient, error) {
 	var scli api.SealerClient
 
-	addr, err := net.ResolveTCPAddr("tcp", string(listen))
+	maddr, err := listenDialAddr(string(listen))
 	if err != nil {
 		return scli, err
 	}
 
-	ip := addr.IP
-	if ip == nil || ip.Equal(net.IPv4zero) {
-		ip = net.IPv4(127, 0, 0, 1)
+	token, err := rpcauth.LoadToken(home.Dir())
+	if err != nil {
+		return scli, err
 	}
 
-	maddr := fmt.Sprintf("/ip4/%s/tcp/%d", ip, addr.Port)
-
-	ainfo := vapi.NewAPIInfo(maddr, "")
+	ainfo := vapi.NewAPIInfo(maddr, token)
 	apiAddr, err := ainfo.DialArgs(vapi.VerString(api.MajorVersion))
 	if err != nil {
 		return scli, err
 	}
 
-	closer, err := jsonrpc.NewClient(gctx, apiAddr, "Venus", &scli, ainfo.AuthHeader())
+	scfg.Lock()
+	tlsEnabled := scfg.Common.TLS.Server.CertFile != ""
+	scfg.Unlock()
+
+	header := ainfo.AuthHeader()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	if tlsEnabled {
+		apiAddr = "wss://" + strings.TrimPrefix(apiAddr, "ws://")
+
+		var tlsHeader http.Header
+		apiAddr, tlsHeader, err = tlsutil.DialURL(gctx, apiAddr, tlsConf)
+		if err != nil {
+			return scli, err
+		}
+
+		for k := range tlsHeader {
+			header.Set(k, tlsHeader.Get(k))
+		}
+	}
+
+	closer, err := jsonrpc.NewClient(gctx, apiAddr, "Venus", &scli, header)
 	if err != nil {
 		return scli, err
 	}
@@ -215,12 +373,45 @@ func BuildSealerClient(gctx GlobalContext, lc fx.Lifecycle, listen ListenAddress
 	return scli, nil
 }
 
-func BuildChainClient(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.Config, locker confmgr.RLocker) (chain.API, error) {
+// listenDialAddr converts the listen address into a multiaddr for dialing.
+// The host is kept as it is, so that it could be verified against the server certificate,
+// and only the unspecified ones are replaced with the loopback address.
+func listenDialAddr(listen string) (string, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", fmt.Errorf("parse listen address %s: %w", listen, err)
+	}
+
+	if host == "" {
+		return fmt.Sprintf("/ip4/127.0.0.1/tcp/%s", port), nil
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return fmt.Sprintf("/dns/%s/tcp/%s", host, port), nil
+
+	case ip.IsUnspecified():
+		if ip.To4() == nil {
+			return fmt.Sprintf("/ip6/::1/tcp/%s", port), nil
+		}
+
+		return fmt.Sprintf("/ip4/127.0.0.1/tcp/%s", port), nil
+
+	case ip.To4() == nil:
+		return fmt.Sprintf("/ip6/%s/tcp/%s", ip, port), nil
+
+	default:
+		return fmt.Sprintf("/ip4/%s/tcp/%s", ip, port), nil
+	}
+}
+
+func BuildChainClient(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.Config, locker confmgr.RLocker, tlsConf ClientTLSConfig) (chain.API, error) {
 	locker.Lock()
 	api, token := scfg.Common.API.Chain, scfg.Common.API.Token
 	locker.Unlock()
 
-	ccli, ccloser, err := chain.New(gctx, api, token)
+	ccli, ccloser, err := chain.New(gctx, api, token, tlsConf)
 	if err != nil {
 		return nil, err
 	}
@@ -333,12 +524,283 @@ func BuildSectorIndexMetaStore(gctx GlobalContext, lc fx.Lifecycle, home *homedi
 	return store, nil
 }
 
-func BuildPersistedFileStoreMgr(scfg *modules.Config, locker confmgr.RLocker) (PersistedObjectStoreManager, error) {
+// openObjectStore opens the store according to its type, kind is only used in the logs
+func openObjectStore(ctx context.Context, kind string, ocfg modules.ObjectStoreConfig, tlsConf *tls.Config) (objstore.Store, error) {
+	switch ocfg.Type {
+	case "", modules.ObjectStoreTypeFS:
+		store, err := filestore.Open(ocfg.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Infow("load store", "kind", kind, "type", modules.ObjectStoreTypeFS, "name", store.Instance(ctx), "path", ocfg.Path)
+		return store, nil
+
+	case modules.ObjectStoreTypeHTTP:
+		store, err := httpstore.Open(httpstore.Config{
+			Name:     ocfg.Name,
+			URL:      ocfg.URL,
+			Token:    ocfg.Token,
+			ReadOnly: ocfg.ReadOnly,
+			Client:   tlsutil.HTTPClient(tlsConf),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("open http %s store %s: %w", kind, ocfg.Name, err)
+		}
+
+		log.Infow("load store", "kind", kind, "type", modules.ObjectStoreTypeHTTP, "name", store.Instance(ctx), "url", ocfg.URL, "mount", ocfg.Path)
+		if ocfg.Path != "" {
+			return objstore.Mounted(store, ocfg.Path), nil
+		}
+
+		return store, nil
+
+	case modules.ObjectStoreTypeS3:
+		store, err := s3store.Open(ocfg.Name, ocfg.ReadOnly, ocfg.S3, tlsutil.HTTPClient(tlsConf))
+		if err != nil {
+			return nil, fmt.Errorf("open s3 %s store %s: %w", kind, ocfg.Name, err)
+		}
+
+		log.Infow("load store", "kind", kind, "type", modules.ObjectStoreTypeS3, "name", store.Instance(ctx), "endpoint", ocfg.S3.Endpoint, "bucket", ocfg.S3.Bucket, "mount", ocfg.Path)
+		if ocfg.Path != "" {
+			return objstore.Mounted(store, ocfg.Path), nil
+		}
+
+		return store, nil
+
+	default:
+		return nil, fmt.Errorf("unknown type %q of %s store %s", ocfg.Type, kind, ocfg.Name)
+	}
+}
+
+func BuildPersistedObjectStoreMgr(
+	gctx GlobalContext,
+	scfg *modules.Config,
+	locker confmgr.RLocker,
+	signer *rpcauth.Signer,
+	tlsConf ClientTLSConfig,
+) (PersistedObjectStoreManager, error) {
 	locker.Lock()
 	persistCfg := scfg.Common.PersistStores
 	locker.Unlock()
 
-	return filestore.NewManager(persistCfg)
+	stores := make([]objstore.Store, 0, len(persistCfg))
+	locals := make([]objstore.Store, 0, len(persistCfg))
+	for _, pcfg := range persistCfg {
+		store, err := openObjectStore(gctx, "persist", pcfg, tlsConf)
+		if err != nil {
+			return nil, err
+		}
+
+		// the sectors are proved through the local paths
+		if objstore.IsRemote(store) {
+			return nil, fmt.Errorf("%s persist store %s requires Path, the local mount point of its objects, for proving the sectors in it", pcfg.Type, pcfg.Name)
+		}
+
+		stores = append(stores, store)
+		if _, ok := store.(*filestore.Store); ok {
+			locals = append(locals, store)
+		}
+	}
+
+	mgr, err := objstore.NewManager(gctx, stores)
+	if err != nil {
+		return nil, err
+	}
+
+	servePersistStores(gctx, locals, signer)
+
+	return objstore.Metriced(mgr), nil
+}
+
+// BuildPersistStorePlacer works on the local persist stores, the others are never recommended
+func BuildPersistStorePlacer(gctx GlobalContext, storeMgr PersistedObjectStoreManager, meta OnlineMetaStore) (api.PersistStorePlacer, error) {
+	store, err := kvstore.NewWrappedKVStore([]byte("persist-reservations"), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return sectors.NewPersistPlacer(gctx, storeMgr, store)
+}
+
+func BuildStorageMover(
+	gctx GlobalContext,
+	lc fx.Lifecycle,
+	scfg *modules.SafeConfig,
+	indexer api.SectorIndexer,
+	placer api.PersistStorePlacer,
+	meta OnlineMetaStore,
+) (api.StorageMover, error) {
+	store, err := kvstore.NewWrappedKVStore([]byte("storage-move-jobs"), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	mover, err := storage.NewMover(gctx, scfg, indexer, placer, store)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go mover.Run(runCtx)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return mover, nil
+}
+
+func BuildStorageAuditor(
+	gctx GlobalContext,
+	lc fx.Lifecycle,
+	scfg *modules.SafeConfig,
+	capi chain.API,
+	infoAPI api.MinerInfoAPI,
+	state api.SectorStateManager,
+	indexer api.SectorIndexer,
+) (api.StorageAuditor, error) {
+	auditor := storage.NewAuditor(scfg, capi, infoAPI, state, indexer)
+
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go auditor.Run(runCtx)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return auditor, nil
+}
+
+func BuildStorageCollector(
+	gctx GlobalContext,
+	lc fx.Lifecycle,
+	scfg *modules.SafeConfig,
+	capi chain.API,
+	infoAPI api.MinerInfoAPI,
+	state api.SectorStateManager,
+	indexer api.SectorIndexer,
+	meta OnlineMetaStore,
+) (api.StorageCollector, error) {
+	store, err := kvstore.NewWrappedKVStore([]byte("storage-gc"), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	collector, err := storage.NewCollector(scfg, capi, infoAPI, state, indexer, store)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go collector.Run(runCtx)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return collector, nil
+}
+
+func BuildUnsealManager(
+	gctx GlobalContext,
+	lc fx.Lifecycle,
+	state api.SectorStateManager,
+	indexer api.SectorIndexer,
+	meta OnlineMetaStore,
+) (api.UnsealManager, error) {
+	store, err := kvstore.NewWrappedKVStore([]byte("unseal-jobs"), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	unsealer := storage.NewUnsealer(state, indexer, store)
+
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go unsealer.Run(runCtx)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return unsealer, nil
+}
+
+// servePersistStores exposes the local persist stores, so that they can be used as http stores by the other hosts.
+// Reading requires the read permission, writing requires the worker permission, and deleting requires the admin permission.
+// The stores are never served without the token checks, so nothing will be registered if the rpc auth is disabled.
+func servePersistStores(ctx context.Context, locals []objstore.Store, signer *rpcauth.Signer) {
+	if len(locals) == 0 {
+		return
+	}
+
+	if signer == nil {
+		log.Warn("rpc auth is disabled, persist stores will not be served to the other hosts")
+		return
+	}
+
+	mux := http.NewServeMux()
+	for _, store := range locals {
+		objstore.ServeHTTP(ctx, mux, store)
+	}
+
+	hdl := rpcauth.Protect(signer, mux, func(req *http.Request) auth.Permission {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead:
+			return rpcauth.PermRead
+
+		case http.MethodDelete:
+			return rpcauth.PermAdmin
+
+		default:
+			return rpcauth.PermSign
+		}
+	})
+
+	http.DefaultServeMux.Handle(HttpEndpointObjstore, http.StripPrefix(strings.TrimSuffix(HttpEndpointObjstore, "/"), hdl))
+	log.Infow("persist stores have been registered into default mux", "count", len(locals))
+}
+
+// ProvideRPCSigner initializes the signer in the home dir if the auth is enabled, nil will be returned otherwise
+func ProvideRPCSigner(home *homedir.Home, scfg *modules.SafeConfig) (*rpcauth.Signer, error) {
+	scfg.Lock()
+	enabled := scfg.Common.API.EnableAuth
+	scfg.Unlock()
+
+	if !enabled {
+		return nil, nil
+	}
+
+	signer, err := rpcauth.Init(home.Dir())
+	if err != nil {
+		return nil, fmt.Errorf("init rpc auth: %w", err)
+	}
+
+	return signer, nil
 }
 
 func BuildSectorIndexer(storeMgr PersistedObjectStoreManager, kv SectorIndexMetaStore) (api.SectorIndexer, error) {
@@ -349,14 +811,94 @@ func BuildSectorTracker(indexer api.SectorIndexer) (api.SectorTracker, error) {
 	return sectors.NewTracker(indexer)
 }
 
+func BuildSnapUpManager(scfg *modules.SafeConfig, capi chain.API, mapi api.MinerInfoAPI, stmgr api.SectorStateManager, indexer api.SectorIndexer) (api.SnapUpSectorManager, error) {
+	return sectors.NewSnapUpAllocator(scfg, capi, mapi, stmgr, indexer)
+}
+
+func BuildSectorExtensionManager(
+	gctx GlobalContext,
+	lc fx.Lifecycle,
+	scfg *modules.SafeConfig,
+	capi chain.API,
+	mapi api.MinerInfoAPI,
+	msgClient messager.API,
+) (api.SectorExtensionManager, error) {
+	mgr, err := sectors.NewExtensionManager(scfg, capi, mapi, msgClient)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go mgr.Run(runCtx)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return mgr, nil
+}
+
+func BuildSectorTerminationManager(
+	gctx GlobalContext,
+	lc fx.Lifecycle,
+	scfg *modules.SafeConfig,
+	capi chain.API,
+	mapi api.MinerInfoAPI,
+	msgClient messager.API,
+	stmgr api.SectorStateManager,
+	meta OnlineMetaStore,
+) (api.SectorTerminationManager, error) {
+	store, err := kvstore.NewWrappedKVStore([]byte("sector-termination"), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr, err := sectors.NewTerminationManager(scfg, capi, mapi, msgClient, stmgr, store)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go mgr.Run(runCtx)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
+	return mgr, nil
+}
+
+func BuildSectorImporter(
+	capi chain.API,
+	mapi api.MinerInfoAPI,
+	stmgr api.SectorStateManager,
+	indexer api.SectorIndexer,
+	numAlloc api.SectorNumberAllocator,
+) (api.SectorImporter, error) {
+	return sectors.NewImporter(capi, mapi, stmgr, indexer, numAlloc)
+}
+
 type MarketAPIRelatedComponets struct {
 	fx.Out
 
-	DealManager api.DealManager
-	MarketAPI   market.API
+	DealManager    api.DealManager
+	DealReconciler api.DealReconciler
+	MarketAPI      market.API
 }
 
-func BuildMarketAPI(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.SafeConfig, infoAPI api.MinerInfoAPI) (market.API, error) {
+func BuildMarketAPI(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.SafeConfig, infoAPI api.MinerInfoAPI, tlsConf ClientTLSConfig) (market.API, error) {
 	scfg.Lock()
 	api, token := scfg.Common.API.Market, scfg.Common.API.Token
 	defer scfg.Unlock()
@@ -365,7 +907,7 @@ func BuildMarketAPI(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.SafeConfi
 		return nil, nil
 	}
 
-	mapi, mcloser, err := market.New(gctx, api, token)
+	mapi, mcloser, err := market.New(gctx, api, token, tlsConf)
 	if err != nil {
 		return nil, fmt.Errorf("construct market api: %w", err)
 	}
@@ -380,8 +922,17 @@ func BuildMarketAPI(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.SafeConfi
 	return mapi, nil
 }
 
-func BuildMarketAPIRelated(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.SafeConfig, infoAPI api.MinerInfoAPI) (MarketAPIRelatedComponets, error) {
-	mapi, err := BuildMarketAPI(gctx, lc, scfg, infoAPI)
+func BuildMarketAPIRelated(
+	gctx GlobalContext,
+	lc fx.Lifecycle,
+	scfg *modules.SafeConfig,
+	infoAPI api.MinerInfoAPI,
+	state api.SectorStateManager,
+	unseal api.UnsealManager,
+	tlsConf ClientTLSConfig,
+	signer *rpcauth.Signer,
+) (MarketAPIRelatedComponets, error) {
+	mapi, err := BuildMarketAPI(gctx, lc, scfg, infoAPI, tlsConf)
 	if err != nil {
 		return MarketAPIRelatedComponets{}, fmt.Errorf("build market api: %w", err)
 	}
@@ -389,8 +940,9 @@ func BuildMarketAPIRelated(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.Sa
 	if mapi == nil {
 		log.Warn("deal manager based on market api is disabled, use mocked")
 		return MarketAPIRelatedComponets{
-			DealManager: mock.NewDealManager(),
-			MarketAPI:   nil,
+			DealManager:    mock.NewDealManager(),
+			DealReconciler: mock.NewDealReconciler(),
+			MarketAPI:      nil,
 		}, nil
 	}
 
@@ -398,17 +950,37 @@ func BuildMarketAPIRelated(gctx GlobalContext, lc fx.Lifecycle, scfg *modules.Sa
 	pieceStoreCfg := scfg.Common.PieceStores
 	scfg.Unlock()
 
-	locals, err := filestore.OpenMany(pieceStoreCfg)
-	if err != nil {
-		return MarketAPIRelatedComponets{}, fmt.Errorf("open local piece stores: %w", err)
+	pieceStores := make([]objstore.Store, 0, len(pieceStoreCfg))
+	for _, pcfg := range pieceStoreCfg {
+		store, err := openObjectStore(gctx, "piece", pcfg, tlsConf)
+		if err != nil {
+			return MarketAPIRelatedComponets{}, fmt.Errorf("open piece stores: %w", err)
+		}
+
+		pieceStores = append(pieceStores, store)
 	}
 
-	proxy := piecestore.NewProxy(locals, mapi)
+	proxy := piecestore.NewProxy(pieceStores, mapi, unseal, signer)
 	http.DefaultServeMux.Handle(HttpEndpointPiecestore, http.StripPrefix(HttpEndpointPiecestore, proxy))
 	log.Info("piecestore proxy has been registered into default mux")
 
+	reconciler := dealmgr.NewReconciler(mapi, infoAPI, scfg, state)
+	runCtx, runCancel := context.WithCancel(gctx)
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go reconciler.Run(runCtx)
+			return nil
+		},
+
+		OnStop: func(ctx context.Context) error {
+			runCancel()
+			return nil
+		},
+	})
+
 	return MarketAPIRelatedComponets{
-		DealManager: dealmgr.New(mapi, infoAPI, scfg),
-		MarketAPI:   mapi,
+		DealManager:    dealmgr.New(mapi, infoAPI, scfg),
+		DealReconciler: reconciler,
+		MarketAPI:      mapi,
 	}, nil
 }
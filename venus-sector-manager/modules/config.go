@@ -12,6 +12,7 @@ import (
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/confmgr"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore/filestore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore/s3store"
 )
 
 func init() {
@@ -60,6 +61,10 @@ type CommonAPIConfig struct {
 	Market   string
 	Gateway  []string
 	Token    string
+
+	// EnableAuth turns on the token checks of the sealer rpc and the http services,
+	// the requests without a token will only be granted the read permission
+	EnableAuth bool
 }
 
 func defaultCommonAPIConfig(example bool) CommonAPIConfig {
@@ -74,10 +79,122 @@ func defaultCommonAPIConfig(example bool) CommonAPIConfig {
 	return cfg
 }
 
+// ServerTLSConfig enables TLS for the daemon http server when both CertFile and KeyFile are set
+type ServerTLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// client certificates signed by the CAs in this bundle will be required if set
+	ClientCAFile string
+}
+
+// ClientTLSConfig is used by the clients connecting to the https / wss endpoints
+type ClientTLSConfig struct {
+	// appended to the system cert pool for verifying the servers
+	CAFile string
+	// presented to the servers requiring client certificates
+	CertFile string
+	KeyFile  string
+}
+
+type CommonTLSConfig struct {
+	Server ServerTLSConfig
+	Client ClientTLSConfig
+}
+
+type CommonWorkersConfig struct {
+	// workers without heartbeats for this long will be marked as offline
+	OfflineTimeout Duration
+}
+
+func defaultCommonWorkersConfig() CommonWorkersConfig {
+	return CommonWorkersConfig{
+		OfflineTimeout: Duration(3 * time.Minute),
+	}
+}
+
+type CommonStuckSectorsConfig struct {
+	CheckInterval Duration
+	// timeout for the phases not listed in Timeouts, 0 for no limit
+	DefaultTimeout Duration
+	// keyed by the worker state names, or the message phases: PreCommitWaitSend, PreCommitPending, CommitWaitSend, CommitPending
+	Timeouts map[string]Duration
+	// stuck sectors in these phases will be aborted automatically
+	AutoAbort []string
+}
+
+func defaultCommonStuckSectorsConfig() CommonStuckSectorsConfig {
+	return CommonStuckSectorsConfig{
+		CheckInterval:  Duration(5 * time.Minute),
+		DefaultTimeout: Duration(24 * time.Hour),
+		Timeouts:       map[string]Duration{},
+		AutoAbort:      []string{},
+	}
+}
+
+type CommonDealsConfig struct {
+	// interval of the reconciliation between the local sector states and venus-market, 0 to disable
+	ReconcileInterval Duration
+}
+
+func defaultCommonDealsConfig() CommonDealsConfig {
+	return CommonDealsConfig{
+		ReconcileInterval: Duration(10 * time.Minute),
+	}
+}
+
+type CommonStorageConfig struct {
+	// max bytes per second read from the source store in each move job, 0 for unlimited
+	MoveRateLimit uint64
+	// delay before deleting the source files of the moved sectors, so that the proofs in progress can finish reading them
+	MoveDeleteDelay Duration
+	// interval of the audit comparing the on-chain sectors, the index and the files, 0 to disable
+	AuditInterval Duration
+	// interval of the collection of the files of the aborted and the removed sectors, 0 to disable
+	GCInterval Duration
+	// the files of a sector will only be collected after it has been a candidate for this long
+	GCGracePeriod Duration
+}
+
+func defaultCommonStorageConfig() CommonStorageConfig {
+	return CommonStorageConfig{
+		MoveRateLimit:   100 << 20,
+		MoveDeleteDelay: Duration(10 * time.Minute),
+		AuditInterval:   Duration(6 * time.Hour),
+		GCInterval:      0,
+		GCGracePeriod:   Duration(72 * time.Hour),
+	}
+}
+
 type CommonConfig struct {
 	API           CommonAPIConfig
-	PieceStores   []filestore.Config
-	PersistStores []filestore.Config
+	TLS           CommonTLSConfig
+	Workers       CommonWorkersConfig
+	StuckSectors  CommonStuckSectorsConfig
+	Deals         CommonDealsConfig
+	Storage       CommonStorageConfig
+	PieceStores   []ObjectStoreConfig
+	PersistStores []ObjectStoreConfig
+}
+
+const (
+	ObjectStoreTypeFS   = "fs"
+	ObjectStoreTypeHTTP = "http"
+	ObjectStoreTypeS3   = "s3"
+)
+
+// ObjectStoreConfig is a local dir by default, Name & ReadOnly are also used by the other types
+type ObjectStoreConfig struct {
+	// Path is the local mount point of the objects for the http & s3 stores, required by the persist stores
+	filestore.Config
+
+	// "fs", "http" or "s3", empty for "fs"
+	Type string
+	// base url of the store served by another venus-sector-manager, for the http stores
+	URL string
+	// token granted by the remote venus-sector-manager, for the http stores
+	Token string
+	// for the s3 stores
+	S3 s3store.Config
 }
 
 func exampleFilestoreConfig() filestore.Config {
@@ -90,13 +207,21 @@ func exampleFilestoreConfig() filestore.Config {
 func defaultCommonConfig(example bool) CommonConfig {
 	cfg := CommonConfig{
 		API:           defaultCommonAPIConfig(example),
-		PieceStores:   []filestore.Config{},
-		PersistStores: []filestore.Config{},
+		Workers:       defaultCommonWorkersConfig(),
+		StuckSectors:  defaultCommonStuckSectorsConfig(),
+		Deals:         defaultCommonDealsConfig(),
+		Storage:       defaultCommonStorageConfig(),
+		PieceStores:   []ObjectStoreConfig{},
+		PersistStores: []ObjectStoreConfig{},
 	}
 
 	if example {
-		cfg.PieceStores = append(cfg.PieceStores, exampleFilestoreConfig())
-		cfg.PersistStores = append(cfg.PersistStores, exampleFilestoreConfig())
+		cfg.PieceStores = append(cfg.PieceStores, ObjectStoreConfig{
+			Config: exampleFilestoreConfig(),
+		})
+		cfg.PersistStores = append(cfg.PersistStores, ObjectStoreConfig{
+			Config: exampleFilestoreConfig(),
+		})
 	}
 
 	return cfg
@@ -118,12 +243,21 @@ type MinerSectorConfig struct {
 	InitNumber uint64
 	MaxNumber  *uint64
 	Enabled    bool
+	// allocate the numbers not taken on chain or locally before the current one first
+	FillHoles bool
+	// relative weight among the miners in allocation
+	Weight uint
+	// max number of sectors being sealed at the same time, 0 for no limit
+	MaxInFlight uint
+	// max number of sectors allocated per day (UTC), 0 for no limit
+	DailyQuota uint
 }
 
 func defaultMinerSectorConfig(example bool) MinerSectorConfig {
 	cfg := MinerSectorConfig{
 		InitNumber: 0,
 		Enabled:    true,
+		Weight:     1,
 	}
 
 	if example {
@@ -134,6 +268,18 @@ func defaultMinerSectorConfig(example bool) MinerSectorConfig {
 	return cfg
 }
 
+type MinerSnapUpConfig struct {
+	Enabled bool
+	MinerCommitmentPolicyConfig
+}
+
+func defaultMinerSnapUpConfig(example bool) MinerSnapUpConfig {
+	return MinerSnapUpConfig{
+		Enabled:                     false,
+		MinerCommitmentPolicyConfig: defaultMinerCommitmentPolicyConfig(example),
+	}
+}
+
 type MinerCommitmentConfig struct {
 	Confidence int64
 	Pre        MinerCommitmentPolicyConfig
@@ -232,22 +378,67 @@ func defaultMinerProofConfig() MinerProofConfig {
 	}
 }
 
+type MinerExtensionConfig struct {
+	Enabled bool
+	Sender  MustAddress
+	FeeConfig
+	// sectors expiring within this window will be extended
+	Window Duration
+	// target lifetime counted from the current head, capped by the chain policy
+	Extension     Duration
+	CheckInterval Duration
+}
+
+func defaultMinerExtensionConfig(example bool) MinerExtensionConfig {
+	cfg := MinerExtensionConfig{
+		Enabled:       false,
+		FeeConfig:     defaultFeeConfig(),
+		Window:        Duration(7 * 24 * time.Hour),
+		Extension:     Duration(540 * 24 * time.Hour),
+		CheckInterval: Duration(time.Hour),
+	}
+
+	if example {
+		cfg.Sender = fakeAddress
+	}
+
+	return cfg
+}
+
+type MinerTerminationConfig struct {
+	// the worker address will be used if not set, the sender given in the request takes precedence
+	Sender MustAddress
+	FeeConfig
+}
+
+func defaultMinerTerminationConfig() MinerTerminationConfig {
+	return MinerTerminationConfig{
+		FeeConfig: defaultFeeConfig(),
+	}
+}
+
 type MinerConfig struct {
-	Actor      abi.ActorID
-	Sector     MinerSectorConfig
-	Commitment MinerCommitmentConfig
-	PoSt       MinerPoStConfig
-	Proof      MinerProofConfig
-	Deal       MinerDealConfig
+	Actor       abi.ActorID
+	Sector      MinerSectorConfig
+	SnapUp      MinerSnapUpConfig
+	Commitment  MinerCommitmentConfig
+	PoSt        MinerPoStConfig
+	Proof       MinerProofConfig
+	Deal        MinerDealConfig
+	Extension   MinerExtensionConfig
+	Termination MinerTerminationConfig
 }
 
 func defaultMinerConfig(example bool) MinerConfig {
 	cfg := MinerConfig{
-		Sector:     defaultMinerSectorConfig(example),
-		Commitment: defaultMinerCommitmentConfig(example),
-		PoSt:       defaultMinerPoStConfig(example),
-		Proof:      defaultMinerProofConfig(),
-		Deal:       defaultMinerDealConfig(),
+		Sector:      defaultMinerSectorConfig(example),
+		SnapUp:      defaultMinerSnapUpConfig(example),
+		Commitment:  defaultMinerCommitmentConfig(example),
+		PoSt:        defaultMinerPoStConfig(example),
+		Proof:       defaultMinerProofConfig(),
+		Deal:        defaultMinerDealConfig(),
+		Extension:   defaultMinerExtensionConfig(example),
+		Termination: defaultMinerTerminationConfig(),
 	}
 
 	if example {
@@ -268,7 +459,9 @@ func (c *Config) UnmarshalConfig(data []byte) error {
 	primitive := struct {
 		Common CommonConfig
 		Miners []toml.Primitive
-	}{}
+	}{
+		Common: defaultCommonConfig(false),
+	}
 
 	meta, err := toml.NewDecoder(bytes.NewReader(data)).Decode(&primitive)
 	if err != nil {
@@ -2,6 +2,7 @@ package commitmgr
 
 import (
 	"context"
+	"strconv"
 	"sync"
 
 	"github.com/filecoin-project/go-address"
@@ -9,11 +10,13 @@ import (
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
 )
 
 type Batcher struct {
 	ctx      context.Context
 	mid      abi.ActorID
+	kind     string
 	ctrlAddr address.Address
 
 	pendingCh chan api.SectorState
@@ -49,6 +52,12 @@ func (b *Batcher) run() {
 
 	pending := make([]api.SectorState, 0, pendingCap)
 
+	miner := strconv.FormatUint(uint64(b.mid), 10)
+	pendingGauge := metrics.BatcherPending.WithLabelValues(b.kind, miner)
+	flush := func(reason string) {
+		metrics.BatcherFlushes.WithLabelValues(b.kind, miner, reason).Inc()
+	}
+
 	for {
 		tick, manual := false, false
 
@@ -77,6 +86,14 @@ func (b *Batcher) run() {
 				pending = pending[:0]
 
 				cleanAll = true
+				switch {
+				case full:
+					flush("full")
+				case manual:
+					flush("manual")
+				default:
+					flush("batch_disabled")
+				}
 			} else if tick {
 				expired, err := b.processor.Expire(b.ctx, pending, b.mid)
 				if err != nil {
@@ -95,6 +112,7 @@ func (b *Batcher) run() {
 					}
 
 					pending = remain
+					flush("expired")
 				}
 			}
 
@@ -110,6 +128,8 @@ func (b *Batcher) run() {
 			}
 		}
 
+		pendingGauge.Set(float64(len(pending)))
+
 		if tick || cleanAll {
 			timer.Stop()
 			timer = b.processor.CheckAfter(b.mid)
@@ -117,16 +137,17 @@ func (b *Batcher) run() {
 	}
 }
 
-func NewBatcher(ctx context.Context, mid abi.ActorID, ctrlAddr address.Address, processer Processor, l *logging.ZapLogger) *Batcher {
+func NewBatcher(ctx context.Context, mid abi.ActorID, kind string, ctrlAddr address.Address, processer Processor, l *logging.ZapLogger) *Batcher {
 	b := &Batcher{
 		ctx:       ctx,
 		mid:       mid,
+		kind:      kind,
 		ctrlAddr:  ctrlAddr,
 		pendingCh: make(chan api.SectorState),
 		force:     make(chan struct{}),
 		stop:      make(chan struct{}),
 		processor: processer,
-		log:       log.With("miner", mid),
+		log:       log.With("miner", mid, "kind", kind),
 	}
 	go b.run()
 
@@ -32,6 +32,8 @@ type ErrBadSeed struct{ error }
 type ErrInvalidProof struct{ error }
 type ErrNoPrecommit struct{ error }
 type ErrCommitWaitFailed struct{ error }
+type ErrBadSectorState struct{ error }
+type ErrSectorUpgraded struct{ error }
 
 // checkPrecommit checks that data commitment generated in the sealing process
 //  matches pieces, and that the seal ticket isn't expired
@@ -177,3 +179,85 @@ func checkCommit(ctx context.Context, si api.SectorState, proof []byte, tok api.
 
 	return nil
 }
+
+// checkReplicaUpdate checks that the sector is still a cc sector on chain,
+// and that the deals and data commitment are valid for the upgrade
+func checkReplicaUpdate(ctx context.Context, maddr address.Address, si api.SectorState, api SealingAPI) error {
+	if si.UpgradedInfo == nil {
+		return &ErrBadSectorState{fmt.Errorf("no replica update info available")}
+	}
+
+	tok, _, err := api.ChainHead(ctx)
+	if err != nil {
+		return &ErrApi{fmt.Errorf("get chain head failed %w", err)}
+	}
+
+	onChain, err := api.StateSectorGetInfo(ctx, maddr, si.ID.Number, tok)
+	if err != nil {
+		return &ErrApi{fmt.Errorf("get sector info: %w", err)}
+	}
+
+	if onChain == nil {
+		return &ErrBadSectorState{fmt.Errorf("sector info not found on chain")}
+	}
+
+	if onChain.SectorKeyCID != nil {
+		if onChain.SealedCID.Equals(si.UpgradedInfo.NewSealedCID) {
+			return &ErrSectorUpgraded{fmt.Errorf("sector already upgraded on chain")}
+		}
+
+		return &ErrBadSectorState{fmt.Errorf("sector already upgraded with another sealed cid %s", onChain.SealedCID)}
+	}
+
+	deals := upgradeDealIDs(si)
+	if len(deals) == 0 {
+		return &ErrInvalidDeals{fmt.Errorf("no deal in sector")}
+	}
+
+	// pledge pieces are not checked
+	pieces := si
+	pieces.Deals = nil
+	for _, d := range si.Deals {
+		if d.ID != 0 {
+			pieces.Deals = append(pieces.Deals, d)
+		}
+	}
+
+	if err := checkPieces(ctx, maddr, pieces, api); err != nil {
+		return err
+	}
+
+	for _, dealID := range deals {
+		proposal, err := api.StateMarketStorageDealProposal(ctx, dealID, tok)
+		if err != nil {
+			return &ErrApi{fmt.Errorf("getting deal %d: %w", dealID, err)}
+		}
+
+		if proposal.EndEpoch > onChain.Expiration {
+			return &ErrInvalidDeals{fmt.Errorf("deal %d ends at %d, after the sector expiration %d", dealID, proposal.EndEpoch, onChain.Expiration)}
+		}
+	}
+
+	commD, err := api.StateComputeDataCommitment(ctx, maddr, si.SectorType, deals, tok)
+	if err != nil {
+		return &ErrApi{fmt.Errorf("calling StateComputeDataCommitment: %w", err)}
+	}
+
+	if !commD.Equals(si.UpgradedInfo.NewUnsealedCID) {
+		return &ErrBadCommD{fmt.Errorf("on chain CommD differs: %s != %s ", si.UpgradedInfo.NewUnsealedCID, commD)}
+	}
+
+	return nil
+}
+
+// upgradeDealIDs returns the ids of the deals, pledge pieces excluded
+func upgradeDealIDs(si api.SectorState) []abi.DealID {
+	res := make([]abi.DealID, 0, len(si.Deals))
+	for i := range si.Deals {
+		if si.Deals[i].ID != 0 {
+			res = append(res, si.Deals[i].ID)
+		}
+	}
+
+	return res
+}
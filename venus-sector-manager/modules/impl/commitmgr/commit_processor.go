@@ -74,7 +74,7 @@ func (c CommitProcessor) processIndividually(ctx context.Context, sectors []api.
 				return
 			}
 
-			mcid, err := pushMessage(ctx, from, mid, collateral, miner.Methods.ProveCommitSector, c.msgClient, spec, enc.Bytes(), slog)
+			mcid, err := PushMessage(ctx, from, mid, collateral, miner.Methods.ProveCommitSector, c.msgClient, spec, enc.Bytes(), slog)
 			if err != nil {
 				slog.Error("push commit single failed: ", err)
 				return
@@ -168,7 +168,7 @@ func (c CommitProcessor) Process(ctx context.Context, sectors []api.SectorState,
 	spec.GasOverEstimation = mcfg.Commitment.Prove.GasOverEstimation
 	spec.MaxFeeCap = mcfg.Commitment.Prove.MaxFeeCap.Std()
 
-	ccid, err := pushMessage(ctx, ctrlAddr, mid, collateral, miner.Methods.ProveCommitAggregate,
+	ccid, err := PushMessage(ctx, ctrlAddr, mid, collateral, miner.Methods.ProveCommitAggregate,
 		c.msgClient, spec, enc.Bytes(), plog)
 	if err != nil {
 		return fmt.Errorf("push aggregate prove message failed: %w", err)
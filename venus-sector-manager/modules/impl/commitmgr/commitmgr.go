@@ -20,6 +20,7 @@ import (
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
 )
 
 var (
@@ -28,6 +29,8 @@ var (
 	errMsgSectorAllocated       = "sector already allocated"
 	errMsgPreCommitInfoNotFound = "pre-commit info not found on chain"
 	errMsgSectorInfoNotFound    = "sector info not found on chain"
+	errMsgNotSnapUpSector       = "sector is not allocated for snapup"
+	errMsgSectorNotUpgraded     = "sector not upgraded on chain"
 )
 
 var (
@@ -49,13 +52,21 @@ type CommitmentMgrImpl struct {
 
 	commitBatcher    map[abi.ActorID]*Batcher
 	preCommitBatcher map[abi.ActorID]*Batcher
+	snapBatcher      map[abi.ActorID]*Batcher
 
-	prePendingChan chan api.SectorState
-	proPendingChan chan api.SectorState
+	prePendingChan  chan api.SectorState
+	proPendingChan  chan api.SectorState
+	snapPendingChan chan api.SectorState
 
 	verif  api.Verifier
 	prover api.Prover
 
+	// messages whose final states have been recorded in the metrics, since they are polled repeatedly
+	landed struct {
+		sync.Mutex
+		msgs map[string]struct{}
+	}
+
 	stopOnce sync.Once
 	stop     chan struct{}
 }
@@ -65,6 +76,7 @@ func NewCommitmentMgr(ctx context.Context, commitApi messager.API, stateMgr Seal
 ) (*CommitmentMgrImpl, error) {
 	prePendingChan := make(chan api.SectorState, 1024)
 	proPendingChan := make(chan api.SectorState, 1024)
+	snapPendingChan := make(chan api.SectorState, 1024)
 
 	mgr := CommitmentMgrImpl{
 		ctx:       ctx,
@@ -75,15 +87,18 @@ func NewCommitmentMgr(ctx context.Context, commitApi messager.API, stateMgr Seal
 
 		commitBatcher:    map[abi.ActorID]*Batcher{},
 		preCommitBatcher: map[abi.ActorID]*Batcher{},
+		snapBatcher:      map[abi.ActorID]*Batcher{},
 
-		prePendingChan: prePendingChan,
-		proPendingChan: proPendingChan,
+		prePendingChan:  prePendingChan,
+		proPendingChan:  proPendingChan,
+		snapPendingChan: snapPendingChan,
 
 		verif:  verif,
 		prover: prover,
 		stop:   make(chan struct{}),
 	}
 
+	mgr.landed.msgs = map[string]struct{}{}
 	return &mgr, nil
 }
 
@@ -101,7 +116,21 @@ func updateSector(ctx context.Context, stmgr api.SectorStateManager, sector []ap
 	plog.Infof("Process sectors %v finished", sectorID)
 }
 
-func pushMessage(ctx context.Context, from address.Address, mid abi.ActorID, value abi.TokenAmount, method abi.MethodNum,
+func updateUpgradeSector(ctx context.Context, stmgr api.SectorStateManager, sector []api.SectorState, plog *logging.ZapLogger) {
+	sectorID := make([]abi.SectorID, len(sector))
+	for i := range sector {
+		sectorID[i] = sector[i].ID
+		sector[i].UpgradeMessageInfo.NeedSend = false
+		err := stmgr.Update(ctx, sector[i].ID, sector[i].UpgradeMessageInfo)
+		if err != nil {
+			plog.With("sector", sector[i].ID.Number).Errorf("Update sector UpgradeMessageInfo failed: %s", err)
+		}
+	}
+
+	plog.Infof("Process sectors %v finished", sectorID)
+}
+
+func PushMessage(ctx context.Context, from address.Address, mid abi.ActorID, value abi.TokenAmount, method abi.MethodNum,
 	msgClient messager.API, spec messager.MsgMeta, params []byte, mlog *logging.ZapLogger) (cid.Cid, error) {
 
 	to, err := address.NewIDAddress(uint64(mid))
@@ -148,6 +177,7 @@ func pushMessage(ctx context.Context, from address.Address, mid abi.ActorID, val
 	}
 
 	uid, err := msgClient.PushMessageWithId(ctx, mcid.String(), &msg, &spec)
+	metrics.MessagePushes.WithLabelValues(method.String(), metrics.Result(err)).Inc()
 	if err != nil {
 		return cid.Undef, fmt.Errorf("push message with id failed: %w", err)
 	}
@@ -179,6 +209,7 @@ func NewMIdFromBytes(seed []byte) (cid.Cid, error) {
 func (c *CommitmentMgrImpl) Run(ctx context.Context) {
 	go c.startPreLoop()
 	go c.startProLoop()
+	go c.startSnapLoop()
 
 	go c.restartSector(ctx)
 }
@@ -188,6 +219,7 @@ func (c *CommitmentMgrImpl) Stop() {
 	c.stopOnce.Do(func() {
 		close(c.prePendingChan)
 		close(c.proPendingChan)
+		close(c.snapPendingChan)
 		close(c.stop)
 
 		for i := range c.commitBatcher {
@@ -196,6 +228,9 @@ func (c *CommitmentMgrImpl) Stop() {
 		for i := range c.commitBatcher {
 			c.preCommitBatcher[i].waitStop()
 		}
+		for i := range c.snapBatcher {
+			c.snapBatcher[i].waitStop()
+		}
 	})
 }
 
@@ -225,6 +260,19 @@ func (c *CommitmentMgrImpl) proveSender(mid abi.ActorID) (address.Address, error
 	return mcfg.Commitment.Prove.Sender.Std(), nil
 }
 
+func (c *CommitmentMgrImpl) snapSender(mid abi.ActorID) (address.Address, error) {
+	mcfg, err := c.cfg.MinerConfig(mid)
+	if err != nil {
+		return address.Undef, fmt.Errorf("get miner config for %d: %w", mid, err)
+	}
+
+	if !mcfg.SnapUp.Sender.Valid() {
+		return address.Undef, fmt.Errorf("sender address not valid")
+	}
+
+	return mcfg.SnapUp.Sender.Std(), nil
+}
+
 func (c *CommitmentMgrImpl) startPreLoop() {
 	llog := log.With("loop", "pre")
 
@@ -246,7 +294,7 @@ func (c *CommitmentMgrImpl) startPreLoop() {
 				continue
 			}
 
-			c.preCommitBatcher[miner] = NewBatcher(c.ctx, miner, sender, PreCommitProcessor{
+			c.preCommitBatcher[miner] = NewBatcher(c.ctx, miner, "pre", sender, PreCommitProcessor{
 				api:       c.stateMgr,
 				msgClient: c.msgClient,
 				smgr:      c.smgr,
@@ -279,7 +327,7 @@ func (c *CommitmentMgrImpl) startProLoop() {
 				continue
 			}
 
-			c.commitBatcher[miner] = NewBatcher(c.ctx, miner, sender, CommitProcessor{
+			c.commitBatcher[miner] = NewBatcher(c.ctx, miner, "prove", sender, CommitProcessor{
 				api:       c.stateMgr,
 				msgClient: c.msgClient,
 				smgr:      c.smgr,
@@ -292,6 +340,39 @@ func (c *CommitmentMgrImpl) startProLoop() {
 	}
 }
 
+func (c *CommitmentMgrImpl) startSnapLoop() {
+	llog := log.With("loop", "snap")
+
+	llog.Info("pending loop start")
+	defer llog.Info("pending loop stop")
+
+	for s := range c.snapPendingChan {
+		miner := s.ID.Miner
+		if _, ok := c.snapBatcher[miner]; !ok {
+			_, err := address.NewIDAddress(uint64(miner))
+			if err != nil {
+				llog.Errorf("trans miner from actor %d to address failed: %s", miner, err)
+				continue
+			}
+
+			sender, err := c.snapSender(miner)
+			if err != nil {
+				llog.Errorf("get sender address: %s", err)
+				continue
+			}
+
+			c.snapBatcher[miner] = NewBatcher(c.ctx, miner, "snapup", sender, ReplicaUpdateProcessor{
+				api:       c.stateMgr,
+				msgClient: c.msgClient,
+				smgr:      c.smgr,
+				config:    c.cfg,
+			}, llog)
+		}
+
+		c.snapBatcher[miner].Add(s)
+	}
+}
+
 func (c *CommitmentMgrImpl) restartSector(ctx context.Context) {
 	sectors, err := c.smgr.All(ctx, api.WorkerOnline)
 	if err != nil {
@@ -302,6 +383,14 @@ func (c *CommitmentMgrImpl) restartSector(ctx context.Context) {
 	log.Debugw("previous sectors loaded", "count", len(sectors))
 
 	for i := range sectors {
+		if sectors[i].Upgraded {
+			if sectors[i].UpgradeMessageInfo.NeedSend {
+				c.snapPendingChan <- *sectors[i]
+			}
+
+			continue
+		}
+
 		if sectors[i].MessageInfo.NeedSend {
 			if sectors[i].MessageInfo.PreCommitCid == nil {
 				c.prePendingChan <- *sectors[i]
@@ -526,6 +615,120 @@ func (c *CommitmentMgrImpl) ProofState(ctx context.Context, id abi.SectorID) (ap
 	return api.PollProofStateResp{State: state, Desc: maybe}, nil
 }
 
+func (c *CommitmentMgrImpl) SubmitReplicaUpdate(ctx context.Context, id abi.SectorID, info api.ReplicaUpdateInfo, hardReset bool) (api.SubmitProofResp, error) {
+	_, err := c.snapSender(id.Miner)
+	if err != nil {
+		return api.SubmitProofResp{}, err
+	}
+
+	sector, err := c.smgr.Load(ctx, id)
+	if err != nil {
+		return api.SubmitProofResp{}, err
+	}
+
+	maddr, err := address.NewIDAddress(uint64(id.Miner))
+	if err != nil {
+		errMsg := err.Error()
+		return api.SubmitProofResp{Res: api.SubmitRejected, Desc: &errMsg}, nil
+	}
+
+	if !sector.Upgraded {
+		return api.SubmitProofResp{Res: api.SubmitRejected, Desc: &errMsgNotSnapUpSector}, nil
+	}
+
+	if sector.UpgradedInfo != nil && !hardReset {
+		changed := !sector.UpgradedInfo.NewSealedCID.Equals(info.NewSealedCID) ||
+			!sector.UpgradedInfo.NewUnsealedCID.Equals(info.NewUnsealedCID) ||
+			!bytes.Equal(sector.UpgradedInfo.Proof, info.Proof)
+
+		if changed {
+			return api.SubmitProofResp{Res: api.SubmitMismatchedSubmission}, nil
+		}
+
+		return api.SubmitProofResp{Res: api.SubmitAccepted}, nil
+	}
+
+	sector.UpgradedInfo = &info
+
+	if err := checkReplicaUpdate(ctx, maddr, *sector, c.stateMgr); err != nil {
+		switch err.(type) {
+		case *ErrApi:
+			return api.SubmitProofResp{}, err
+
+		case *ErrSectorUpgraded:
+			return api.SubmitProofResp{Res: api.SubmitAccepted}, nil
+
+		default:
+			errMsg := err.Error()
+			return api.SubmitProofResp{Res: api.SubmitRejected, Desc: &errMsg}, nil
+		}
+	}
+
+	sector.UpgradeMessageInfo.NeedSend = true
+	sector.UpgradeMessageInfo.UpgradeCid = nil
+	err = c.smgr.Update(ctx, id, sector.UpgradedInfo, sector.UpgradeMessageInfo)
+	if err != nil {
+		return api.SubmitProofResp{}, err
+	}
+
+	go func() {
+		c.snapPendingChan <- *sector
+	}()
+
+	return api.SubmitProofResp{
+		Res: api.SubmitAccepted,
+	}, nil
+}
+
+func (c *CommitmentMgrImpl) ReplicaUpdateState(ctx context.Context, id abi.SectorID) (api.PollProofStateResp, error) {
+	maddr, err := address.NewIDAddress(uint64(id.Miner))
+	if err != nil {
+		return api.PollProofStateResp{}, err
+	}
+
+	sector, err := c.smgr.Load(ctx, id)
+	if err != nil {
+		return api.PollProofStateResp{}, err
+	}
+
+	if !sector.Upgraded {
+		return api.PollProofStateResp{State: api.OnChainStatePermFailed, Desc: &errMsgNotSnapUpSector}, nil
+	}
+
+	if sector.UpgradeMessageInfo.UpgradeCid == nil {
+		if sector.UpgradeMessageInfo.NeedSend {
+			return api.PollProofStateResp{State: api.OnChainStatePending}, nil
+		}
+
+		return api.PollProofStateResp{State: api.OnChainStateFailed, Desc: &errMsgPublishAttemptFailed}, nil
+	}
+
+	msg, err := c.msgClient.GetMessageByUid(ctx, sector.UpgradeMessageInfo.UpgradeCid.String())
+	if err != nil {
+		return api.PollProofStateResp{}, err
+	}
+
+	mlog := log.With("sector-id", id, "stage", "replica-update")
+	state, maybe := c.handleMessage(ctx, id.Miner, msg, mlog)
+	if state == api.OnChainStateLanded {
+		si, err := c.stateMgr.StateSectorGetInfo(ctx, maddr, id.Number, nil)
+		if err != nil {
+			return api.PollProofStateResp{}, err
+		}
+
+		if si == nil {
+			return api.PollProofStateResp{State: api.OnChainStateFailed, Desc: &errMsgSectorInfoNotFound}, nil
+		}
+
+		// the message may be landed, while the update for this sector failed in a batch
+		if si.SectorKeyCID == nil || sector.UpgradedInfo == nil || !si.SealedCID.Equals(sector.UpgradedInfo.NewSealedCID) {
+			return api.PollProofStateResp{State: api.OnChainStateFailed, Desc: &errMsgSectorNotUpgraded}, nil
+		}
+	}
+
+	return api.PollProofStateResp{State: state, Desc: maybe}, nil
+}
+
 func (c *CommitmentMgrImpl) handleMessage(ctx context.Context, mid abi.ActorID, msg *messager.Message, mlog *logging.ZapLogger) (api.OnChainState, *string) {
 	mlog = mlog.With("msg-cid", msg.ID, "msg-state", messager.MessageStateToString(msg.State))
 	if msg.SignedCid != nil {
@@ -552,16 +755,20 @@ func (c *CommitmentMgrImpl) handleMessage(ctx context.Context, mid abi.ActorID,
 		}
 
 		if msg.Receipt == nil {
+			c.observeLanding(msg, "receipt_not_found")
 			return api.OnChainStateFailed, &errMsgReceiptNotFound
 		}
 
 		if msg.Receipt.ExitCode != exitcode.Ok {
+			c.observeLanding(msg, "exit_code_error")
 			return api.OnChainStateFailed, maybeMsg
 		}
 
+		c.observeLanding(msg, "landed")
 		return api.OnChainStateLanded, maybeMsg
 
 	case messager.MessageState.FailedMsg:
+		c.observeLanding(msg, "failed")
 		return api.OnChainStateFailed, maybeMsg
 
 	default:
@@ -569,4 +776,21 @@ func (c *CommitmentMgrImpl) handleMessage(ctx context.Context, mid abi.ActorID,
 	}
 }
 
+func (c *CommitmentMgrImpl) observeLanding(msg *messager.Message, result string) {
+	c.landed.Lock()
+	defer c.landed.Unlock()
+
+	if _, ok := c.landed.msgs[msg.ID]; ok {
+		return
+	}
+
+	// the sectors should have been moved on long before this
+	if len(c.landed.msgs) >= 16384 {
+		c.landed.msgs = map[string]struct{}{}
+	}
+
+	c.landed.msgs[msg.ID] = struct{}{}
+	metrics.MessageLandings.WithLabelValues(msg.Method.String(), result).Inc()
+}
+
 var _ api.CommitmentManager = (*CommitmentMgrImpl)(nil)
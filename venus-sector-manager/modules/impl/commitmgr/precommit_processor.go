@@ -57,7 +57,7 @@ func (p PreCommitProcessor) processIndividually(ctx context.Context, sectors []a
 				return
 			}
 
-			mcid, err := pushMessage(ctx, from, mid, deposit, miner.Methods.PreCommitSector, p.msgClient, spec, enc.Bytes(), slog)
+			mcid, err := PushMessage(ctx, from, mid, deposit, miner.Methods.PreCommitSector, p.msgClient, spec, enc.Bytes(), slog)
 			if err != nil {
 				slog.Error("push pre-commit single failed: ", err)
 				return
@@ -118,7 +118,7 @@ func (p PreCommitProcessor) Process(ctx context.Context, sectors []api.SectorSta
 	spec.GasOverEstimation = mcfg.Commitment.Pre.GasOverEstimation
 	spec.MaxFeeCap = mcfg.Commitment.Pre.MaxFeeCap.Std()
 
-	ccid, err := pushMessage(ctx, ctrlAddr, mid, deposit, miner.Methods.PreCommitSectorBatch,
+	ccid, err := PushMessage(ctx, ctrlAddr, mid, deposit, miner.Methods.PreCommitSectorBatch,
 		p.msgClient, spec, enc.Bytes(), plog)
 	if err != nil {
 		return fmt.Errorf("push batch precommit message failed: %w", err)
@@ -0,0 +1,226 @@
+package commitmgr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	miner7 "github.com/filecoin-project/specs-actors/v7/actors/builtin/miner"
+
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin"
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin/miner"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
+)
+
+type ReplicaUpdateProcessor struct {
+	api       SealingAPI
+	msgClient messager.API
+
+	smgr api.SectorStateManager
+
+	config *modules.SafeConfig
+}
+
+func (p ReplicaUpdateProcessor) replicaUpdateParams(ctx context.Context, maddr address.Address, sector api.SectorState, tok api.TipSetToken) (*miner7.ReplicaUpdate, error) {
+	if sector.UpgradedInfo == nil {
+		return nil, fmt.Errorf("replica update info not found")
+	}
+
+	sl, err := p.api.StateSectorPartition(ctx, maddr, sector.ID.Number, tok)
+	if err != nil {
+		return nil, fmt.Errorf("get sector location: %w", err)
+	}
+
+	if sl == nil {
+		return nil, fmt.Errorf("sector location not found")
+	}
+
+	updateProof, err := sector.SectorType.RegisteredUpdateProof()
+	if err != nil {
+		return nil, fmt.Errorf("get registered update proof type: %w", err)
+	}
+
+	return &miner7.ReplicaUpdate{
+		SectorID:           sector.ID.Number,
+		Deadline:           sl.Deadline,
+		Partition:          sl.Partition,
+		NewSealedSectorCID: sector.UpgradedInfo.NewSealedCID,
+		Deals:              upgradeDealIDs(sector),
+		UpdateProofType:    updateProof,
+		ReplicaProof:       sector.UpgradedInfo.Proof,
+	}, nil
+}
+
+func (p ReplicaUpdateProcessor) processIndividually(ctx context.Context, sectors []api.SectorState, from address.Address, mid abi.ActorID, l *logging.ZapLogger) {
+	mcfg := p.config.MustMinerConfig(mid)
+
+	var spec messager.MsgMeta
+	spec.GasOverEstimation = mcfg.SnapUp.GasOverEstimation
+	spec.MaxFeeCap = mcfg.SnapUp.MaxFeeCap.Std()
+
+	maddr, err := address.NewIDAddress(uint64(mid))
+	if err != nil {
+		l.Errorf("trans miner from actor %d to address failed: %s", mid, err)
+		return
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(sectors))
+	for i := range sectors {
+		go func(idx int) {
+			slog := l.With("sector", sectors[idx].ID.Number)
+
+			defer wg.Done()
+
+			tok, _, err := p.api.ChainHead(ctx)
+			if err != nil {
+				slog.Error("get chain head: ", err)
+				return
+			}
+
+			update, err := p.replicaUpdateParams(ctx, maddr, sectors[idx], tok)
+			if err != nil {
+				slog.Error("get replica update params failed: ", err)
+				return
+			}
+
+			params := &miner7.ProveReplicaUpdatesParams{
+				Updates: []miner7.ReplicaUpdate{*update},
+			}
+
+			enc := new(bytes.Buffer)
+			if err := params.MarshalCBOR(enc); err != nil {
+				slog.Error("serialize replica update parameters failed: ", err)
+				return
+			}
+
+			mcid, err := PushMessage(ctx, from, mid, big.Zero(), miner.Methods.ProveReplicaUpdates, p.msgClient, spec, enc.Bytes(), slog)
+			if err != nil {
+				slog.Error("push replica update single failed: ", err)
+				return
+			}
+
+			sectors[idx].UpgradeMessageInfo.UpgradeCid = &mcid
+			slog.Info("push replica update success, cid: ", mcid)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (p ReplicaUpdateProcessor) Process(ctx context.Context, sectors []api.SectorState, mid abi.ActorID, ctrlAddr address.Address) error {
+	// Notice: If a sector in sectors has been sent, it's cid failed should be changed already.
+	plog := log.With("proc", "snapup", "miner", mid, "ctrl", ctrlAddr.String(), "len", len(sectors))
+
+	start := time.Now()
+	defer plog.Infof("finished process, elasped %s", time.Since(start))
+	defer updateUpgradeSector(ctx, p.smgr, sectors, plog)
+
+	if !p.EnableBatch(mid) {
+		p.processIndividually(ctx, sectors, ctrlAddr, mid, plog)
+		return nil
+	}
+
+	maddr, err := address.NewIDAddress(uint64(mid))
+	if err != nil {
+		return fmt.Errorf("trans miner from actor %d to address failed: %w", mid, err)
+	}
+
+	tok, _, err := p.api.ChainHead(ctx)
+	if err != nil {
+		return fmt.Errorf("get chain head failed: %w", err)
+	}
+
+	params := miner7.ProveReplicaUpdatesParams{}
+	failed := map[abi.SectorID]struct{}{}
+	for _, s := range sectors {
+		update, err := p.replicaUpdateParams(ctx, maddr, s, tok)
+		if err != nil {
+			plog.Errorf("get replica update params for %d failed: %s\n", s.ID.Number, err)
+			failed[s.ID] = struct{}{}
+			continue
+		}
+
+		params.Updates = append(params.Updates, *update)
+	}
+
+	if len(params.Updates) == 0 {
+		return fmt.Errorf("no valid replica update in batch")
+	}
+
+	enc := new(bytes.Buffer)
+	if err := params.MarshalCBOR(enc); err != nil {
+		return fmt.Errorf("couldn't serialize ProveReplicaUpdatesParams: %w", err)
+	}
+
+	mcfg := p.config.MustMinerConfig(mid)
+
+	var spec messager.MsgMeta
+	spec.GasOverEstimation = mcfg.SnapUp.GasOverEstimation
+	spec.MaxFeeCap = mcfg.SnapUp.MaxFeeCap.Std()
+
+	ccid, err := PushMessage(ctx, ctrlAddr, mid, big.Zero(), miner.Methods.ProveReplicaUpdates,
+		p.msgClient, spec, enc.Bytes(), plog)
+	if err != nil {
+		return fmt.Errorf("push batch replica update message failed: %w", err)
+	}
+
+	for i := range sectors {
+		if _, ok := failed[sectors[i].ID]; !ok {
+			sectors[i].UpgradeMessageInfo.UpgradeCid = &ccid
+		}
+	}
+
+	return nil
+}
+
+// Expire picks the sectors whose earliest deal is about to start, the replica updates
+// should be landed before any of the deals starts.
+func (p ReplicaUpdateProcessor) Expire(ctx context.Context, sectors []api.SectorState, mid abi.ActorID) (map[abi.SectorID]struct{}, error) {
+	maxWait := p.config.MustMinerConfig(mid).SnapUp.Batch.MaxWait.Std()
+	maxWaitHeight := abi.ChainEpoch(maxWait / (builtin.EpochDurationSeconds * time.Second))
+	tok, h, err := p.api.ChainHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	expire := map[abi.SectorID]struct{}{}
+	for _, s := range sectors {
+		for _, dealID := range upgradeDealIDs(s) {
+			proposal, err := p.api.StateMarketStorageDealProposal(ctx, dealID, tok)
+			if err != nil {
+				return nil, fmt.Errorf("get deal proposal %d: %w", dealID, err)
+			}
+
+			if proposal.StartEpoch-h <= maxWaitHeight {
+				expire[s.ID] = struct{}{}
+				break
+			}
+		}
+	}
+
+	return expire, nil
+}
+
+func (p ReplicaUpdateProcessor) CheckAfter(mid abi.ActorID) *time.Timer {
+	return time.NewTimer(p.config.MustMinerConfig(mid).SnapUp.Batch.CheckInterval.Std())
+}
+
+func (p ReplicaUpdateProcessor) Threshold(mid abi.ActorID) int {
+	return p.config.MustMinerConfig(mid).SnapUp.Batch.Threshold
+}
+
+func (p ReplicaUpdateProcessor) EnableBatch(mid abi.ActorID) bool {
+	return p.config.MustMinerConfig(mid).SnapUp.Batch.Enabled
+}
+
+var _ Processor = (*ReplicaUpdateProcessor)(nil)
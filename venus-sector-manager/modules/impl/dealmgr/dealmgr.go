@@ -95,3 +95,30 @@ func (dm *DealManager) Release(ctx context.Context, sid abi.SectorID, deals api.
 
 	return nil
 }
+
+// Reacquire marks the released deals as assigned again, used when the sector is restored
+func (dm *DealManager) Reacquire(ctx context.Context, sid abi.SectorID, deals api.Deals) error {
+	maddr, err := address.NewIDAddress(uint64(sid.Miner))
+	if err != nil {
+		return fmt.Errorf("invalid miner id %d: %w", sid.Miner, err)
+	}
+
+	var wg multierror.Group
+	for i := range deals {
+		dealID := deals[i].ID
+		if dealID == 0 {
+			continue
+		}
+
+		wg.Go(func() error {
+			return dm.market.UpdateDealStatus(ctx, maddr, dealID, market.DealStatusAssigned)
+		})
+	}
+
+	err = wg.Wait().ErrorOrNil()
+	if err != nil {
+		return fmt.Errorf("get errors in some or all of the requests: %w", err)
+	}
+
+	return nil
+}
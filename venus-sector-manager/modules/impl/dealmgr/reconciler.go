@@ -0,0 +1,252 @@
+package dealmgr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/market"
+)
+
+var log = logging.New("dealmgr")
+
+var _ api.DealReconciler = (*Reconciler)(nil)
+
+const (
+	sectorStateOnline  = "online"
+	sectorStateLanded  = "landed"
+	sectorStateAborted = "aborted"
+)
+
+func NewReconciler(marketAPI market.API, infoAPI api.MinerInfoAPI, scfg *modules.SafeConfig, state api.SectorStateManager) *Reconciler {
+	return &Reconciler{
+		market: marketAPI,
+		info:   infoAPI,
+		scfg:   scfg,
+		state:  state,
+	}
+}
+
+// Reconciler compares the deals held by the local sectors with the statuses in venus-market
+type Reconciler struct {
+	market market.API
+	info   api.MinerInfoAPI
+	scfg   *modules.SafeConfig
+	state  api.SectorStateManager
+}
+
+type dealKey struct {
+	miner abi.ActorID
+	deal  abi.DealID
+}
+
+type dealHolder struct {
+	sector   abi.SectorID
+	state    string
+	priority int
+}
+
+// holders finds the sector holding each deal, the landed ones win over the online ones, and then the aborted ones,
+// since a released deal could be assigned to another sector.
+func (r *Reconciler) holders(ctx context.Context) (map[dealKey]dealHolder, error) {
+	holders := map[dealKey]dealHolder{}
+	add := func(st api.SectorState, state string, priority int) {
+		for _, deal := range st.Deals {
+			if deal.ID == 0 {
+				continue
+			}
+
+			key := dealKey{miner: st.ID.Miner, deal: deal.ID}
+			if prev, ok := holders[key]; ok && prev.priority >= priority {
+				continue
+			}
+
+			holders[key] = dealHolder{sector: st.ID, state: state, priority: priority}
+		}
+	}
+
+	if err := r.state.ForEach(ctx, api.WorkerOnline, func(st api.SectorState) error {
+		add(st, sectorStateOnline, 2)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("scan online sectors: %w", err)
+	}
+
+	if err := r.state.ForEach(ctx, api.WorkerOffline, func(st api.SectorState) error {
+		if st.AbortReason != "" {
+			add(st, sectorStateAborted, 1)
+		} else if st.Finalized {
+			add(st, sectorStateLanded, 3)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("scan offline sectors: %w", err)
+	}
+
+	return holders, nil
+}
+
+// expected returns the status the deal should be moved to, or an empty string if it is acceptable
+func expected(state string, status market.DealStatus) market.DealStatus {
+	switch state {
+	case sectorStateOnline:
+		// restored sectors may have their deals proving already
+		if status == market.DealStatusUndefine {
+			return market.DealStatusAssigned
+		}
+
+	case sectorStateLanded:
+		if status != market.DealStatusProving {
+			return market.DealStatusProving
+		}
+
+	case sectorStateAborted:
+		if status == market.DealStatusAssigned || status == market.DealStatusPacking {
+			return market.DealStatusUndefine
+		}
+	}
+
+	return ""
+}
+
+func (r *Reconciler) Check(ctx context.Context) (api.DealReconcileReport, error) {
+	report := api.DealReconcileReport{
+		Time: time.Now().Unix(),
+	}
+
+	holders, err := r.holders(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	report.Checked = len(holders)
+
+	r.scfg.Lock()
+	miners := make([]abi.ActorID, 0, len(r.scfg.Miners))
+	for _, mcfg := range r.scfg.Miners {
+		if mcfg.Deal.Enabled {
+			miners = append(miners, mcfg.Actor)
+		}
+	}
+	r.scfg.Unlock()
+
+	for _, mid := range miners {
+		minfo, err := r.info.Get(ctx, mid)
+		if err != nil {
+			return report, fmt.Errorf("get miner info for %d: %w", mid, err)
+		}
+
+		// deals already activated won't be listed, and they need no fix
+		deals, err := r.market.MarketListIncompleteDeals(ctx, minfo.Addr)
+		if err != nil {
+			return report, fmt.Errorf("list incomplete deals for %d: %w", mid, err)
+		}
+
+		for _, deal := range deals {
+			if deal.DealID == 0 {
+				continue
+			}
+
+			holder, ok := holders[dealKey{miner: mid, deal: deal.DealID}]
+			if !ok {
+				if deal.PieceStatus == market.DealStatusAssigned || deal.PieceStatus == market.DealStatusPacking {
+					report.Mismatches = append(report.Mismatches, api.DealMismatch{
+						DealID:       deal.DealID,
+						MarketStatus: string(deal.PieceStatus),
+					})
+				}
+
+				continue
+			}
+
+			if exp := expected(holder.state, deal.PieceStatus); exp != "" {
+				sid := holder.sector
+				report.Mismatches = append(report.Mismatches, api.DealMismatch{
+					DealID:       deal.DealID,
+					Sector:       &sid,
+					SectorState:  holder.state,
+					MarketStatus: string(deal.PieceStatus),
+					Expected:     string(exp),
+				})
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// Run fixes the mismatches periodically, until the ctx is done.
+// A mismatch will only be fixed if it is found in two rounds in a row, so that the deals being acquired won't be touched.
+func (r *Reconciler) Run(ctx context.Context) {
+	suspected := map[dealKey]string{}
+	for {
+		r.scfg.Lock()
+		interval := r.scfg.Common.Deals.ReconcileInterval.Std()
+		r.scfg.Unlock()
+
+		// check again later, in case it is enabled
+		wait := interval
+		if wait <= 0 {
+			wait = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if interval <= 0 {
+			continue
+		}
+
+		report, err := r.Check(ctx)
+		if err != nil {
+			log.Warnw("check deals", "err", err)
+			continue
+		}
+
+		found := map[dealKey]string{}
+		for _, mismatch := range report.Mismatches {
+			if mismatch.Sector == nil {
+				log.Warnw("deal assigned without local sector", "deal", mismatch.DealID, "status", mismatch.MarketStatus)
+				continue
+			}
+
+			key := dealKey{miner: mismatch.Sector.Miner, deal: mismatch.DealID}
+			if suspected[key] != mismatch.Expected {
+				found[key] = mismatch.Expected
+				continue
+			}
+
+			r.fix(ctx, mismatch)
+		}
+
+		suspected = found
+		log.Debugw("deals reconciled", "checked", report.Checked, "mismatches", len(report.Mismatches), "suspected", len(suspected))
+	}
+}
+
+func (r *Reconciler) fix(ctx context.Context, mismatch api.DealMismatch) {
+	sid := *mismatch.Sector
+	dlog := log.With("miner", sid.Miner, "num", sid.Number, "deal", mismatch.DealID, "from", mismatch.MarketStatus, "to", mismatch.Expected)
+
+	minfo, err := r.info.Get(ctx, sid.Miner)
+	if err != nil {
+		dlog.Warnw("get miner info", "err", err)
+		return
+	}
+
+	if err := r.market.UpdateDealStatus(ctx, minfo.Addr, mismatch.DealID, market.DealStatus(mismatch.Expected)); err != nil {
+		dlog.Warnw("update deal status", "err", err)
+		return
+	}
+
+	dlog.Infow("deal status fixed", "sector-state", mismatch.SectorState)
+}
@@ -16,6 +16,7 @@ func NewCommitManager() api.CommitmentManager {
 
 	cmgr.pres.commits = map[abi.SectorID]api.PreCommitInfo{}
 	cmgr.proofs.proofs = map[abi.SectorID]api.ProofInfo{}
+	cmgr.upgrades.infos = map[abi.SectorID]api.ReplicaUpdateInfo{}
 	return cmgr
 }
 
@@ -29,6 +30,11 @@ type commitMgr struct {
 		sync.RWMutex
 		proofs map[abi.SectorID]api.ProofInfo
 	}
+
+	upgrades struct {
+		sync.RWMutex
+		infos map[abi.SectorID]api.ReplicaUpdateInfo
+	}
 }
 
 func (c *commitMgr) SubmitPreCommit(ctx context.Context, sid abi.SectorID, info api.PreCommitInfo, hardReset bool) (api.SubmitPreCommitResp, error) {
@@ -106,3 +112,41 @@ func (c *commitMgr) ProofState(ctx context.Context, sid abi.SectorID) (api.PollP
 		Desc:  nil,
 	}, nil
 }
+
+func (c *commitMgr) SubmitReplicaUpdate(ctx context.Context, sid abi.SectorID, info api.ReplicaUpdateInfo, hardReset bool) (api.SubmitProofResp, error) {
+	c.upgrades.Lock()
+	defer c.upgrades.Unlock()
+
+	if !hardReset {
+		if _, ok := c.upgrades.infos[sid]; ok {
+			return api.SubmitProofResp{
+				Res:  api.SubmitDuplicateSubmit,
+				Desc: nil,
+			}, nil
+		}
+	}
+
+	c.upgrades.infos[sid] = info
+
+	return api.SubmitProofResp{
+		Res:  api.SubmitAccepted,
+		Desc: nil,
+	}, nil
+}
+
+func (c *commitMgr) ReplicaUpdateState(ctx context.Context, sid abi.SectorID) (api.PollProofStateResp, error) {
+	c.upgrades.RLock()
+	defer c.upgrades.RUnlock()
+
+	if _, ok := c.upgrades.infos[sid]; ok {
+		return api.PollProofStateResp{
+			State: api.OnChainStateLanded,
+			Desc:  nil,
+		}, nil
+	}
+
+	return api.PollProofStateResp{
+		State: api.OnChainStateNotFound,
+		Desc:  nil,
+	}, nil
+}
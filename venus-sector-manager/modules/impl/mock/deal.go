@@ -2,6 +2,7 @@ package mock
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/filecoin-project/go-state-types/abi"
 
@@ -24,3 +25,20 @@ func (*nullDeal) Acquire(context.Context, abi.SectorID, *uint) (api.Deals, error
 func (*nullDeal) Release(context.Context, abi.SectorID, api.Deals) error {
 	return nil
 }
+
+func (*nullDeal) Reacquire(context.Context, abi.SectorID, api.Deals) error {
+	return nil
+}
+
+var _ api.DealReconciler = (*nullDealReconciler)(nil)
+
+func NewDealReconciler() api.DealReconciler {
+	return &nullDealReconciler{}
+}
+
+type nullDealReconciler struct {
+}
+
+func (*nullDealReconciler) Check(context.Context) (api.DealReconcileReport, error) {
+	return api.DealReconcileReport{}, fmt.Errorf("market api is not available")
+}
@@ -2,14 +2,16 @@ package mock
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
-	"github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/filecoin-project/venus/venus-shared/actors/builtin"
 	"github.com/filecoin-project/venus/venus-shared/types"
 
+	"github.com/ipfs/go-cid"
+
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
 )
 
@@ -69,6 +71,11 @@ func (s *Sealer) WaitSeed(ctx context.Context, sid abi.SectorID) (api.WaitSeedRe
 	}, nil
 }
 
+// AllocatePersistStore leaves the choice to the worker
+func (s *Sealer) AllocatePersistStore(ctx context.Context, sid abi.SectorID) (*api.PersistStoreAllocation, error) {
+	return nil, nil
+}
+
 func (s *Sealer) SubmitPersisted(ctx context.Context, sid abi.SectorID, instance string) (bool, error) {
 	log.Warnf("sector m-%d-s-%d is in the instance %s", sid.Miner, sid.Number, instance)
 	return true, nil
@@ -82,8 +89,8 @@ func (s *Sealer) PollProofState(ctx context.Context, sid abi.SectorID) (api.Poll
 	return s.commit.ProofState(ctx, sid)
 }
 
-func (s *Sealer) ListSectors(context.Context, api.SectorWorkerState) ([]*api.SectorState, error) {
-	return nil, nil
+func (s *Sealer) ListSectors(context.Context, api.SectorWorkerState, api.SectorListQuery) (api.SectorListPage, error) {
+	return api.SectorListPage{}, nil
 }
 
 func (s *Sealer) ReportState(ctx context.Context, sid abi.SectorID, req api.ReportStateReq) (api.Meta, error) {
@@ -101,10 +108,138 @@ func (s *Sealer) ReportAborted(ctx context.Context, sid abi.SectorID, reason str
 	return api.Empty, nil
 }
 
-func (s *Sealer) CheckProvable(context.Context, abi.RegisteredPoStProof, []storage.SectorRef, bool) (map[abi.SectorNumber]string, error) {
+func (s *Sealer) CheckProvable(context.Context, abi.ActorID, []builtin.ExtendedSectorInfo, bool) (map[abi.SectorNumber]string, error) {
 	return nil, nil
 }
 
 func (s *Sealer) SimulateWdPoSt(context.Context, address.Address, []builtin.ExtendedSectorInfo, abi.PoStRandomness) error {
 	return nil
 }
+
+func (s *Sealer) AllocateSnapUpSector(ctx context.Context, spec api.AllocateSnapUpSpec) (*api.AllocatedSnapUpSector, error) {
+	log.Warnf("snapup sector allocation is not supported in mock mode")
+	return nil, nil
+}
+
+func (s *Sealer) SubmitSnapUpProof(ctx context.Context, sid abi.SectorID, info api.SnapUpOnChainInfo, reset bool) (api.SubmitProofResp, error) {
+	rinfo, err := info.IntoReplicaUpdateInfo()
+	if err != nil {
+		return api.SubmitProofResp{}, err
+	}
+
+	return s.commit.SubmitReplicaUpdate(ctx, sid, rinfo, reset)
+}
+
+func (s *Sealer) PollSnapUpProofState(ctx context.Context, sid abi.SectorID) (api.PollProofStateResp, error) {
+	return s.commit.ReplicaUpdateState(ctx, sid)
+}
+
+func (s *Sealer) ExtendSectors(ctx context.Context, mid abi.ActorID, spec api.ExtendSectorsSpec) ([]api.SectorExtensionMessage, error) {
+	return nil, nil
+}
+
+func (s *Sealer) TerminateSectors(ctx context.Context, mid abi.ActorID, spec api.TerminateSectorsSpec) ([]api.SectorTerminationMessage, error) {
+	return nil, nil
+}
+
+func (s *Sealer) ImportSectors(ctx context.Context, sectors []api.SectorImportInfo, override bool) ([]api.SectorImportResult, error) {
+	return nil, nil
+}
+
+func (s *Sealer) InspectSectorNumber(ctx context.Context, mid abi.ActorID, refresh bool) (api.SectorNumberInfo, error) {
+	return api.SectorNumberInfo{}, nil
+}
+
+func (s *Sealer) ResetSectorNumber(ctx context.Context, mid abi.ActorID, num uint64) (api.Meta, error) {
+	return api.Empty, nil
+}
+
+func (s *Sealer) SectorEvents(ctx context.Context, filter api.SectorEventFilter) (<-chan api.SectorEvent, error) {
+	ch := make(chan api.SectorEvent)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+func (s *Sealer) SectorHistory(ctx context.Context, sid abi.SectorID) ([]api.SectorStateRecord, error) {
+	return nil, nil
+}
+
+func (s *Sealer) RestoreSector(ctx context.Context, sid abi.SectorID) (api.Meta, error) {
+	return api.Empty, nil
+}
+
+func (s *Sealer) RegisterWorker(ctx context.Context, info api.WorkerInfo) (api.Meta, error) {
+	return api.Empty, nil
+}
+
+func (s *Sealer) WorkerHeartbeat(ctx context.Context, name string, res api.WorkerResources) (api.Meta, error) {
+	return api.Empty, nil
+}
+
+func (s *Sealer) ListWorkers(ctx context.Context) ([]api.WorkerStatus, error) {
+	return nil, nil
+}
+
+func (s *Sealer) ListStuckSectors(ctx context.Context) ([]api.StuckSector, error) {
+	return nil, nil
+}
+
+func (s *Sealer) CheckDeals(ctx context.Context) (api.DealReconcileReport, error) {
+	return api.DealReconcileReport{}, nil
+}
+
+func (s *Sealer) ListPersistStores(ctx context.Context) ([]api.PersistStoreStatus, error) {
+	return nil, nil
+}
+
+func (s *Sealer) MoveStorage(ctx context.Context, spec api.StorageMoveSpec) (api.StorageMoveJob, error) {
+	return api.StorageMoveJob{}, fmt.Errorf("storage move is not supported by the mock sealer")
+}
+
+func (s *Sealer) ListStorageMoveJobs(ctx context.Context) ([]api.StorageMoveJob, error) {
+	return nil, nil
+}
+
+func (s *Sealer) CancelStorageMove(ctx context.Context, id string) (api.Meta, error) {
+	return api.Empty, nil
+}
+
+func (s *Sealer) ListStorageSectors(ctx context.Context, instance string) (api.StorageSectors, error) {
+	return api.StorageSectors{Instance: instance}, nil
+}
+
+func (s *Sealer) ReindexStorage(ctx context.Context, spec api.StorageReindexSpec) (api.StorageReindexReport, error) {
+	return api.StorageReindexReport{}, fmt.Errorf("storage reindex is not supported by the mock sealer")
+}
+
+func (s *Sealer) AuditStorage(ctx context.Context, refresh bool) (api.StorageAuditReport, error) {
+	return api.StorageAuditReport{}, nil
+}
+
+func (s *Sealer) CollectStorage(ctx context.Context, dryRun bool) (api.StorageGCReport, error) {
+	return api.StorageGCReport{}, fmt.Errorf("storage gc is not supported by the mock sealer")
+}
+
+func (s *Sealer) ListStorageGCRecords(ctx context.Context, limit int) ([]api.StorageGCRecord, error) {
+	return nil, nil
+}
+
+func (s *Sealer) UnsealPiece(ctx context.Context, pieceCid cid.Cid) (api.UnsealJob, error) {
+	return api.UnsealJob{}, fmt.Errorf("unseal is not supported by the mock sealer")
+}
+
+func (s *Sealer) ListUnsealJobs(ctx context.Context) ([]api.UnsealJob, error) {
+	return nil, nil
+}
+
+func (s *Sealer) AcquireUnsealJob(ctx context.Context, worker string) (*api.UnsealJob, error) {
+	return nil, nil
+}
+
+func (s *Sealer) ReportUnsealProgress(ctx context.Context, id string, progress api.UnsealProgress) (api.Meta, error) {
+	return api.Empty, nil
+}
@@ -0,0 +1,46 @@
+package sectors
+
+// declPart is a range of the sectors in a declaration
+type declPart struct {
+	decl  int
+	start int
+	end   int
+}
+
+// batchDeclarations packs the declarations, described by the numbers of their sectors, into messages,
+// each of which addresses at most declMax declarations and addrMax sectors.
+// A declaration exceeding the room left in the current message is split across messages.
+func batchDeclarations(sizes []int, declMax, addrMax int) [][]declPart {
+	var batches [][]declPart
+	var cur []declPart
+	addressed := 0
+
+	flush := func() {
+		if len(cur) > 0 {
+			batches = append(batches, cur)
+		}
+
+		cur = nil
+		addressed = 0
+	}
+
+	for di, total := range sizes {
+		for start := 0; start < total; {
+			if len(cur) >= declMax || addressed >= addrMax {
+				flush()
+			}
+
+			size := total - start
+			if room := addrMax - addressed; size > room {
+				size = room
+			}
+
+			cur = append(cur, declPart{decl: di, start: start, end: start + size})
+			addressed += size
+			start += size
+		}
+	}
+
+	flush()
+	return batches
+}
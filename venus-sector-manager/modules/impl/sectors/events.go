@@ -0,0 +1,279 @@
+package sectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+)
+
+var _ api.SectorEventHub = (*EventHub)(nil)
+
+const (
+	// number of events kept in the store for resuming
+	eventsRetained = 100_000
+	eventsPruneGap = 1000
+	// events buffered for each subscriber, slow subscribers will be dropped when it is full
+	eventsSubBuffer = 256
+)
+
+func NewEventHub(ctx context.Context, store kvstore.KVStore) (*EventHub, error) {
+	hub := &EventHub{
+		store: store,
+		subs:  map[*eventSub]struct{}{},
+	}
+
+	last, err := hub.lastSeq(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load last event seq: %w", err)
+	}
+
+	hub.seq = last
+	return hub, nil
+}
+
+// EventHub persists the sector events, and delivers them to the subscribers
+type EventHub struct {
+	store kvstore.KVStore
+
+	// serializes the publishers, so that the events are persisted & delivered in order,
+	// without blocking the subscribers during the persisting
+	pubMu sync.Mutex
+
+	// seq is the last persisted event, updated along with the delivery
+	mu   sync.Mutex
+	seq  uint64
+	subs map[*eventSub]struct{}
+}
+
+type eventSub struct {
+	filter api.SectorEventFilter
+	ch     chan api.SectorEvent
+}
+
+// Publish persists the event and delivers it to the subscribers.
+// The seq is only taken by the event once it is persisted, so that a failed one leaves no gap
+// for the subscribers resuming from the store.
+func (h *EventHub) Publish(ctx context.Context, evt api.SectorEvent) error {
+	h.pubMu.Lock()
+	defer h.pubMu.Unlock()
+
+	h.mu.Lock()
+	evt.Seq = h.seq + 1
+	h.mu.Unlock()
+
+	evt.Time = time.Now().Unix()
+
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("marshal sector event: %w", err)
+	}
+
+	if err := h.store.Put(ctx, makeEventKey(evt.Seq), b); err != nil {
+		return fmt.Errorf("save sector event %d: %w", evt.Seq, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// subscribers registered from now on will replay this event from the store
+	h.seq = evt.Seq
+	for sub := range h.subs {
+		if !sub.filter.Match(evt) {
+			continue
+		}
+
+		select {
+		case sub.ch <- evt:
+		default:
+			log.Warnw("sector event subscriber too slow, dropped", "seq", evt.Seq)
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+
+	if evt.Seq%eventsPruneGap == 0 && evt.Seq > eventsRetained {
+		go h.prune(context.Background(), evt.Seq-eventsRetained)
+	}
+
+	return nil
+}
+
+// Subscribe delivers the events after filter.Since, the stored ones will be replayed first.
+// The channel will be closed once the ctx is done, or the subscriber falls too far behind.
+func (h *EventHub) Subscribe(ctx context.Context, filter api.SectorEventFilter) (<-chan api.SectorEvent, error) {
+	sub := &eventSub{
+		filter: filter,
+		ch:     make(chan api.SectorEvent, eventsSubBuffer),
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	current := h.seq
+	h.mu.Unlock()
+
+	var replay []api.SectorEvent
+	if filter.Since < current {
+		var err error
+		replay, err = h.load(ctx, filter, current)
+		if err != nil {
+			h.unsubscribe(sub)
+			return nil, fmt.Errorf("load stored events: %w", err)
+		}
+	}
+
+	out := make(chan api.SectorEvent, eventsSubBuffer)
+	go func() {
+		defer close(out)
+		defer h.unsubscribe(sub)
+
+		for _, evt := range replay {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- evt:
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case evt, ok := <-sub.ch:
+				if !ok {
+					return
+				}
+
+				// already replayed
+				if evt.Seq <= current {
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case out <- evt:
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (h *EventHub) unsubscribe(sub *eventSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+func (h *EventHub) load(ctx context.Context, filter api.SectorEventFilter, until uint64) ([]api.SectorEvent, error) {
+	iter, err := h.store.Scan(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var events []api.SectorEvent
+	for ok := iter.Seek(makeEventKey(filter.Since + 1)); ok; ok = iter.Next() {
+		seq, parsed := parseEventKey(iter.Key())
+		if !parsed {
+			continue
+		}
+
+		if seq > until {
+			break
+		}
+
+		var evt api.SectorEvent
+		if err := iter.View(ctx, func(data []byte) error {
+			return json.Unmarshal(data, &evt)
+		}); err != nil {
+			return nil, fmt.Errorf("load event %d: %w", seq, err)
+		}
+
+		if filter.Match(evt) {
+			events = append(events, evt)
+		}
+	}
+
+	return events, nil
+}
+
+func (h *EventHub) lastSeq(ctx context.Context) (uint64, error) {
+	iter, err := h.store.Scan(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer iter.Close()
+
+	var last uint64
+	for iter.Next() {
+		if seq, ok := parseEventKey(iter.Key()); ok && seq > last {
+			last = seq
+		}
+	}
+
+	return last, nil
+}
+
+func (h *EventHub) prune(ctx context.Context, before uint64) {
+	iter, err := h.store.Scan(ctx, nil)
+	if err != nil {
+		log.Warnw("scan sector events for pruning", "err", err)
+		return
+	}
+
+	var keys []kvstore.Key
+	for iter.Next() {
+		seq, ok := parseEventKey(iter.Key())
+		if !ok {
+			continue
+		}
+
+		if seq >= before {
+			break
+		}
+
+		keys = append(keys, makeEventKey(seq))
+	}
+
+	iter.Close()
+
+	for _, key := range keys {
+		if err := h.store.Del(ctx, key); err != nil {
+			log.Warnw("prune sector event", "err", err)
+			return
+		}
+	}
+}
+
+// the zero-padded seq keeps the events in order in the store
+func makeEventKey(seq uint64) kvstore.Key {
+	return []byte(fmt.Sprintf("%020d", seq))
+}
+
+func parseEventKey(key kvstore.Key) (uint64, bool) {
+	if len(key) != 20 {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(string(key), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return seq, true
+}
@@ -0,0 +1,70 @@
+package sectors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+)
+
+type flakyKVStore struct {
+	*kvstore.MemKVStore
+	failPut bool
+}
+
+func (s *flakyKVStore) Put(ctx context.Context, key kvstore.Key, val kvstore.Val) error {
+	if s.failPut {
+		return errors.New("disk full")
+	}
+
+	return s.MemKVStore.Put(ctx, key, val)
+}
+
+func TestEventHubSeqAfterFailedPut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := &flakyKVStore{MemKVStore: kvstore.NewMemKVStore()}
+	hub, err := NewEventHub(ctx, store)
+	if err != nil {
+		t.Fatalf("construct event hub: %s", err)
+	}
+
+	sid := abi.SectorID{Miner: 1000, Number: 1}
+	if err := hub.Publish(ctx, api.SectorEvent{Type: api.SectorEventAllocated, ID: sid}); err != nil {
+		t.Fatalf("publish #1: %s", err)
+	}
+
+	store.failPut = true
+	if err := hub.Publish(ctx, api.SectorEvent{Type: api.SectorEventFinalized, ID: sid}); err == nil {
+		t.Fatal("expected an error from the failed put")
+	}
+
+	store.failPut = false
+	if err := hub.Publish(ctx, api.SectorEvent{Type: api.SectorEventFinalized, ID: sid}); err != nil {
+		t.Fatalf("publish #2: %s", err)
+	}
+
+	// all the events are replayed from the store without any gap
+	ch, err := hub.Subscribe(ctx, api.SectorEventFilter{})
+	if err != nil {
+		t.Fatalf("subscribe: %s", err)
+	}
+
+	for _, expected := range []uint64{1, 2} {
+		select {
+		case evt := <-ch:
+			if evt.Seq != expected {
+				t.Fatalf("got event %d, expected %d", evt.Seq, expected)
+			}
+
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not replayed", expected)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package sectors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	miner5 "github.com/filecoin-project/specs-actors/v5/actors/builtin/miner"
+
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin"
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin/miner"
+	specpolicy "github.com/filecoin-project/venus/venus-shared/actors/policy"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/impl/commitmgr"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
+)
+
+var _ api.SectorExtensionManager = (*ExtensionManager)(nil)
+
+func NewExtensionManager(scfg *modules.SafeConfig, capi chain.API, mapi api.MinerInfoAPI, msgClient messager.API) (*ExtensionManager, error) {
+	mgr := &ExtensionManager{
+		scfg:      scfg,
+		capi:      capi,
+		info:      mapi,
+		msgClient: msgClient,
+	}
+
+	mgr.pending.msgs = map[abi.ActorID][]string{}
+	mgr.pending.extending = map[abi.ActorID]struct{}{}
+	return mgr, nil
+}
+
+// ExtensionManager extends the expirations of the sectors which are about to expire
+type ExtensionManager struct {
+	scfg      *modules.SafeConfig
+	capi      chain.API
+	info      api.MinerInfoAPI
+	msgClient messager.API
+
+	pending struct {
+		sync.Mutex
+		msgs map[abi.ActorID][]string
+		// miners whose extension messages are being built & pushed
+		extending map[abi.ActorID]struct{}
+	}
+}
+
+func (e *ExtensionManager) Extend(ctx context.Context, mid abi.ActorID, spec api.ExtendSectorsSpec) ([]api.SectorExtensionMessage, error) {
+	mcfg, err := e.scfg.MinerConfig(mid)
+	if err != nil {
+		return nil, fmt.Errorf("get miner config: %w", err)
+	}
+
+	within := spec.Within
+	if within == 0 {
+		within = durationToEpochs(mcfg.Extension.Window.Std())
+	}
+
+	extension := spec.Extension
+	if extension == 0 {
+		extension = durationToEpochs(mcfg.Extension.Extension.Std())
+	}
+
+	if within <= 0 || extension <= 0 {
+		return nil, fmt.Errorf("invalid extension window %d or target %d", within, extension)
+	}
+
+	from := mcfg.Extension.Sender.Std()
+	if !spec.DryRun && from == address.Undef {
+		return nil, fmt.Errorf("extension sender not configured for miner %d", mid)
+	}
+
+	if !spec.DryRun {
+		if err := e.reserve(ctx, mid); err != nil {
+			return nil, err
+		}
+
+		defer e.unreserve(mid)
+	}
+
+	minfo, err := e.info.Get(ctx, mid)
+	if err != nil {
+		return nil, fmt.Errorf("get miner info: %w", err)
+	}
+
+	ts, err := e.capi.ChainHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get chain head: %w", err)
+	}
+
+	nv, err := e.capi.StateNetworkVersion(ctx, ts.Key())
+	if err != nil {
+		return nil, fmt.Errorf("get network version: %w", err)
+	}
+
+	declMax, err := specpolicy.GetDeclarationsMax(nv)
+	if err != nil {
+		return nil, fmt.Errorf("get max declarations: %w", err)
+	}
+
+	addrMax, err := specpolicy.GetAddressedSectorsMax(nv)
+	if err != nil {
+		return nil, fmt.Errorf("get max addressed sectors: %w", err)
+	}
+
+	head := ts.Height()
+	maxExtension := head + specpolicy.GetMaxSectorExpirationExtension()
+
+	var decls []api.SectorExtension
+	for dlIdx := uint64(0); dlIdx < miner.WPoStPeriodDeadlines; dlIdx++ {
+		partitions, err := e.capi.StateMinerPartitions(ctx, minfo.Addr, dlIdx, ts.Key())
+		if err != nil {
+			return nil, fmt.Errorf("get partitions for deadline %d: %w", dlIdx, err)
+		}
+
+		for partIdx := range partitions {
+			active := partitions[partIdx].ActiveSectors
+			infos, err := e.capi.StateMinerSectors(ctx, minfo.Addr, &active, ts.Key())
+			if err != nil {
+				return nil, fmt.Errorf("get sectors in deadline %d partition %d: %w", dlIdx, partIdx, err)
+			}
+
+			byExpiration := map[abi.ChainEpoch][]abi.SectorNumber{}
+			for _, info := range infos {
+				if info.Expiration < head || info.Expiration > head+within {
+					continue
+				}
+
+				newExp := head + extension
+				if newExp > maxExtension {
+					newExp = maxExtension
+				}
+
+				if maxLifetime := info.Activation + specpolicy.GetSectorMaxLifetime(info.SealProof, nv); newExp > maxLifetime {
+					newExp = maxLifetime
+				}
+
+				if newExp <= info.Expiration {
+					continue
+				}
+
+				byExpiration[newExp] = append(byExpiration[newExp], info.SectorNumber)
+			}
+
+			for newExp, nums := range byExpiration {
+				sort.Slice(nums, func(i, j int) bool {
+					return nums[i] < nums[j]
+				})
+
+				decls = append(decls, api.SectorExtension{
+					Deadline:      dlIdx,
+					Partition:     uint64(partIdx),
+					Sectors:       nums,
+					NewExpiration: newExp,
+				})
+			}
+		}
+	}
+
+	sort.Slice(decls, func(i, j int) bool {
+		if decls[i].Deadline != decls[j].Deadline {
+			return decls[i].Deadline < decls[j].Deadline
+		}
+
+		if decls[i].Partition != decls[j].Partition {
+			return decls[i].Partition < decls[j].Partition
+		}
+
+		return decls[i].NewExpiration < decls[j].NewExpiration
+	})
+
+	msgs := batchExtensions(decls, declMax, addrMax)
+	if spec.DryRun || len(msgs) == 0 {
+		return msgs, nil
+	}
+
+	var msgSpec messager.MsgMeta
+	msgSpec.GasOverEstimation = mcfg.Extension.GasOverEstimation
+	msgSpec.MaxFeeCap = mcfg.Extension.MaxFeeCap.Std()
+
+	mlog := log.With("miner", mid, "proc", "extension")
+	defer e.addPending(mid, msgs)
+
+	for i := range msgs {
+		params := extensionParams(msgs[i].Extensions)
+
+		enc := new(bytes.Buffer)
+		if err := params.MarshalCBOR(enc); err != nil {
+			return msgs, fmt.Errorf("serialize extension params: %w", err)
+		}
+
+		mcid, err := commitmgr.PushMessage(ctx, from, mid, big.Zero(), miner.Methods.ExtendSectorExpiration, e.msgClient, msgSpec, enc.Bytes(), mlog)
+		if err != nil {
+			return msgs, fmt.Errorf("push extension message: %w", err)
+		}
+
+		msgs[i].MessageID = mcid.String()
+		mlog.Infow("extension message pushed", "msgid", msgs[i].MessageID, "declarations", len(msgs[i].Extensions))
+	}
+
+	return msgs, nil
+}
+
+// Run extends the sectors for the miners with automatic extension enabled periodically
+func (e *ExtensionManager) Run(ctx context.Context) {
+	log.Info("extension manager loop start")
+	defer log.Info("extension manager loop stop")
+
+	wait := time.Minute
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-time.After(wait):
+		}
+
+		wait = e.extendAll(ctx)
+	}
+}
+
+func (e *ExtensionManager) extendAll(ctx context.Context) time.Duration {
+	e.scfg.Lock()
+	miners := e.scfg.Miners
+	e.scfg.Unlock()
+
+	next := time.Hour
+	for mi := range miners {
+		mcfg := miners[mi].Extension
+		if !mcfg.Enabled {
+			continue
+		}
+
+		if interval := mcfg.CheckInterval.Std(); interval > 0 && interval < next {
+			next = interval
+		}
+
+		mid := miners[mi].Actor
+		mlog := log.With("miner", mid, "proc", "extension")
+
+		if e.hasPending(ctx, mid) {
+			mlog.Debug("previous extension messages are still pending")
+			continue
+		}
+
+		if _, err := e.Extend(ctx, mid, api.ExtendSectorsSpec{}); err != nil {
+			mlog.Errorf("extend sectors: %s", err)
+		}
+	}
+
+	return next
+}
+
+// reserve marks the miner as being extended, the concurrent extensions for the same miner would push duplicate messages
+func (e *ExtensionManager) reserve(ctx context.Context, mid abi.ActorID) error {
+	e.pending.Lock()
+	defer e.pending.Unlock()
+
+	if _, ok := e.pending.extending[mid]; ok {
+		return fmt.Errorf("extension for miner %d is in progress", mid)
+	}
+
+	if e.checkPending(ctx, mid) {
+		return fmt.Errorf("previous extension messages for miner %d are still pending", mid)
+	}
+
+	e.pending.extending[mid] = struct{}{}
+	return nil
+}
+
+func (e *ExtensionManager) unreserve(mid abi.ActorID) {
+	e.pending.Lock()
+	delete(e.pending.extending, mid)
+	e.pending.Unlock()
+}
+
+func (e *ExtensionManager) addPending(mid abi.ActorID, msgs []api.SectorExtensionMessage) {
+	e.pending.Lock()
+	defer e.pending.Unlock()
+
+	for _, msg := range msgs {
+		if msg.MessageID != "" {
+			e.pending.msgs[mid] = append(e.pending.msgs[mid], msg.MessageID)
+		}
+	}
+}
+
+// hasPending checks if any of the previous extension messages is still waiting to be landed,
+// new messages built upon the current chain state would duplicate them.
+func (e *ExtensionManager) hasPending(ctx context.Context, mid abi.ActorID) bool {
+	e.pending.Lock()
+	defer e.pending.Unlock()
+
+	if _, ok := e.pending.extending[mid]; ok {
+		return true
+	}
+
+	return e.checkPending(ctx, mid)
+}
+
+// checkPending should be called with the pending lock held
+func (e *ExtensionManager) checkPending(ctx context.Context, mid abi.ActorID) bool {
+	var pending []string
+	for _, msgID := range e.pending.msgs[mid] {
+		msg, err := e.msgClient.GetMessageByUid(ctx, msgID)
+		if err != nil {
+			log.Warnw("get extension message", "miner", mid, "msgid", msgID, "err", err)
+			pending = append(pending, msgID)
+			continue
+		}
+
+		switch msg.State {
+		case messager.MessageState.OnChainMsg, messager.MessageState.FailedMsg, messager.MessageState.ReplacedMsg:
+		default:
+			pending = append(pending, msgID)
+		}
+	}
+
+	if len(pending) == 0 {
+		delete(e.pending.msgs, mid)
+		return false
+	}
+
+	e.pending.msgs[mid] = pending
+	return true
+}
+
+// batchExtensions packs the declarations into messages within the chain limits
+func batchExtensions(decls []api.SectorExtension, declMax, addrMax int) []api.SectorExtensionMessage {
+	sizes := make([]int, len(decls))
+	for i := range decls {
+		sizes[i] = len(decls[i].Sectors)
+	}
+
+	batches := batchDeclarations(sizes, declMax, addrMax)
+	msgs := make([]api.SectorExtensionMessage, len(batches))
+	for i, parts := range batches {
+		for _, p := range parts {
+			part := decls[p.decl]
+			part.Sectors = part.Sectors[p.start:p.end]
+			msgs[i].Extensions = append(msgs[i].Extensions, part)
+		}
+	}
+
+	return msgs
+}
+
+func extensionParams(decls []api.SectorExtension) *miner5.ExtendSectorExpirationParams {
+	params := &miner5.ExtendSectorExpirationParams{
+		Extensions: make([]miner5.ExpirationExtension, 0, len(decls)),
+	}
+
+	for _, decl := range decls {
+		nums := make([]uint64, len(decl.Sectors))
+		for i := range decl.Sectors {
+			nums[i] = uint64(decl.Sectors[i])
+		}
+
+		params.Extensions = append(params.Extensions, miner5.ExpirationExtension{
+			Deadline:      decl.Deadline,
+			Partition:     decl.Partition,
+			Sectors:       bitfield.NewFromSet(nums),
+			NewExpiration: decl.NewExpiration,
+		})
+	}
+
+	return params
+}
+
+func durationToEpochs(d time.Duration) abi.ChainEpoch {
+	return abi.ChainEpoch(d / (builtin.EpochDurationSeconds * time.Second))
+}
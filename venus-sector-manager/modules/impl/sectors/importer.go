@@ -0,0 +1,191 @@
+package sectors
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus/venus-shared/types"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+var _ api.SectorImporter = (*Importer)(nil)
+
+func NewImporter(capi chain.API, mapi api.MinerInfoAPI, state api.SectorStateManager, indexer api.SectorIndexer, numAlloc api.SectorNumberAllocator) (*Importer, error) {
+	return &Importer{
+		capi:     capi,
+		info:     mapi,
+		state:    state,
+		indexer:  indexer,
+		numAlloc: numAlloc,
+	}, nil
+}
+
+// Importer builds the sector states & indexes for the sectors sealed by other deployments
+type Importer struct {
+	capi     chain.API
+	info     api.MinerInfoAPI
+	state    api.SectorStateManager
+	indexer  api.SectorIndexer
+	numAlloc api.SectorNumberAllocator
+}
+
+func (i *Importer) Import(ctx context.Context, sectors []api.SectorImportInfo, override bool) ([]api.SectorImportResult, error) {
+	ts, err := i.capi.ChainHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get chain head: %w", err)
+	}
+
+	results := make([]api.SectorImportResult, len(sectors))
+	maxNums := map[abi.ActorID]abi.SectorNumber{}
+	for si := range sectors {
+		sid := sectors[si].ID
+		results[si].ID = sid
+
+		imported, err := i.importOne(ctx, ts.Key(), sectors[si], override)
+		if err != nil {
+			log.Warnw("import sector", "miner", sid.Miner, "num", sid.Number, "err", err)
+			results[si].Reason = err.Error()
+			continue
+		}
+
+		if !imported {
+			results[si].Reason = "already exists"
+			continue
+		}
+
+		results[si].Imported = true
+		if sid.Number > maxNums[sid.Miner] {
+			maxNums[sid.Miner] = sid.Number
+		}
+	}
+
+	for mid, num := range maxNums {
+		if _, err := i.numAlloc.Advance(ctx, mid, uint64(num)); err != nil {
+			return results, fmt.Errorf("advance sector number for %d to %d: %w", mid, num, err)
+		}
+	}
+
+	return results, nil
+}
+
+func (i *Importer) importOne(ctx context.Context, tsk types.TipSetKey, info api.SectorImportInfo, override bool) (bool, error) {
+	sid := info.ID
+	if info.AccessInstance == "" {
+		return false, fmt.Errorf("access instance required")
+	}
+
+	minfo, err := i.info.Get(ctx, sid.Miner)
+	if err != nil {
+		return false, fmt.Errorf("get miner info: %w", err)
+	}
+
+	onChain, err := i.capi.StateSectorGetInfo(ctx, minfo.Addr, sid.Number, tsk)
+	if err != nil {
+		return false, fmt.Errorf("get sector info: %w", err)
+	}
+
+	if onChain == nil {
+		return false, fmt.Errorf("sector not found on chain")
+	}
+
+	if info.SectorType != 0 && info.SectorType != onChain.SealProof {
+		return false, fmt.Errorf("seal proof type mismatch, %d on chain", onChain.SealProof)
+	}
+
+	ssize, err := onChain.SealProof.SectorSize()
+	if err != nil {
+		return false, fmt.Errorf("get sector size: %w", err)
+	}
+
+	store, err := i.indexer.StoreMgr().GetInstance(ctx, info.AccessInstance)
+	if err != nil {
+		return false, fmt.Errorf("get objstore instance %s: %w", info.AccessInstance, err)
+	}
+
+	// sectors upgraded by snapdeals should be proved with the updated replica
+	upgraded := onChain.SectorKeyCID != nil
+	if err := checkSectorFiles(ctx, store, sid, ssize, upgraded); err != nil {
+		return false, err
+	}
+
+	state := api.SectorState{
+		ID:         sid,
+		SectorType: onChain.SealProof,
+		Ticket:     info.Ticket,
+		Seed:       info.Seed,
+		Pre: &api.PreCommitInfo{
+			CommR: onChain.SealedCID,
+			Deals: onChain.DealIDs,
+		},
+		MessageInfo: api.MessageInfo{
+			PreCommitCid: info.PreCommitCid,
+			CommitCid:    info.CommitCid,
+		},
+		Finalized: true,
+		Imported:  true,
+	}
+
+	if info.CommD != nil {
+		state.Pre.CommD = *info.CommD
+	}
+
+	if info.Ticket != nil {
+		state.Pre.Ticket = *info.Ticket
+	}
+
+	if upgraded {
+		state.Pre.CommR = *onChain.SectorKeyCID
+		state.Upgraded = true
+		state.UpgradedInfo = &api.ReplicaUpdateInfo{
+			NewSealedCID: onChain.SealedCID,
+		}
+	}
+
+	for _, dealID := range onChain.DealIDs {
+		state.Deals = append(state.Deals, api.DealInfo{ID: dealID})
+	}
+
+	imported, err := i.state.Import(ctx, state, override)
+	if err != nil {
+		return false, fmt.Errorf("save sector state: %w", err)
+	}
+
+	if !imported {
+		return false, nil
+	}
+
+	var indexer api.SectorTypedIndexer = i.indexer
+	if upgraded {
+		indexer = i.indexer.Upgrade()
+	}
+
+	if err := indexer.Update(ctx, sid, info.AccessInstance); err != nil {
+		return false, fmt.Errorf("update sector index: %w", err)
+	}
+
+	return true, nil
+}
+
+func checkSectorFiles(ctx context.Context, store objstore.Store, sid abi.SectorID, ssize abi.SectorSize, upgraded bool) error {
+	subCache, subSealed := sectorPaths(sid, upgraded)
+	toCheck := map[string]int64{
+		subSealed:                        0,
+		filepath.Join(subCache, "p_aux"): 0,
+	}
+	addCachePathsForSectorSize(toCheck, subCache, ssize)
+
+	for p := range toCheck {
+		if _, err := store.Stat(ctx, p); err != nil {
+			return fmt.Errorf("get %s stat info for %s: %w", p, util.FormatSectorID(sid), err)
+		}
+	}
+
+	return nil
+}
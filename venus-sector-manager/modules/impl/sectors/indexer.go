@@ -3,10 +3,13 @@ package sectors
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/filecoin-project/go-state-types/abi"
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
 )
@@ -14,21 +17,128 @@ import (
 var _ api.SectorIndexer = (*Indexer)(nil)
 
 func NewIndexer(storeMgr objstore.Manager, kv kvstore.KVStore) (*Indexer, error) {
+	upgradeKV, err := kvstore.NewWrappedKVStore([]byte("upgrade"), kv)
+	if err != nil {
+		return nil, err
+	}
+
+	normal, err := newTypedIndexer(kv)
+	if err != nil {
+		return nil, err
+	}
+
+	upgrade, err := newTypedIndexer(upgradeKV)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Indexer{
 		storeMgr: storeMgr,
-		kv:       kv,
+		normal:   normal,
+		upgrade:  upgrade,
 	}, nil
 }
 
 type Indexer struct {
 	storeMgr objstore.Manager
-	kv       kvstore.KVStore
+	normal   *typedIndexer
+	upgrade  *typedIndexer
 }
 
 func (i *Indexer) Find(ctx context.Context, sid abi.SectorID) (string, bool, error) {
+	return i.normal.Find(ctx, sid)
+}
+
+func (i *Indexer) Update(ctx context.Context, sid abi.SectorID, instance string) error {
+	return i.normal.Update(ctx, sid, instance)
+}
+
+func (i *Indexer) Sectors(ctx context.Context, instance string) ([]abi.SectorID, error) {
+	return i.normal.Sectors(ctx, instance)
+}
+
+func (i *Indexer) Upgrade() api.SectorTypedIndexer {
+	return i.upgrade
+}
+
+func (i *Indexer) StoreMgr() objstore.Manager {
+	return i.storeMgr
+}
+
+var _ api.SectorTypedIndexer = (*typedIndexer)(nil)
+
+// the marker of the reverse index being built from the existing entries
+var reverseBuiltKey = kvstore.Key("reverse-built")
+
+func newTypedIndexer(kv kvstore.KVStore) (*typedIndexer, error) {
+	reverse, err := kvstore.NewWrappedKVStore([]byte("reverse"), kv)
+	if err != nil {
+		return nil, err
+	}
+
+	ti := &typedIndexer{
+		kv:      kv,
+		reverse: reverse,
+	}
+
+	if err := ti.buildReverse(context.Background()); err != nil {
+		return nil, fmt.Errorf("build reverse index: %w", err)
+	}
+
+	return ti, nil
+}
+
+// typedIndexer maps the sectors to the instances, and keeps a reverse index of instance/sector for the lookups by instance
+type typedIndexer struct {
+	kv      kvstore.KVStore
+	reverse kvstore.KVStore
+	mu      sync.Mutex
+}
+
+func makeReverseKey(instance string, sid abi.SectorID) kvstore.Key {
+	return append(makeReversePrefix(instance), makeSectorKey(sid)...)
+}
+
+func makeReversePrefix(instance string) kvstore.Prefix {
+	return kvstore.Prefix(instance + "/")
+}
+
+// buildReverse fills the reverse index for the entries written before it is introduced, only once
+func (ti *typedIndexer) buildReverse(ctx context.Context) error {
+	built, err := ti.kv.Has(ctx, reverseBuiltKey)
+	if err != nil {
+		return err
+	}
+
+	if built {
+		return nil
+	}
+
+	entries := map[abi.SectorID]string{}
+	if err := ti.ForEach(ctx, func(sid abi.SectorID, instance string) error {
+		entries[sid] = instance
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for sid, instance := range entries {
+		if err := ti.reverse.Put(ctx, makeReverseKey(instance, sid), []byte{}); err != nil {
+			return err
+		}
+	}
+
+	if len(entries) > 0 {
+		log.Infof("reverse index built for %d sectors", len(entries))
+	}
+
+	return ti.kv.Put(ctx, reverseBuiltKey, []byte{})
+}
+
+func (ti *typedIndexer) Find(ctx context.Context, sid abi.SectorID) (string, bool, error) {
 	var s string
 	// string(b) will copy the underlying bytes, so we use View here
-	err := i.kv.View(ctx, makeSectorKey(sid), func(b []byte) error {
+	err := ti.kv.View(ctx, makeSectorKey(sid), func(b []byte) error {
 		s = string(b)
 		return nil
 	})
@@ -44,10 +154,88 @@ func (i *Indexer) Find(ctx context.Context, sid abi.SectorID) (string, bool, err
 	return s, true, nil
 }
 
-func (i *Indexer) Update(ctx context.Context, sid abi.SectorID, instance string) error {
-	return i.kv.Put(ctx, makeSectorKey(sid), []byte(instance))
+func (ti *typedIndexer) Update(ctx context.Context, sid abi.SectorID, instance string) error {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	prev, found, err := ti.Find(ctx, sid)
+	if err != nil {
+		return err
+	}
+
+	if err := ti.kv.Put(ctx, makeSectorKey(sid), []byte(instance)); err != nil {
+		return err
+	}
+
+	// a stale reverse entry left by a failure here will be filtered out in Sectors
+	if found && prev != instance {
+		if err := ti.reverse.Del(ctx, makeReverseKey(prev, sid)); err != nil && !errors.Is(err, kvstore.ErrKeyNotFound) {
+			return fmt.Errorf("delete reverse index in %s: %w", prev, err)
+		}
+	}
+
+	return ti.reverse.Put(ctx, makeReverseKey(instance, sid), []byte{})
 }
 
-func (i *Indexer) StoreMgr() objstore.Manager {
-	return i.storeMgr
+func (ti *typedIndexer) Sectors(ctx context.Context, instance string) ([]abi.SectorID, error) {
+	prefix := makeReversePrefix(instance)
+	iter, err := ti.reverse.Scan(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []abi.SectorID
+	for iter.Next() {
+		key := iter.Key()
+		if sid, ok := parseSectorKey(key[len(prefix):]); ok {
+			candidates = append(candidates, sid)
+		}
+	}
+
+	iter.Close()
+
+	sids := make([]abi.SectorID, 0, len(candidates))
+	for _, sid := range candidates {
+		current, found, err := ti.Find(ctx, sid)
+		if err != nil {
+			return nil, fmt.Errorf("find index of %s: %w", util.FormatSectorID(sid), err)
+		}
+
+		if found && current == instance {
+			sids = append(sids, sid)
+		}
+	}
+
+	return sids, nil
+}
+
+func (ti *typedIndexer) ForEach(ctx context.Context, fn func(abi.SectorID, string) error) error {
+	// the keys of the other typed indexers are wrapped with their own prefixes
+	iter, err := ti.kv.Scan(ctx, kvstore.Prefix("m-"))
+	if err != nil {
+		return err
+	}
+
+	defer iter.Close()
+
+	for iter.Next() {
+		sid, ok := parseSectorKey(iter.Key())
+		if !ok {
+			continue
+		}
+
+		var instance string
+		if err := iter.View(ctx, func(b []byte) error {
+			instance = string(b)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("view index of %s: %w", util.FormatSectorID(sid), err)
+		}
+
+		if err := fn(sid, instance); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
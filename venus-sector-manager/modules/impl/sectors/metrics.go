@@ -0,0 +1,67 @@
+package sectors
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
+)
+
+// RunStateMetrics counts the online sectors periodically, until the ctx is done.
+// The offline ones are not counted, since they only grow and would make the scan more and more expensive.
+func RunStateMetrics(ctx context.Context, sm *StateManager, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		countSectorStates(ctx, sm)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func countSectorStates(ctx context.Context, sm *StateManager) {
+	counts, err := sm.countByMiner(ctx, api.WorkerOnline)
+	if err != nil {
+		log.Warnw("count sector states", "state", api.WorkerOnline, "err", err)
+		return
+	}
+
+	// drop the miners without any online sector left
+	metrics.Sectors.Reset()
+	for mid, count := range counts {
+		metrics.Sectors.WithLabelValues(strconv.FormatUint(uint64(mid), 10), string(api.WorkerOnline)).Set(float64(count))
+	}
+}
+
+// countByMiner counts the sectors of each miner, only the keys are scanned
+func (sm *StateManager) countByMiner(ctx context.Context, ws api.SectorWorkerState) (map[abi.ActorID]int, error) {
+	store, err := sm.workerStore(ws)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := store.Scan(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	counts := map[abi.ActorID]int{}
+	for iter.Next() {
+		if sid, ok := parseSectorKey(iter.Key()); ok {
+			counts[sid.Miner]++
+		}
+	}
+
+	return counts, nil
+}
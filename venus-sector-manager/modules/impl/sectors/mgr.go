@@ -1,16 +1,20 @@
 package sectors
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
-	"math/rand"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/filecoin-project/go-state-types/abi"
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/confmgr"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
 )
 
@@ -25,14 +29,27 @@ func NewManager(
 	locker confmgr.RLocker,
 	mapi api.MinerInfoAPI,
 	numAlloc api.SectorNumberAllocator,
+	state api.SectorStateManager,
+	quota kvstore.KVStore,
 ) (*Manager, error) {
 	mgr := &Manager{
 		info:     mapi,
 		numAlloc: numAlloc,
+		state:    state,
+		quota:    quota,
 	}
 
 	mgr.cfg.Config = cfg
 	mgr.cfg.RLocker = locker
+	mgr.sched.weights = map[abi.ActorID]int64{}
+	mgr.sched.daily = map[abi.ActorID]uint{}
+
+	inflight, err := mgr.inflightSectors(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("count in-flight sectors: %w", err)
+	}
+
+	mgr.sched.inflight = inflight
 	return mgr, nil
 }
 
@@ -49,6 +66,21 @@ type Manager struct {
 
 	info     api.MinerInfoAPI
 	numAlloc api.SectorNumberAllocator
+	state    api.SectorStateManager
+
+	// persisted daily allocation counters, keyed by the UTC day
+	quota kvstore.KVStore
+
+	// smooth weighted round-robin among the miners, with the cached daily allocation counters
+	// and the in-flight counters, which are loaded from the online states once and maintained by
+	// Allocate, Acquire & Release afterwards
+	sched struct {
+		sync.Mutex
+		weights  map[abi.ActorID]int64
+		day      string
+		daily    map[abi.ActorID]uint
+		inflight map[abi.ActorID]uint
+	}
 }
 
 func (m *Manager) Allocate(ctx context.Context, allowedMiners []abi.ActorID, allowedProofs []abi.RegisteredSealProof) (*api.AllocatedSector, error) {
@@ -93,53 +125,50 @@ func (m *Manager) Allocate(ctx context.Context, allowedMiners []abi.ActorID, all
 
 	wg.Wait()
 
-	// TODO: trace errors
-	last := len(miners)
-	i := 0
-	for i < last {
-		minfo := infos[i]
-		ok := minfo != nil
-		if ok && len(allowedMiners) > 0 {
-			should := false
-			for ai := range allowedMiners {
-				if minfo.info.ID == allowedMiners[ai] {
-					should = true
-					break
-				}
-			}
+	skipped := map[abi.ActorID]string{}
+	for i := range miners {
+		if errs[i] != nil {
+			skipped[miners[i].Actor] = errs[i].Error()
+		}
+	}
+
+	m.sched.Lock()
+	defer m.sched.Unlock()
 
-			ok = should
+	today := time.Now().UTC().Format("2006-01-02")
+	if m.sched.day != today {
+		m.sched.day = today
+		m.sched.daily = map[abi.ActorID]uint{}
+		m.pruneDailyCounters(ctx, today)
+	}
+
+	candidates := make([]*minerCandidate, 0, midCnt)
+	for _, minfo := range infos {
+		if minfo == nil {
+			continue
 		}
 
-		if ok && len(allowedProofs) > 0 {
-			should := false
-			for ai := range allowedProofs {
-				if minfo.info.SealProofType == allowedProofs[ai] {
-					should = true
-					break
-				}
+		if minfo.cfg.DailyQuota > 0 {
+			if err := m.loadDailyCounter(ctx, minfo.info.ID); err != nil {
+				return nil, fmt.Errorf("load daily allocation counter for %d: %w", minfo.info.ID, err)
 			}
-
-			ok = should
 		}
 
-		if !ok {
-			infos[i], infos[last-1] = infos[last-1], infos[i]
-			last--
+		if reason := m.checkCandidate(minfo, allowedMiners, allowedProofs); reason != "" {
+			skipped[minfo.info.ID] = reason
 			continue
 		}
 
-		i++
+		candidates = append(candidates, minfo)
 	}
 
-	candidates := infos[:last]
-	for {
-		candidateCount := len(candidates)
-		if candidateCount == 0 {
-			return nil, nil
-		}
+	// keep the order stable, so that the selection is deterministic
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].info.ID < candidates[j].info.ID
+	})
 
-		selectIdx := rand.Intn(candidateCount)
+	for len(candidates) > 0 {
+		selectIdx := m.selectCandidate(candidates)
 		selected := candidates[selectIdx]
 
 		var check func(uint64) bool
@@ -162,6 +191,12 @@ func (m *Manager) Allocate(ctx context.Context, allowedMiners []abi.ActorID, all
 		}
 
 		if available {
+			if err := m.incrDailyCounter(ctx, selected.info.ID); err != nil {
+				return nil, fmt.Errorf("update daily allocation counter for %d: %w", selected.info.ID, err)
+			}
+
+			m.sched.inflight[selected.info.ID]++
+
 			return &api.AllocatedSector{
 				ID: abi.SectorID{
 					Miner:  selected.info.ID,
@@ -171,7 +206,161 @@ func (m *Manager) Allocate(ctx context.Context, allowedMiners []abi.ActorID, all
 			}, nil
 		}
 
-		candidates[candidateCount-1], candidates[selectIdx] = candidates[selectIdx], candidates[candidateCount-1]
-		candidates = candidates[:candidateCount-1]
+		skipped[selected.info.ID] = fmt.Sprintf("max number %d exceeded", *selected.cfg.MaxNumber)
+		candidates = append(candidates[:selectIdx], candidates[selectIdx+1:]...)
+	}
+
+	if len(skipped) > 0 {
+		log.Warnw("no miner available for sector allocation", "skipped", skipped)
+	}
+
+	return nil, nil
+}
+
+// checkCandidate returns the reason why the miner should be skipped, or an empty string if it is allowed
+func (m *Manager) checkCandidate(minfo *minerCandidate, allowedMiners []abi.ActorID, allowedProofs []abi.RegisteredSealProof) string {
+	if len(allowedMiners) > 0 && !containsActorID(allowedMiners, minfo.info.ID) {
+		return "not in allowed miners"
+	}
+
+	if len(allowedProofs) > 0 && !containsProofType(allowedProofs, minfo.info.SealProofType) {
+		return fmt.Sprintf("proof type %d not allowed", minfo.info.SealProofType)
+	}
+
+	if max := minfo.cfg.MaxInFlight; max > 0 && m.sched.inflight[minfo.info.ID] >= max {
+		return fmt.Sprintf("in-flight sectors %d reach the limit %d", m.sched.inflight[minfo.info.ID], max)
+	}
+
+	if quota := minfo.cfg.DailyQuota; quota > 0 && m.sched.daily[minfo.info.ID] >= quota {
+		return fmt.Sprintf("daily quota %d used up", quota)
+	}
+
+	return ""
+}
+
+// loadDailyCounter fills the cached counter of the miner from the kvstore, should be called with sched locked
+func (m *Manager) loadDailyCounter(ctx context.Context, mid abi.ActorID) error {
+	if _, ok := m.sched.daily[mid]; ok {
+		return nil
+	}
+
+	var count uint64
+	err := m.quota.View(ctx, makeDailyCounterKey(m.sched.day, mid), func(data []byte) error {
+		num, read := binary.Uvarint(data)
+		if read != len(data) {
+			return fmt.Errorf("raw data is not a valid uvarint: %v", data)
+		}
+
+		count = num
+		return nil
+	})
+
+	if err != nil && err != kvstore.ErrKeyNotFound {
+		return err
+	}
+
+	m.sched.daily[mid] = uint(count)
+	return nil
+}
+
+// incrDailyCounter should be called with sched locked
+func (m *Manager) incrDailyCounter(ctx context.Context, mid abi.ActorID) error {
+	if err := m.loadDailyCounter(ctx, mid); err != nil {
+		return err
+	}
+
+	count := m.sched.daily[mid] + 1
+	data := make([]byte, binary.MaxVarintLen64)
+	written := binary.PutUvarint(data, uint64(count))
+	if err := m.quota.Put(ctx, makeDailyCounterKey(m.sched.day, mid), data[:written]); err != nil {
+		return err
+	}
+
+	m.sched.daily[mid] = count
+	return nil
+}
+
+// pruneDailyCounters removes the counters of the past days, should be called with sched locked
+func (m *Manager) pruneDailyCounters(ctx context.Context, today string) {
+	iter, err := m.quota.Scan(ctx, nil)
+	if err != nil {
+		log.Warnw("scan daily allocation counters", "err", err)
+		return
+	}
+
+	current := makeDailyCounterPrefix(today)
+	var outdated []kvstore.Key
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, current) {
+			outdated = append(outdated, append(kvstore.Key(nil), key...))
+		}
+	}
+
+	iter.Close()
+
+	for _, key := range outdated {
+		if err := m.quota.Del(ctx, key); err != nil {
+			log.Warnw("remove outdated daily allocation counter", "key", string(key), "err", err)
+		}
 	}
 }
+
+// selectCandidate picks a candidate with the smooth weighted round-robin algorithm, should be called with sched locked
+func (m *Manager) selectCandidate(candidates []*minerCandidate) int {
+	var total int64
+	selectIdx := 0
+	for i, c := range candidates {
+		weight := int64(c.cfg.Weight)
+		if weight == 0 {
+			weight = 1
+		}
+
+		total += weight
+		m.sched.weights[c.info.ID] += weight
+		if m.sched.weights[c.info.ID] > m.sched.weights[candidates[selectIdx].info.ID] {
+			selectIdx = i
+		}
+	}
+
+	m.sched.weights[candidates[selectIdx].info.ID] -= total
+	return selectIdx
+}
+
+// Acquire counts a sector which is moved back into the online store, e.g. restored or upgraded
+func (m *Manager) Acquire(ctx context.Context, sid abi.SectorID) {
+	m.sched.Lock()
+	m.sched.inflight[sid.Miner]++
+	m.sched.Unlock()
+}
+
+// Release uncounts a sector which has left the online store, or is allocated but never initialized
+func (m *Manager) Release(ctx context.Context, sid abi.SectorID) {
+	m.sched.Lock()
+	if m.sched.inflight[sid.Miner] > 0 {
+		m.sched.inflight[sid.Miner]--
+	}
+	m.sched.Unlock()
+}
+
+func (m *Manager) inflightSectors(ctx context.Context) (map[abi.ActorID]uint, error) {
+	counts := map[abi.ActorID]uint{}
+	err := m.state.ForEach(ctx, api.WorkerOnline, func(state api.SectorState) error {
+		counts[state.ID.Miner]++
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func makeDailyCounterPrefix(day string) kvstore.Prefix {
+	return []byte(fmt.Sprintf("%s/", day))
+}
+
+func makeDailyCounterKey(day string, mid abi.ActorID) kvstore.Key {
+	return []byte(fmt.Sprintf("%s/m-%d", day, mid))
+}
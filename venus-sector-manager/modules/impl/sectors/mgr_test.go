@@ -0,0 +1,122 @@
+package sectors
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+)
+
+type staticMinerInfo struct{}
+
+func (staticMinerInfo) Get(ctx context.Context, mid abi.ActorID) (*api.MinerInfo, error) {
+	return &api.MinerInfo{
+		ID:            mid,
+		SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+	}, nil
+}
+
+type seqNumbers struct {
+	api.SectorNumberAllocator
+	mu   sync.Mutex
+	next uint64
+}
+
+func (s *seqNumbers) Next(ctx context.Context, mid abi.ActorID, initNum uint64, check func(uint64) bool) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	return s.next, true, nil
+}
+
+type onlineStates struct {
+	api.SectorStateManager
+	online []abi.SectorID
+}
+
+func (s *onlineStates) ForEach(ctx context.Context, ws api.SectorWorkerState, fn func(api.SectorState) error) error {
+	if ws != api.WorkerOnline {
+		return nil
+	}
+
+	for _, sid := range s.online {
+		if err := fn(api.SectorState{ID: sid}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newTestManager(t *testing.T, mid abi.ActorID, maxInFlight uint, online []abi.SectorID) *Manager {
+	cfg := modules.DefaultConfig(false)
+	cfg.Miners = append(cfg.Miners, modules.MinerConfig{
+		Actor: mid,
+		Sector: modules.MinerSectorConfig{
+			Enabled:     true,
+			MaxInFlight: maxInFlight,
+		},
+	})
+
+	mgr, err := NewManager(&cfg, &sync.Mutex{}, staticMinerInfo{}, &seqNumbers{}, &onlineStates{online: online}, kvstore.NewMemKVStore())
+	if err != nil {
+		t.Fatalf("construct manager: %s", err)
+	}
+
+	return mgr
+}
+
+func TestManagerMaxInFlight(t *testing.T) {
+	const mid = abi.ActorID(1000)
+	ctx := context.Background()
+
+	// one of the online sectors belongs to another miner
+	mgr := newTestManager(t, mid, 4, []abi.SectorID{{Miner: mid, Number: 1}, {Miner: 1001, Number: 1}})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var allocated []*api.AllocatedSector
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sector, err := mgr.Allocate(ctx, nil, nil)
+			if err != nil {
+				t.Errorf("allocate: %s", err)
+				return
+			}
+
+			if sector != nil {
+				mu.Lock()
+				allocated = append(allocated, sector)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if len(allocated) != 3 {
+		t.Fatalf("%d sectors allocated concurrently, expected 3", len(allocated))
+	}
+
+	// released by finalizing or aborting
+	mgr.Release(ctx, allocated[0].ID)
+	if sector, err := mgr.Allocate(ctx, nil, nil); err != nil || sector == nil {
+		t.Fatalf("expected a sector allocated after the release, got %v, %v", sector, err)
+	}
+
+	// acquired by restoring
+	mgr.Release(ctx, allocated[1].ID)
+	mgr.Acquire(ctx, allocated[1].ID)
+	if sector, err := mgr.Allocate(ctx, nil, nil); err != nil || sector != nil {
+		t.Fatalf("expected no sector allocated after the acquire, got %v, %v", sector, err)
+	}
+}
@@ -4,26 +4,48 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"sync"
 
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/go-state-types/abi"
 
+	"github.com/filecoin-project/venus/venus-shared/types"
+
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
 )
 
 var _ api.SectorNumberAllocator = (*NumberAllocator)(nil)
 
-func NewNumerAllocator(store kvstore.KVStore) (*NumberAllocator, error) {
+func NewNumerAllocator(store kvstore.KVStore, scfg *modules.SafeConfig, capi chain.API, state api.SectorStateManager) (*NumberAllocator, error) {
 	return &NumberAllocator{
 		store:  store,
+		scfg:   scfg,
+		capi:   capi,
+		state:  state,
 		locker: newSectorsLocker(),
+		local:  map[abi.ActorID]*bitfield.BitField{},
+		chain:  map[abi.ActorID]*bitfield.BitField{},
 	}, nil
 }
 
+// NumberAllocator hands out sector numbers, skipping the ones used by the local sector states,
+// or already allocated on chain
 type NumberAllocator struct {
 	store kvstore.KVStore
+	scfg  *modules.SafeConfig
+	capi  chain.API
+	state api.SectorStateManager
 
 	locker *sectorsLocker
+
+	// numbers taken locally or allocated on chain, accessed with the miner locked
+	takenMu sync.Mutex
+	local   map[abi.ActorID]*bitfield.BitField
+	chain   map[abi.ActorID]*bitfield.BitField
 }
 
 func (na *NumberAllocator) Next(ctx context.Context, mid abi.ActorID, initNum uint64, check func(uint64) bool) (uint64, bool, error) {
@@ -34,7 +56,265 @@ func (na *NumberAllocator) Next(ctx context.Context, mid abi.ActorID, initNum ui
 
 	defer lock.unlock()
 
-	key := []byte(fmt.Sprintf("/m-%d", mid))
+	// the allocated sectors on chain are refreshed for every allocation,
+	// the counter could be behind them, e.g. after the local db is lost
+	taken, err := na.takenNumbers(ctx, mid, false, true)
+	if err != nil {
+		return 0, false, fmt.Errorf("load taken numbers for %d: %w", mid, err)
+	}
+
+	key := makeNumberKey(mid)
+	current, found, err := na.load(ctx, key)
+	if err != nil {
+		return 0, false, fmt.Errorf("fetch current number for %d: %w", mid, err)
+	}
+
+	if !found {
+		current = initNum
+	}
+
+	start := current + 1
+	if mcfg, err := na.scfg.MinerConfig(mid); err == nil && mcfg.Sector.FillHoles {
+		start = initNum + 1
+	}
+
+	next, err := nextUntaken(taken, start)
+	if err != nil {
+		return 0, false, fmt.Errorf("find next available number for %d: %w", mid, err)
+	}
+
+	if !check(next) {
+		return next, false, nil
+	}
+
+	if next > current || !found {
+		if err := na.save(ctx, key, next); err != nil {
+			return 0, false, fmt.Errorf("write next seq number: %w", err)
+		}
+	}
+
+	na.takenMu.Lock()
+	if local, ok := na.local[mid]; ok {
+		local.Set(next)
+	}
+	na.takenMu.Unlock()
+
+	return next, true, nil
+}
+
+// Advance moves the counter of the given miner to num, if it is behind
+func (na *NumberAllocator) Advance(ctx context.Context, mid abi.ActorID, num uint64) (uint64, error) {
+	lock := na.locker.lock(abi.SectorID{
+		Miner:  mid,
+		Number: 0,
+	})
+
+	defer lock.unlock()
+
+	key := makeNumberKey(mid)
+	current, found, err := na.load(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("fetch current number for %d: %w", mid, err)
+	}
+
+	if found && current >= num {
+		return current, nil
+	}
+
+	if err := na.save(ctx, key, num); err != nil {
+		return 0, fmt.Errorf("write seq number: %w", err)
+	}
+
+	return num, nil
+}
+
+// Reset sets the counter of the given miner to num, even if it is ahead
+func (na *NumberAllocator) Reset(ctx context.Context, mid abi.ActorID, num uint64) error {
+	lock := na.locker.lock(abi.SectorID{
+		Miner:  mid,
+		Number: 0,
+	})
+
+	defer lock.unlock()
+
+	if err := na.save(ctx, makeNumberKey(mid), num); err != nil {
+		return fmt.Errorf("write seq number: %w", err)
+	}
+
+	return nil
+}
+
+func (na *NumberAllocator) Inspect(ctx context.Context, mid abi.ActorID, refresh bool) (api.SectorNumberInfo, error) {
+	lock := na.locker.lock(abi.SectorID{
+		Miner:  mid,
+		Number: 0,
+	})
+
+	defer lock.unlock()
+
+	var info api.SectorNumberInfo
+	current, found, err := na.load(ctx, makeNumberKey(mid))
+	if err != nil {
+		return info, fmt.Errorf("fetch current number for %d: %w", mid, err)
+	}
+
+	info.Current = current
+	info.Initialized = found
+
+	taken, err := na.takenNumbers(ctx, mid, refresh, refresh)
+	if err != nil {
+		return info, fmt.Errorf("load taken numbers for %d: %w", mid, err)
+	}
+
+	info.Taken, err = taken.Count()
+	if err != nil {
+		return info, fmt.Errorf("count taken numbers: %w", err)
+	}
+
+	if info.Taken > 0 {
+		info.MaxTaken, err = taken.Last()
+		if err != nil {
+			return info, fmt.Errorf("get max taken number: %w", err)
+		}
+	}
+
+	return info, nil
+}
+
+// Load refreshes the taken numbers for all the configured miners
+func (na *NumberAllocator) Load(ctx context.Context) {
+	na.scfg.Lock()
+	miners := na.scfg.Miners
+	na.scfg.Unlock()
+
+	for mi := range miners {
+		mid := miners[mi].Actor
+		lock := na.locker.lock(abi.SectorID{
+			Miner:  mid,
+			Number: 0,
+		})
+
+		_, err := na.takenNumbers(ctx, mid, true, true)
+		lock.unlock()
+
+		if err != nil {
+			log.Warnw("load taken sector numbers", "miner", mid, "err", err)
+		}
+	}
+}
+
+// takenNumbers should be called with the miner locked, the local numbers are collected from the keys of the sector states.
+// The allocated sectors on chain are always taken, whether FillHoles is enabled or not.
+func (na *NumberAllocator) takenNumbers(ctx context.Context, mid abi.ActorID, refreshLocal bool, refreshChain bool) (*bitfield.BitField, error) {
+	local, err := na.localNumbers(ctx, mid, refreshLocal)
+	if err != nil {
+		return nil, err
+	}
+
+	allocated, err := na.chainNumbers(ctx, mid, refreshChain)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := bitfield.MergeBitFields(*local, *allocated)
+	if err != nil {
+		return nil, fmt.Errorf("merge taken numbers: %w", err)
+	}
+
+	return &merged, nil
+}
+
+func (na *NumberAllocator) localNumbers(ctx context.Context, mid abi.ActorID, refresh bool) (*bitfield.BitField, error) {
+	na.takenMu.Lock()
+	local, ok := na.local[mid]
+	na.takenMu.Unlock()
+
+	if ok && !refresh {
+		return local, nil
+	}
+
+	var nums []uint64
+	for _, ws := range []api.SectorWorkerState{api.WorkerOnline, api.WorkerOffline} {
+		wsNums, err := na.state.Numbers(ctx, ws, mid)
+		if err != nil {
+			return nil, fmt.Errorf("scan %s sector numbers: %w", ws, err)
+		}
+
+		for _, num := range wsNums {
+			nums = append(nums, uint64(num))
+		}
+	}
+
+	bf := bitfield.NewFromSet(nums)
+	na.takenMu.Lock()
+	na.local[mid] = &bf
+	na.takenMu.Unlock()
+
+	log.Infow("local sector numbers loaded", "miner", mid, "count", len(nums))
+	return &bf, nil
+}
+
+// chainNumbers fetches the allocated sectors on chain if required, the cached ones are used if the chain is not available
+func (na *NumberAllocator) chainNumbers(ctx context.Context, mid abi.ActorID, refresh bool) (*bitfield.BitField, error) {
+	na.takenMu.Lock()
+	cached, ok := na.chain[mid]
+	na.takenMu.Unlock()
+
+	if ok && !refresh {
+		return cached, nil
+	}
+
+	maddr, err := address.NewIDAddress(uint64(mid))
+	if err != nil {
+		return nil, err
+	}
+
+	allocated, err := na.capi.StateMinerAllocated(ctx, maddr, types.EmptyTSK)
+	if err != nil {
+		if ok {
+			log.Warnw("get allocated sectors on chain, use the cached ones", "miner", mid, "err", err)
+			return cached, nil
+		}
+
+		return nil, fmt.Errorf("get allocated sectors on chain: %w", err)
+	}
+
+	na.takenMu.Lock()
+	na.chain[mid] = allocated
+	na.takenMu.Unlock()
+
+	return allocated, nil
+}
+
+// nextUntaken finds the first number >= start which is not set in taken
+func nextUntaken(taken *bitfield.BitField, start uint64) (uint64, error) {
+	iter, err := taken.RunIterator()
+	if err != nil {
+		return 0, err
+	}
+
+	var pos uint64
+	for iter.HasNext() {
+		run, err := iter.NextRun()
+		if err != nil {
+			return 0, err
+		}
+
+		end := pos + run.Len
+		if run.Val && start >= pos && start < end {
+			start = end
+		}
+
+		pos = end
+		if pos > start {
+			break
+		}
+	}
+
+	return start, nil
+}
+
+func (na *NumberAllocator) load(ctx context.Context, key kvstore.Key) (uint64, bool, error) {
 	var current uint64
 	switch err := na.store.View(ctx, key, func(data []byte) error {
 		num, read := binary.Uvarint(data)
@@ -46,24 +326,22 @@ func (na *NumberAllocator) Next(ctx context.Context, mid abi.ActorID, initNum ui
 		return nil
 	}); err {
 	case nil:
+		return current, true, nil
 
 	case kvstore.ErrKeyNotFound:
-		current = initNum
+		return 0, false, nil
 
 	default:
-		return 0, false, fmt.Errorf("fetch current number for %d: %w", mid, err)
-	}
-
-	current++
-	if !check(current) {
-		return current, false, nil
+		return 0, false, err
 	}
+}
 
+func (na *NumberAllocator) save(ctx context.Context, key kvstore.Key, num uint64) error {
 	data := make([]byte, binary.MaxVarintLen64)
-	written := binary.PutUvarint(data, current)
-	if err := na.store.Put(ctx, key, data[:written]); err != nil {
-		return 0, false, fmt.Errorf("write next seq number: %w", err)
-	}
+	written := binary.PutUvarint(data, num)
+	return na.store.Put(ctx, key, data[:written])
+}
 
-	return current, true, nil
+func makeNumberKey(mid abi.ActorID) kvstore.Key {
+	return []byte(fmt.Sprintf("/m-%d", mid))
 }
@@ -0,0 +1,200 @@
+package sectors
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus/venus-shared/types"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+)
+
+type allocatedChain struct {
+	chain.API
+	allocated []uint64
+	err       error
+	calls     int
+}
+
+func (c *allocatedChain) StateMinerAllocated(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (*bitfield.BitField, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	bf := bitfield.NewFromSet(c.allocated)
+	return &bf, nil
+}
+
+type numberedStates struct {
+	api.SectorStateManager
+	online  []abi.SectorNumber
+	offline []abi.SectorNumber
+}
+
+func (s *numberedStates) Numbers(ctx context.Context, ws api.SectorWorkerState, mid abi.ActorID) ([]abi.SectorNumber, error) {
+	if ws == api.WorkerOnline {
+		return s.online, nil
+	}
+
+	return s.offline, nil
+}
+
+func newTestAllocator(t *testing.T, mid abi.ActorID, fillHoles bool, capi *allocatedChain, states *numberedStates) *NumberAllocator {
+	cfg := modules.DefaultConfig(false)
+	cfg.Miners = append(cfg.Miners, modules.MinerConfig{
+		Actor: mid,
+		Sector: modules.MinerSectorConfig{
+			Enabled:   true,
+			FillHoles: fillHoles,
+		},
+	})
+
+	scfg := &modules.SafeConfig{
+		Config: &cfg,
+		Locker: &sync.Mutex{},
+	}
+
+	alloc, err := NewNumerAllocator(kvstore.NewMemKVStore(), scfg, capi, states)
+	if err != nil {
+		t.Fatalf("construct allocator: %s", err)
+	}
+
+	return alloc
+}
+
+func allocateN(t *testing.T, alloc *NumberAllocator, mid abi.ActorID, initNum uint64, n int) []uint64 {
+	ctx := context.Background()
+	nums := make([]uint64, 0, n)
+	for i := 0; i < n; i++ {
+		num, ok, err := alloc.Next(ctx, mid, initNum, func(uint64) bool { return true })
+		if err != nil {
+			t.Fatalf("allocate #%d: %s", i, err)
+		}
+
+		if !ok {
+			t.Fatalf("allocate #%d: not allocated", i)
+		}
+
+		nums = append(nums, num)
+	}
+
+	return nums
+}
+
+func equalNums(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestNumberAllocatorSkipsTaken(t *testing.T) {
+	const mid = abi.ActorID(1000)
+
+	cases := []struct {
+		name      string
+		fillHoles bool
+		initNum   uint64
+		allocated []uint64
+		online    []abi.SectorNumber
+		offline   []abi.SectorNumber
+		expected  []uint64
+	}{
+		{
+			// the local counter db is lost, while the sectors are allocated on chain
+			name:      "counter behind chain",
+			allocated: []uint64{1, 2, 3, 4, 5},
+			expected:  []uint64{6, 7, 8},
+		},
+		{
+			name:      "counter behind chain with holes",
+			allocated: []uint64{1, 2, 4, 6},
+			expected:  []uint64{3, 5, 7},
+		},
+		{
+			name:      "counter behind chain with fill holes",
+			fillHoles: true,
+			allocated: []uint64{1, 2, 4, 6},
+			expected:  []uint64{3, 5, 7},
+		},
+		{
+			name:      "local states not on chain yet",
+			allocated: []uint64{1, 2},
+			online:    []abi.SectorNumber{3, 4},
+			offline:   []abi.SectorNumber{6},
+			expected:  []uint64{5, 7, 8},
+		},
+		{
+			name:      "init number",
+			initNum:   100,
+			allocated: []uint64{50, 101, 102},
+			expected:  []uint64{103, 104, 105},
+		},
+	}
+
+	for _, c := range cases {
+		capi := &allocatedChain{allocated: c.allocated}
+		states := &numberedStates{online: c.online, offline: c.offline}
+		alloc := newTestAllocator(t, mid, c.fillHoles, capi, states)
+
+		got := allocateN(t, alloc, mid, c.initNum, len(c.expected))
+		if !equalNums(got, c.expected) {
+			t.Errorf("%s: got %v, expected %v", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestNumberAllocatorRefreshesChain(t *testing.T) {
+	const mid = abi.ActorID(1000)
+
+	capi := &allocatedChain{}
+	alloc := newTestAllocator(t, mid, false, capi, &numberedStates{})
+
+	if got := allocateN(t, alloc, mid, 0, 1); !equalNums(got, []uint64{1}) {
+		t.Fatalf("got %v, expected [1]", got)
+	}
+
+	// numbers allocated on chain by others after the previous allocation
+	capi.allocated = []uint64{1, 2, 3}
+	if got := allocateN(t, alloc, mid, 0, 1); !equalNums(got, []uint64{4}) {
+		t.Fatalf("got %v, expected [4]", got)
+	}
+
+	if capi.calls != 2 {
+		t.Fatalf("chain queried %d times, expected once per allocation", capi.calls)
+	}
+
+	// the cached allocated numbers are used when the chain is not available
+	capi.err = errors.New("chain unavailable")
+	if got := allocateN(t, alloc, mid, 0, 1); !equalNums(got, []uint64{5}) {
+		t.Fatalf("got %v, expected [5]", got)
+	}
+}
+
+func TestNumberAllocatorChainRequired(t *testing.T) {
+	const mid = abi.ActorID(1000)
+
+	capi := &allocatedChain{err: errors.New("chain unavailable")}
+	alloc := newTestAllocator(t, mid, false, capi, &numberedStates{})
+
+	if _, _, err := alloc.Next(context.Background(), mid, 0, func(uint64) bool { return true }); err == nil {
+		t.Fatal("expected an error without the allocated numbers on chain")
+	}
+}
@@ -0,0 +1,286 @@
+package sectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore/filestore"
+)
+
+var _ api.PersistStorePlacer = (*PersistPlacer)(nil)
+
+// reservations not released in this duration are considered abandoned, such as those of the sectors lost with their workers
+const persistReservationTimeout = 48 * time.Hour
+
+type persistReservation struct {
+	ID       abi.SectorID
+	Instance string
+	Size     uint64
+	At       int64
+}
+
+func (r persistReservation) expired(now time.Time) bool {
+	return now.Sub(time.Unix(r.At, 0)) > persistReservationTimeout
+}
+
+// NewPersistPlacer works on the persist stores in the manager,
+// only the local ones are chosen, since the free space of the others is unknown
+func NewPersistPlacer(ctx context.Context, mgr objstore.Manager, kv kvstore.KVStore) (*PersistPlacer, error) {
+	p := &PersistPlacer{
+		kv:           kv,
+		mgr:          mgr,
+		reservations: map[abi.SectorID]persistReservation{},
+		rng:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	if err := p.loadAll(ctx); err != nil {
+		return nil, fmt.Errorf("load persist reservations: %w", err)
+	}
+
+	return p, nil
+}
+
+// PersistPlacer chooses the persist stores by their weights, among the ones with enough space
+type PersistPlacer struct {
+	kv  kvstore.KVStore
+	mgr objstore.Manager
+
+	mu           sync.Mutex
+	reservations map[abi.SectorID]persistReservation
+	rng          *rand.Rand
+}
+
+func (p *PersistPlacer) Allocate(ctx context.Context, sid abi.SectorID, size uint64) (api.PersistStoreAllocation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	// retried by the worker
+	if res, ok := p.reservations[sid]; ok && !res.expired(now) {
+		return api.PersistStoreAllocation{
+			Instance: res.Instance,
+			Reserved: res.Size,
+		}, nil
+	}
+
+	reserved := p.reserved(now)
+
+	type candidate struct {
+		instance string
+		weight   uint
+	}
+
+	var candidates []candidate
+	var totalWeight uint
+	for _, store := range p.localStores(ctx) {
+		cfg := store.Config()
+		if cfg.ReadOnly || cfg.Draining {
+			continue
+		}
+
+		space, err := store.Space(ctx)
+		if err != nil {
+			log.Warnw("get space of persist store", "instance", cfg.Name, "err", err)
+			continue
+		}
+
+		if space.Free < reserved[cfg.Name] || space.Free-reserved[cfg.Name] < size {
+			continue
+		}
+
+		weight := cfg.Weight
+		if weight == 0 {
+			weight = 1
+		}
+
+		candidates = append(candidates, candidate{instance: cfg.Name, weight: weight})
+		totalWeight += weight
+	}
+
+	if len(candidates) == 0 {
+		return api.PersistStoreAllocation{}, fmt.Errorf("no persist store has %d bytes available for %s", size, util.FormatSectorID(sid))
+	}
+
+	chosen := candidates[len(candidates)-1].instance
+	point := uint(p.rng.Int63n(int64(totalWeight)))
+	for _, c := range candidates {
+		if point < c.weight {
+			chosen = c.instance
+			break
+		}
+
+		point -= c.weight
+	}
+
+	res := persistReservation{
+		ID:       sid,
+		Instance: chosen,
+		Size:     size,
+		At:       now.Unix(),
+	}
+
+	if err := p.save(ctx, res); err != nil {
+		return api.PersistStoreAllocation{}, err
+	}
+
+	p.reservations[sid] = res
+	log.Infow("persist store allocated", "miner", sid.Miner, "num", sid.Number, "instance", chosen, "size", size)
+
+	return api.PersistStoreAllocation{
+		Instance: chosen,
+		Reserved: size,
+	}, nil
+}
+
+// Release is called once the sector is persisted or aborted, it is ok if nothing reserved for the sector
+func (p *PersistPlacer) Release(ctx context.Context, sid abi.SectorID) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.reservations[sid]; !ok {
+		return nil
+	}
+
+	if err := p.kv.Del(ctx, kvstore.Key(util.FormatSectorID(sid))); err != nil {
+		return fmt.Errorf("delete persist reservation for %s: %w", util.FormatSectorID(sid), err)
+	}
+
+	delete(p.reservations, sid)
+	return nil
+}
+
+func (p *PersistPlacer) List(ctx context.Context) ([]api.PersistStoreStatus, error) {
+	p.mu.Lock()
+	now := time.Now()
+	reserved := p.reserved(now)
+	sectors := map[string][]abi.SectorID{}
+	for sid, res := range p.reservations {
+		if !res.expired(now) {
+			sectors[res.Instance] = append(sectors[res.Instance], sid)
+		}
+	}
+	p.mu.Unlock()
+
+	instances := p.mgr.Instances(ctx)
+	statuses := make([]api.PersistStoreStatus, 0, len(instances))
+	for _, name := range instances {
+		store, err := p.mgr.GetInstance(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get persist store %s: %w", name, err)
+		}
+
+		local, ok := objstore.Unwrap(store).(*filestore.Store)
+		if !ok {
+			statuses = append(statuses, api.PersistStoreStatus{
+				Instance:     name,
+				SpaceUnknown: true,
+			})
+			continue
+		}
+
+		cfg := local.Config()
+		space, err := local.Space(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get space of persist store %s: %w", cfg.Name, err)
+		}
+
+		reservations := sectors[cfg.Name]
+		sort.Slice(reservations, func(i, j int) bool {
+			if reservations[i].Miner != reservations[j].Miner {
+				return reservations[i].Miner < reservations[j].Miner
+			}
+
+			return reservations[i].Number < reservations[j].Number
+		})
+
+		statuses = append(statuses, api.PersistStoreStatus{
+			Instance:     cfg.Name,
+			Weight:       cfg.Weight,
+			ReadOnly:     cfg.ReadOnly,
+			Draining:     cfg.Draining,
+			Total:        space.Total,
+			Free:         space.Free,
+			Reserved:     reserved[cfg.Name],
+			Reservations: reservations,
+		})
+	}
+
+	return statuses, nil
+}
+
+// localStores returns the persist stores on the local file system, the only ones whose space is known
+func (p *PersistPlacer) localStores(ctx context.Context) []*filestore.Store {
+	instances := p.mgr.Instances(ctx)
+	locals := make([]*filestore.Store, 0, len(instances))
+	for _, name := range instances {
+		store, err := p.mgr.GetInstance(ctx, name)
+		if err != nil {
+			log.Warnw("get persist store", "instance", name, "err", err)
+			continue
+		}
+
+		if local, ok := objstore.Unwrap(store).(*filestore.Store); ok {
+			locals = append(locals, local)
+		}
+	}
+
+	return locals
+}
+
+// reserved should be called with mu locked
+func (p *PersistPlacer) reserved(now time.Time) map[string]uint64 {
+	reserved := map[string]uint64{}
+	for _, res := range p.reservations {
+		if !res.expired(now) {
+			reserved[res.Instance] += res.Size
+		}
+	}
+
+	return reserved
+}
+
+func (p *PersistPlacer) save(ctx context.Context, res persistReservation) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("marshal persist reservation: %w", err)
+	}
+
+	if err := p.kv.Put(ctx, kvstore.Key(util.FormatSectorID(res.ID)), b); err != nil {
+		return fmt.Errorf("save persist reservation: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PersistPlacer) loadAll(ctx context.Context) error {
+	iter, err := p.kv.Scan(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer iter.Close()
+
+	for iter.Next() {
+		var res persistReservation
+		if err := iter.View(ctx, func(data []byte) error {
+			return json.Unmarshal(data, &res)
+		}); err != nil {
+			return fmt.Errorf("load reservation of key %s: %w", string(iter.Key()), err)
+		}
+
+		p.reservations[res.ID] = res
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package sectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	miner5 "github.com/filecoin-project/specs-actors/v5/actors/builtin/miner"
+
+	"github.com/filecoin-project/venus/venus-shared/types"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
+)
+
+var _ api.SnapUpSectorManager = (*SnapUpAllocator)(nil)
+
+var errCandidateFound = errors.New("candidate found")
+
+func NewSnapUpAllocator(scfg *modules.SafeConfig, capi chain.API, mapi api.MinerInfoAPI, state api.SectorStateManager, indexer api.SectorIndexer) (*SnapUpAllocator, error) {
+	allocator := &SnapUpAllocator{
+		scfg:    scfg,
+		capi:    capi,
+		info:    mapi,
+		state:   state,
+		indexer: indexer,
+	}
+
+	allocator.inflight.sectors = map[abi.SectorID]struct{}{}
+	return allocator, nil
+}
+
+// SnapUpAllocator picks finalized cc sectors which could be upgraded with deals
+type SnapUpAllocator struct {
+	scfg    *modules.SafeConfig
+	capi    chain.API
+	info    api.MinerInfoAPI
+	state   api.SectorStateManager
+	indexer api.SectorIndexer
+
+	inflight struct {
+		sync.Mutex
+		sectors map[abi.SectorID]struct{}
+	}
+}
+
+func (s *SnapUpAllocator) Allocate(ctx context.Context, spec api.AllocateSectorSpec) (*api.SnapUpCandidate, error) {
+	s.scfg.Lock()
+	miners := s.scfg.Miners
+	s.scfg.Unlock()
+
+	minfos := map[abi.ActorID]*api.MinerInfo{}
+	for mi := range miners {
+		mid := miners[mi].Actor
+		if !miners[mi].SnapUp.Enabled {
+			continue
+		}
+
+		if len(spec.AllowedMiners) > 0 && !containsActorID(spec.AllowedMiners, mid) {
+			continue
+		}
+
+		minfo, err := s.info.Get(ctx, mid)
+		if err != nil {
+			log.Warnw("get miner info for snapup", "miner", mid, "err", err)
+			continue
+		}
+
+		minfos[mid] = minfo
+	}
+
+	if len(minfos) == 0 {
+		return nil, nil
+	}
+
+	ts, err := s.capi.ChainHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get chain head: %w", err)
+	}
+
+	var found *api.SnapUpCandidate
+	err = s.state.ForEach(ctx, api.WorkerOffline, func(state api.SectorState) error {
+		minfo, ok := minfos[state.ID.Miner]
+		if !ok {
+			return nil
+		}
+
+		if len(spec.AllowedProofTypes) > 0 && !containsProofType(spec.AllowedProofTypes, state.SectorType) {
+			return nil
+		}
+
+		candidate, err := s.tryCandidate(ctx, ts, minfo, state)
+		if err != nil {
+			log.Warnw("check snapup candidate", "miner", state.ID.Miner, "num", state.ID.Number, "err", err)
+			return nil
+		}
+
+		if candidate == nil {
+			return nil
+		}
+
+		found = candidate
+		return errCandidateFound
+	})
+
+	if err != nil && !errors.Is(err, errCandidateFound) {
+		return nil, fmt.Errorf("scan offline sectors: %w", err)
+	}
+
+	return found, nil
+}
+
+func (s *SnapUpAllocator) tryCandidate(ctx context.Context, ts *types.TipSet, minfo *api.MinerInfo, state api.SectorState) (*api.SnapUpCandidate, error) {
+	if state.Upgraded || state.AbortReason != "" {
+		return nil, nil
+	}
+
+	for _, deal := range state.Deals {
+		if deal.ID != 0 {
+			return nil, nil
+		}
+	}
+
+	instance, has, err := s.indexer.Find(ctx, state.ID)
+	if err != nil {
+		return nil, fmt.Errorf("find objstore instance: %w", err)
+	}
+
+	if !has {
+		return nil, nil
+	}
+
+	info, err := s.capi.StateSectorGetInfo(ctx, minfo.Addr, state.ID.Number, ts.Key())
+	if err != nil {
+		return nil, fmt.Errorf("get sector info: %w", err)
+	}
+
+	// not on chain, or already upgraded
+	if info == nil || len(info.DealIDs) > 0 || info.SectorKeyCID != nil {
+		return nil, nil
+	}
+
+	// deals lasts at least MinSectorExpiration epochs
+	if info.Expiration-ts.Height() < miner5.MinSectorExpiration {
+		return nil, nil
+	}
+
+	commR, err := commcid.CIDToReplicaCommitmentV1(info.SealedCID)
+	if err != nil {
+		return nil, fmt.Errorf("convert sealed cid to comm_r: %w", err)
+	}
+
+	s.inflight.Lock()
+	defer s.inflight.Unlock()
+
+	if _, ok := s.inflight.sectors[state.ID]; ok {
+		return nil, nil
+	}
+
+	s.inflight.sectors[state.ID] = struct{}{}
+
+	candidate := &api.SnapUpCandidate{
+		Sector: api.AllocatedSector{
+			ID:        state.ID,
+			ProofType: state.SectorType,
+		},
+		Public: api.SectorPublicInfo{
+			SealedCID:  info.SealedCID,
+			Activation: info.Activation,
+			Expiration: info.Expiration,
+		},
+		Private: api.SectorPrivateInfo{
+			AccessInstance: instance,
+		},
+	}
+
+	copy(candidate.Public.CommR[:], commR)
+	return candidate, nil
+}
+
+func (s *SnapUpAllocator) Release(ctx context.Context, candidate *api.SnapUpCandidate) error {
+	s.inflight.Lock()
+	delete(s.inflight.sectors, candidate.Sector.ID)
+	s.inflight.Unlock()
+	return nil
+}
+
+func containsActorID(ids []abi.ActorID, id abi.ActorID) bool {
+	for i := range ids {
+		if ids[i] == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsProofType(proofs []abi.RegisteredSealProof, typ abi.RegisteredSealProof) bool {
+	for i := range proofs {
+		if proofs[i] == typ {
+			return true
+		}
+	}
+
+	return false
+}
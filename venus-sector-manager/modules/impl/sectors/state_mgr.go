@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"time"
 
 	"github.com/filecoin-project/go-state-types/abi"
 
@@ -28,10 +30,12 @@ func init() {
 
 var _ api.SectorStateManager = (*StateManager)(nil)
 
-func NewStateManager(online kvstore.KVStore, offline kvstore.KVStore) (*StateManager, error) {
+func NewStateManager(online kvstore.KVStore, offline kvstore.KVStore, onlineHistory kvstore.KVStore, offlineHistory kvstore.KVStore) (*StateManager, error) {
 	return &StateManager{
-		online:  online,
-		offline: offline,
+		online:         online,
+		offline:        offline,
+		onlineHistory:  onlineHistory,
+		offlineHistory: offlineHistory,
 		locker: &sectorsLocker{
 			sectors: map[abi.SectorID]*sectorLocker{},
 		},
@@ -42,6 +46,10 @@ type StateManager struct {
 	online  kvstore.KVStore
 	offline kvstore.KVStore
 
+	// append-only state histories, moved along with the sector states
+	onlineHistory  kvstore.KVStore
+	offlineHistory kvstore.KVStore
+
 	locker *sectorsLocker
 }
 
@@ -56,36 +64,184 @@ func (sm *StateManager) load(ctx context.Context, key kvstore.Key, state *api.Se
 }
 
 func (sm *StateManager) All(ctx context.Context, ws api.SectorWorkerState) ([]*api.SectorState, error) {
-	var (
-		iter kvstore.Iter
-		err  error
-	)
+	states := make([]*api.SectorState, 0, 32)
+	if err := sm.ForEach(ctx, ws, func(state api.SectorState) error {
+		states = append(states, &state)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
 
+	return states, nil
+}
+
+func (sm *StateManager) workerStore(ws api.SectorWorkerState) (kvstore.KVStore, error) {
 	switch ws {
 	case api.WorkerOnline:
-		iter, err = sm.online.Scan(ctx, nil)
+		return sm.online, nil
 	case api.WorkerOffline:
-		iter, err = sm.offline.Scan(ctx, nil)
+		return sm.offline, nil
+	default:
+		return nil, fmt.Errorf("unknown worker state %q", ws)
 	}
+}
+
+func (sm *StateManager) ForEach(ctx context.Context, ws api.SectorWorkerState, fn func(api.SectorState) error) error {
+	store, err := sm.workerStore(ws)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	iter, err := store.Scan(ctx, nil)
+	if err != nil {
+		return err
 	}
 
 	defer iter.Close()
 
-	states := make([]*api.SectorState, 0, 32)  // TODO 只返回了32个?
 	for iter.Next() {
 		var state api.SectorState
 		if err := iter.View(ctx, func(data []byte) error {
 			return json.Unmarshal(data, &state)
 		}); err != nil {
-			return nil, fmt.Errorf("scan state item of key %s: %w", string(iter.Key()), err)
+			return fmt.Errorf("scan state item of key %s: %w", string(iter.Key()), err)
 		}
 
-		states = append(states, &state)
+		if err := fn(state); err != nil {
+			return err
+		}
 	}
 
-	return states, nil
+	return nil
+}
+
+// Numbers returns the numbers of the sectors of the given miner, only the keys are scanned
+func (sm *StateManager) Numbers(ctx context.Context, ws api.SectorWorkerState, mid abi.ActorID) ([]abi.SectorNumber, error) {
+	store, err := sm.workerStore(ws)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := store.Scan(ctx, makeMinerSectorPrefix(mid))
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var nums []abi.SectorNumber
+	for iter.Next() {
+		sid, ok := parseSectorKey(iter.Key())
+		if !ok || sid.Miner != mid {
+			continue
+		}
+
+		nums = append(nums, sid.Number)
+	}
+
+	return nums, nil
+}
+
+// List returns a page of the sector states matching the query, in the numeric order of the sector ids.
+// The miner & number filters are applied on the keys, so that the unmatched states won't be decoded.
+// The numbers in the keys are not zero-padded, so the matched keys are collected and sorted before
+// the states are loaded, and the cursor is the key of the last sector in the page.
+func (sm *StateManager) List(ctx context.Context, ws api.SectorWorkerState, query api.SectorListQuery) (api.SectorListPage, error) {
+	var page api.SectorListPage
+
+	store, err := sm.workerStore(ws)
+	if err != nil {
+		return page, err
+	}
+
+	var after *abi.SectorID
+	if query.Cursor != "" {
+		sid, ok := parseSectorKey(kvstore.Key(query.Cursor))
+		if !ok {
+			return page, fmt.Errorf("invalid cursor %q", query.Cursor)
+		}
+
+		after = &sid
+	}
+
+	sids, err := sm.listIDs(ctx, store, query, after)
+	if err != nil {
+		return page, err
+	}
+
+	for _, sid := range sids {
+		key := makeSectorKey(sid)
+		var state api.SectorState
+		err := store.View(ctx, key, func(data []byte) error {
+			return json.Unmarshal(data, &state)
+		})
+
+		// moved to the other store after the keys are collected
+		if err == kvstore.ErrKeyNotFound {
+			continue
+		}
+
+		if err != nil {
+			return page, fmt.Errorf("load state item of key %s: %w", string(key), err)
+		}
+
+		if !query.Match(&state) {
+			continue
+		}
+
+		// one more matched state found, let the caller come back for it
+		if query.Limit > 0 && len(page.Sectors) >= query.Limit {
+			page.Cursor = string(makeSectorKey(page.Sectors[len(page.Sectors)-1].ID))
+			break
+		}
+
+		page.Sectors = append(page.Sectors, &state)
+	}
+
+	return page, nil
+}
+
+// listIDs collects the sorted ids matching the query after the given one, only the keys are scanned
+func (sm *StateManager) listIDs(ctx context.Context, store kvstore.KVStore, query api.SectorListQuery, after *abi.SectorID) ([]abi.SectorID, error) {
+	var prefix kvstore.Prefix
+	if query.Miner != 0 {
+		prefix = makeMinerSectorPrefix(query.Miner)
+	}
+
+	iter, err := store.Scan(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var sids []abi.SectorID
+	for iter.Next() {
+		sid, parsed := parseSectorKey(iter.Key())
+		if !parsed || !query.MatchID(sid) {
+			continue
+		}
+
+		if after != nil && !sectorIDLess(*after, sid) {
+			continue
+		}
+
+		sids = append(sids, sid)
+	}
+
+	sort.Slice(sids, func(i, j int) bool {
+		return sectorIDLess(sids[i], sids[j])
+	})
+
+	return sids, nil
+}
+
+func sectorIDLess(a, b abi.SectorID) bool {
+	if a.Miner != b.Miner {
+		return a.Miner < b.Miner
+	}
+
+	return a.Number < b.Number
 }
 
 func (sm *StateManager) Init(ctx context.Context, sid abi.SectorID, st abi.RegisteredSealProof) error {
@@ -124,13 +280,24 @@ func (sm *StateManager) Load(ctx context.Context, sid abi.SectorID) (*api.Sector
 }
 
 func (sm *StateManager) Update(ctx context.Context, sid abi.SectorID, fieldvals ...interface{}) error {
+	return sm.update(ctx, sm.online, sid, fieldvals...)
+}
+
+// UpdateOffline modifies the fields of a finalized sector
+func (sm *StateManager) UpdateOffline(ctx context.Context, sid abi.SectorID, fieldvals ...interface{}) error {
+	return sm.update(ctx, sm.offline, sid, fieldvals...)
+}
+
+func (sm *StateManager) update(ctx context.Context, store kvstore.KVStore, sid abi.SectorID, fieldvals ...interface{}) error {
 	lock := sm.locker.lock(sid)
 	defer lock.unlock()
 
 	var state api.SectorState
 	key := makeSectorKey(sid)
-	if err := sm.load(ctx, key, &state); err != nil {
-		return err
+	if err := store.View(ctx, key, func(content []byte) error {
+		return json.Unmarshal(content, &state)
+	}); err != nil {
+		return fmt.Errorf("load state: %w", err)
 	}
 
 	statev := reflect.ValueOf(&state).Elem()
@@ -141,7 +308,7 @@ func (sm *StateManager) Update(ctx context.Context, sid abi.SectorID, fieldvals
 		}
 	}
 
-	return save(ctx, sm.online, key, state)
+	return save(ctx, store, key, state)
 }
 
 func (sm *StateManager) Finalize(ctx context.Context, sid abi.SectorID, onFinalize func(*api.SectorState) error) error {
@@ -170,6 +337,168 @@ func (sm *StateManager) Finalize(ctx context.Context, sid abi.SectorID, onFinali
 		return fmt.Errorf("del from online store: %w", err)
 	}
 
+	if err := moveHistory(ctx, sm.onlineHistory, sm.offlineHistory, sid); err != nil {
+		return fmt.Errorf("move state history into offline store: %w", err)
+	}
+
+	return nil
+}
+
+// Restore moves the sector state from the offline store back to the online one,
+// onRestore can be used to modify the state before it is saved.
+func (sm *StateManager) Restore(ctx context.Context, sid abi.SectorID, onRestore func(*api.SectorState) error) error {
+	lock := sm.locker.lock(sid)
+	defer lock.unlock()
+
+	key := makeSectorKey(sid)
+	err := sm.online.View(ctx, key, func([]byte) error { return nil })
+	if err == nil {
+		return fmt.Errorf("sector %s already in online store", string(key))
+	}
+
+	if err != kvstore.ErrKeyNotFound {
+		return err
+	}
+
+	var state api.SectorState
+	if err := sm.offline.View(ctx, key, func(content []byte) error {
+		return json.Unmarshal(content, &state)
+	}); err != nil {
+		return fmt.Errorf("load from offline store: %w", err)
+	}
+
+	if onRestore != nil {
+		err := onRestore(&state)
+		if err != nil {
+			return fmt.Errorf("callback falied before restore: %w", err)
+		}
+	}
+
+	state.Finalized = false
+	if err := save(ctx, sm.online, key, state); err != nil {
+		return fmt.Errorf("save info into online store: %w", err)
+	}
+
+	if err := sm.offline.Del(ctx, key); err != nil {
+		return fmt.Errorf("del from offline store: %w", err)
+	}
+
+	if err := moveHistory(ctx, sm.offlineHistory, sm.onlineHistory, sid); err != nil {
+		return fmt.Errorf("move state history into online store: %w", err)
+	}
+
+	return nil
+}
+
+// Import saves the given state into the offline store, existing state will only be replaced if override is set.
+// Sectors still being sealed in the online store can not be imported.
+func (sm *StateManager) Import(ctx context.Context, state api.SectorState, override bool) (bool, error) {
+	lock := sm.locker.lock(state.ID)
+	defer lock.unlock()
+
+	key := makeSectorKey(state.ID)
+	err := sm.online.View(ctx, key, func([]byte) error { return nil })
+	if err == nil {
+		return false, fmt.Errorf("sector %s already in online store", string(key))
+	}
+
+	if err != kvstore.ErrKeyNotFound {
+		return false, err
+	}
+
+	if !override {
+		err := sm.offline.View(ctx, key, func([]byte) error { return nil })
+		if err == nil {
+			return false, nil
+		}
+
+		if err != kvstore.ErrKeyNotFound {
+			return false, err
+		}
+	}
+
+	if err := save(ctx, sm.offline, key, state); err != nil {
+		return false, fmt.Errorf("save info into offline store: %w", err)
+	}
+
+	return true, nil
+}
+
+// AppendHistory records a reported state in the history of the sector
+func (sm *StateManager) AppendHistory(ctx context.Context, sid abi.SectorID, req api.ReportStateReq) error {
+	lock := sm.locker.lock(sid)
+	defer lock.unlock()
+
+	now := time.Now()
+	b, err := json.Marshal(api.SectorStateRecord{
+		Time:  now.Unix(),
+		State: req,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal state record: %w", err)
+	}
+
+	return sm.onlineHistory.Put(ctx, makeHistoryKey(sid, now.UnixNano()), b)
+}
+
+// History returns the state records of the sector, in the order of reporting
+func (sm *StateManager) History(ctx context.Context, sid abi.SectorID) ([]api.SectorStateRecord, error) {
+	lock := sm.locker.lock(sid)
+	defer lock.unlock()
+
+	var records []api.SectorStateRecord
+	for _, store := range []kvstore.KVStore{sm.offlineHistory, sm.onlineHistory} {
+		iter, err := store.Scan(ctx, makeHistoryPrefix(sid))
+		if err != nil {
+			return nil, err
+		}
+
+		for iter.Next() {
+			var record api.SectorStateRecord
+			if err := iter.View(ctx, func(data []byte) error {
+				return json.Unmarshal(data, &record)
+			}); err != nil {
+				iter.Close()
+				return nil, fmt.Errorf("scan state record of key %s: %w", string(iter.Key()), err)
+			}
+
+			records = append(records, record)
+		}
+
+		iter.Close()
+	}
+
+	return records, nil
+}
+
+// moveHistory should be called with the sector locked
+func moveHistory(ctx context.Context, from, to kvstore.KVStore, sid abi.SectorID) error {
+	iter, err := from.Scan(ctx, makeHistoryPrefix(sid))
+	if err != nil {
+		return err
+	}
+
+	var keys []kvstore.Key
+	for iter.Next() {
+		key := append(kvstore.Key{}, iter.Key()...)
+		if err := iter.View(ctx, func(data []byte) error {
+			return to.Put(ctx, key, append([]byte{}, data...))
+		}); err != nil {
+			iter.Close()
+			return fmt.Errorf("copy state record of key %s: %w", string(key), err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	iter.Close()
+
+	for _, key := range keys {
+		if err := from.Del(ctx, key); err != nil {
+			return fmt.Errorf("del state record of key %s: %w", string(key), err)
+		}
+	}
+
 	return nil
 }
 
@@ -196,7 +525,30 @@ func makeSectorKey(sid abi.SectorID) kvstore.Key {
 	return []byte(fmt.Sprintf("m-%d-n-%d", sid.Miner, sid.Number))
 }
 
+func makeMinerSectorPrefix(mid abi.ActorID) kvstore.Prefix {
+	return []byte(fmt.Sprintf("m-%d-n-", mid))
+}
+
+func parseSectorKey(key kvstore.Key) (abi.SectorID, bool) {
+	var sid abi.SectorID
+	if _, err := fmt.Sscanf(string(key), "m-%d-n-%d", &sid.Miner, &sid.Number); err != nil {
+		return sid, false
+	}
+
+	return sid, true
+}
+
+func makeHistoryPrefix(sid abi.SectorID) kvstore.Prefix {
+	return []byte(fmt.Sprintf("m-%d-n-%d/", sid.Miner, sid.Number))
+}
+
+// the zero-padded timestamp keeps the records in order
+func makeHistoryKey(sid abi.SectorID, ts int64) kvstore.Key {
+	return []byte(fmt.Sprintf("m-%d-n-%d/%020d", sid.Miner, sid.Number, ts))
+}
+
 func save(ctx context.Context, store kvstore.KVStore, key kvstore.Key, state api.SectorState) error {
+	state.UpdatedAt = time.Now().Unix()
 	b, err := json.Marshal(state)
 	if err != nil {
 		return fmt.Errorf("marshal state: %w", err)
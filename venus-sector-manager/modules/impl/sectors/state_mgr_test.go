@@ -0,0 +1,103 @@
+package sectors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+)
+
+func newTestStateManager(t *testing.T, sids []abi.SectorID) *StateManager {
+	sm, err := NewStateManager(kvstore.NewMemKVStore(), kvstore.NewMemKVStore(), kvstore.NewMemKVStore(), kvstore.NewMemKVStore())
+	if err != nil {
+		t.Fatalf("construct state manager: %s", err)
+	}
+
+	for _, sid := range sids {
+		if err := sm.Init(context.Background(), sid, abi.RegisteredSealProof_StackedDrg32GiBV1_1); err != nil {
+			t.Fatalf("init sector %v: %s", sid, err)
+		}
+	}
+
+	return sm
+}
+
+func TestStateManagerListOrder(t *testing.T) {
+	var sids []abi.SectorID
+	for _, mid := range []abi.ActorID{999, 1000} {
+		for _, num := range []abi.SectorNumber{1, 2, 9, 10, 100} {
+			sids = append(sids, abi.SectorID{Miner: mid, Number: num})
+		}
+	}
+
+	sm := newTestStateManager(t, sids)
+
+	cases := []struct {
+		name     string
+		query    api.SectorListQuery
+		expected []abi.SectorID
+	}{
+		{
+			name:     "all",
+			query:    api.SectorListQuery{Limit: 3},
+			expected: sids,
+		},
+		{
+			name:     "miner",
+			query:    api.SectorListQuery{Miner: 1000, Limit: 2},
+			expected: sids[5:],
+		},
+		{
+			name:     "number range",
+			query:    api.SectorListQuery{Miner: 999, MinNumber: 2, MaxNumber: 10, Limit: 1},
+			expected: sids[1:4],
+		},
+		{
+			name:     "no limit",
+			query:    api.SectorListQuery{MinNumber: 9},
+			expected: []abi.SectorID{sids[2], sids[3], sids[4], sids[7], sids[8], sids[9]},
+		},
+	}
+
+	for _, c := range cases {
+		var got []abi.SectorID
+		query := c.query
+		for pages := 0; ; pages++ {
+			if pages > len(sids) {
+				t.Fatalf("%s: too many pages", c.name)
+			}
+
+			page, err := sm.List(context.Background(), api.WorkerOnline, query)
+			if err != nil {
+				t.Fatalf("%s: list: %s", c.name, err)
+			}
+
+			if query.Limit > 0 && len(page.Sectors) > query.Limit {
+				t.Fatalf("%s: got %d sectors in a page, limit %d", c.name, len(page.Sectors), query.Limit)
+			}
+
+			for _, st := range page.Sectors {
+				got = append(got, st.ID)
+			}
+
+			if page.Cursor == "" {
+				break
+			}
+
+			query.Cursor = page.Cursor
+		}
+
+		if len(got) != len(c.expected) {
+			t.Fatalf("%s: got %v, expected %v", c.name, got, c.expected)
+		}
+
+		for i := range got {
+			if got[i] != c.expected[i] {
+				t.Fatalf("%s: got %v, expected %v", c.name, got, c.expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package sectors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+)
+
+var _ api.StuckSectorDetector = (*StuckDetector)(nil)
+
+func NewStuckDetector(scfg *modules.SafeConfig, state api.SectorStateManager) *StuckDetector {
+	return &StuckDetector{
+		scfg:  scfg,
+		state: state,
+	}
+}
+
+// StuckDetector applies the per-phase timeouts on the online sectors
+type StuckDetector struct {
+	scfg  *modules.SafeConfig
+	state api.SectorStateManager
+}
+
+func (d *StuckDetector) Check(ctx context.Context) ([]api.StuckSector, error) {
+	d.scfg.Lock()
+	defaultTimeout := d.scfg.Common.StuckSectors.DefaultTimeout.Std()
+	timeouts := make(map[string]time.Duration, len(d.scfg.Common.StuckSectors.Timeouts))
+	for phase, timeout := range d.scfg.Common.StuckSectors.Timeouts {
+		timeouts[phase] = timeout.Std()
+	}
+	d.scfg.Unlock()
+
+	now := time.Now()
+	var stuck []api.StuckSector
+	err := d.state.ForEach(ctx, api.WorkerOnline, func(st api.SectorState) error {
+		// states saved before UpdatedAt was introduced can not be judged until the next update
+		if st.UpdatedAt == 0 || st.AbortReason != "" {
+			return nil
+		}
+
+		phase := st.Phase()
+		timeout, ok := timeouts[phase]
+		if !ok {
+			timeout = defaultTimeout
+		}
+
+		if timeout <= 0 || now.Sub(time.Unix(st.UpdatedAt, 0)) <= timeout {
+			return nil
+		}
+
+		item := api.StuckSector{
+			ID:      st.ID,
+			Phase:   phase,
+			Since:   st.UpdatedAt,
+			Timeout: timeout,
+		}
+
+		if st.LatestState != nil {
+			item.Worker = st.LatestState.Worker
+		}
+
+		stuck = append(stuck, item)
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("scan online sectors: %w", err)
+	}
+
+	return stuck, nil
+}
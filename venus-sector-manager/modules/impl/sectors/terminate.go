@@ -0,0 +1,427 @@
+package sectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/ipfs/go-cid"
+
+	miner5 "github.com/filecoin-project/specs-actors/v5/actors/builtin/miner"
+
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin"
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin/miner"
+	specpolicy "github.com/filecoin-project/venus/venus-shared/actors/policy"
+	"github.com/filecoin-project/venus/venus-shared/types"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/impl/commitmgr"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
+)
+
+var _ api.SectorTerminationManager = (*TerminationManager)(nil)
+
+func NewTerminationManager(
+	scfg *modules.SafeConfig,
+	capi chain.API,
+	mapi api.MinerInfoAPI,
+	msgClient messager.API,
+	state api.SectorStateManager,
+	kv kvstore.KVStore,
+) (*TerminationManager, error) {
+	return &TerminationManager{
+		scfg:      scfg,
+		capi:      capi,
+		info:      mapi,
+		msgClient: msgClient,
+		state:     state,
+		pending:   kv,
+	}, nil
+}
+
+// TerminationManager terminates the given sectors on chain.
+// The pushed messages are tracked in the background, and the sector states are marked as terminated once the messages land.
+type TerminationManager struct {
+	scfg      *modules.SafeConfig
+	capi      chain.API
+	info      api.MinerInfoAPI
+	msgClient messager.API
+	state     api.SectorStateManager
+	pending   kvstore.KVStore
+}
+
+// pendingTermination is a pushed message waiting to be landed, it is persisted so that the tracking survives the restarts
+type pendingTermination struct {
+	Miner   abi.ActorID
+	Message cid.Cid
+	Sectors []abi.SectorNumber
+}
+
+func (t *TerminationManager) Terminate(ctx context.Context, mid abi.ActorID, spec api.TerminateSectorsSpec) ([]api.SectorTerminationMessage, error) {
+	if len(spec.Sectors) == 0 {
+		return nil, fmt.Errorf("no sector specified")
+	}
+
+	mcfg, err := t.scfg.MinerConfig(mid)
+	if err != nil {
+		return nil, fmt.Errorf("get miner config: %w", err)
+	}
+
+	minfo, err := t.info.Get(ctx, mid)
+	if err != nil {
+		return nil, fmt.Errorf("get miner info: %w", err)
+	}
+
+	ts, err := t.capi.ChainHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get chain head: %w", err)
+	}
+
+	from := spec.From
+	if from == address.Undef {
+		from = mcfg.Termination.Sender.Std()
+	}
+
+	if from == address.Undef {
+		mi, err := t.capi.StateMinerInfo(ctx, minfo.Addr, ts.Key())
+		if err != nil {
+			return nil, fmt.Errorf("get miner worker: %w", err)
+		}
+
+		from = mi.Worker
+	}
+
+	nv, err := t.capi.StateNetworkVersion(ctx, ts.Key())
+	if err != nil {
+		return nil, fmt.Errorf("get network version: %w", err)
+	}
+
+	declMax, err := specpolicy.GetDeclarationsMax(nv)
+	if err != nil {
+		return nil, fmt.Errorf("get max declarations: %w", err)
+	}
+
+	addrMax, err := specpolicy.GetAddressedSectorsMax(nv)
+	if err != nil {
+		return nil, fmt.Errorf("get max addressed sectors: %w", err)
+	}
+
+	type location struct {
+		deadline  uint64
+		partition uint64
+	}
+
+	grouped := map[location][]abi.SectorNumber{}
+	seen := map[abi.SectorNumber]struct{}{}
+	for _, num := range spec.Sectors {
+		if _, ok := seen[num]; ok {
+			continue
+		}
+
+		seen[num] = struct{}{}
+
+		sl, err := t.capi.StateSectorPartition(ctx, minfo.Addr, num, ts.Key())
+		if err != nil {
+			return nil, fmt.Errorf("get location of sector %d: %w", num, err)
+		}
+
+		if sl == nil {
+			return nil, fmt.Errorf("location of sector %d not found", num)
+		}
+
+		loc := location{deadline: sl.Deadline, partition: sl.Partition}
+		grouped[loc] = append(grouped[loc], num)
+	}
+
+	decls := make([]api.SectorTermination, 0, len(grouped))
+	for loc, nums := range grouped {
+		sort.Slice(nums, func(i, j int) bool {
+			return nums[i] < nums[j]
+		})
+
+		decls = append(decls, api.SectorTermination{
+			Deadline:  loc.deadline,
+			Partition: loc.partition,
+			Sectors:   nums,
+		})
+	}
+
+	sort.Slice(decls, func(i, j int) bool {
+		if decls[i].Deadline != decls[j].Deadline {
+			return decls[i].Deadline < decls[j].Deadline
+		}
+
+		return decls[i].Partition < decls[j].Partition
+	})
+
+	msgs := batchTerminations(decls, declMax, addrMax)
+	params := make([][]byte, len(msgs))
+	for i := range msgs {
+		enc := new(bytes.Buffer)
+		if err := terminationParams(msgs[i].Terminations).MarshalCBOR(enc); err != nil {
+			return nil, fmt.Errorf("serialize termination params: %w", err)
+		}
+
+		params[i] = enc.Bytes()
+
+		fee, err := t.estimateFee(ctx, ts.Key(), from, minfo.Addr, params[i])
+		if err != nil {
+			return nil, fmt.Errorf("estimate termination fee: %w", err)
+		}
+
+		msgs[i].Fee = fee
+	}
+
+	if spec.DryRun {
+		return msgs, nil
+	}
+
+	var msgSpec messager.MsgMeta
+	msgSpec.GasOverEstimation = mcfg.Termination.GasOverEstimation
+	msgSpec.MaxFeeCap = mcfg.Termination.MaxFeeCap.Std()
+
+	mlog := log.With("miner", mid, "proc", "termination")
+	for i := range msgs {
+		mcid, err := commitmgr.PushMessage(ctx, from, mid, big.Zero(), miner.Methods.TerminateSectors, t.msgClient, msgSpec, params[i], mlog)
+		if err != nil {
+			return msgs, fmt.Errorf("push termination message: %w", err)
+		}
+
+		msgs[i].MessageID = mcid.String()
+		mlog.Infow("termination message pushed", "msgid", msgs[i].MessageID, "declarations", len(msgs[i].Terminations))
+
+		var nums []abi.SectorNumber
+		for _, decl := range msgs[i].Terminations {
+			nums = append(nums, decl.Sectors...)
+		}
+
+		if err := t.savePending(ctx, pendingTermination{Miner: mid, Message: mcid, Sectors: nums}); err != nil {
+			return msgs, err
+		}
+	}
+
+	return msgs, nil
+}
+
+func makePendingTerminationKey(mcid cid.Cid) kvstore.Key {
+	return []byte(mcid.String())
+}
+
+func (t *TerminationManager) savePending(ctx context.Context, p pendingTermination) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal pending termination: %w", err)
+	}
+
+	if err := t.pending.Put(ctx, makePendingTerminationKey(p.Message), b); err != nil {
+		return fmt.Errorf("save pending termination %s: %w", p.Message, err)
+	}
+
+	return nil
+}
+
+// Run checks the pending termination messages periodically, until the ctx is done
+func (t *TerminationManager) Run(ctx context.Context) {
+	log.Info("termination manager loop start")
+	defer log.Info("termination manager loop stop")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-time.After(time.Minute):
+		}
+
+		if err := t.checkPending(ctx); err != nil {
+			log.Warnf("check pending termination messages: %s", err)
+		}
+	}
+}
+
+func (t *TerminationManager) checkPending(ctx context.Context) error {
+	iter, err := t.pending.Scan(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var pendings []pendingTermination
+	for iter.Next() {
+		var p pendingTermination
+		if err := iter.View(ctx, func(b []byte) error {
+			return json.Unmarshal(b, &p)
+		}); err != nil {
+			iter.Close()
+			return fmt.Errorf("load pending termination %s: %w", string(iter.Key()), err)
+		}
+
+		pendings = append(pendings, p)
+	}
+
+	iter.Close()
+
+	for _, p := range pendings {
+		done, err := t.checkMessage(ctx, p)
+		if err != nil {
+			log.Warnw("check termination message", "miner", p.Miner, "msgid", p.Message.String(), "err", err)
+			continue
+		}
+
+		if !done {
+			continue
+		}
+
+		if err := t.pending.Del(ctx, makePendingTerminationKey(p.Message)); err != nil {
+			return fmt.Errorf("delete pending termination %s: %w", p.Message, err)
+		}
+	}
+
+	return nil
+}
+
+// checkMessage marks the sectors as terminated once the message lands, true is returned if the message no longer needs tracking
+func (t *TerminationManager) checkMessage(ctx context.Context, p pendingTermination) (bool, error) {
+	mcfg, err := t.scfg.MinerConfig(p.Miner)
+	if err != nil {
+		return false, fmt.Errorf("get miner config: %w", err)
+	}
+
+	msg, err := t.msgClient.GetMessageByUid(ctx, p.Message.String())
+	if err != nil {
+		return false, fmt.Errorf("get message: %w", err)
+	}
+
+	mlog := log.With("miner", p.Miner, "proc", "termination", "msgid", p.Message.String())
+	switch msg.State {
+	case messager.MessageState.OnChainMsg:
+		if msg.Confidence < mcfg.Commitment.Confidence {
+			return false, nil
+		}
+
+	case messager.MessageState.FailedMsg:
+		mlog.Errorw("termination message failed", "sectors", p.Sectors)
+		return true, nil
+
+	default:
+		return false, nil
+	}
+
+	if msg.Receipt == nil {
+		return false, fmt.Errorf("receipt not found")
+	}
+
+	if !msg.Receipt.ExitCode.IsSuccess() {
+		mlog.Errorw("termination message failed", "exit-code", msg.Receipt.ExitCode, "sectors", p.Sectors)
+		return true, nil
+	}
+
+	info := api.TerminateInfo{
+		Terminated:   true,
+		TerminateCid: &p.Message,
+		TerminatedAt: abi.ChainEpoch(msg.Height),
+	}
+
+	for _, num := range p.Sectors {
+		sid := abi.SectorID{Miner: p.Miner, Number: num}
+		err := t.state.UpdateOffline(ctx, sid, info)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, kvstore.ErrKeyNotFound) {
+			mlog.Warnw("sector state not found in offline store", "num", num)
+			continue
+		}
+
+		return false, fmt.Errorf("mark sector %d as terminated: %w", num, err)
+	}
+
+	mlog.Infow("sectors terminated", "sectors", len(p.Sectors), "height", msg.Height)
+	return true, nil
+}
+
+// estimateFee executes the termination message on the given tipset, and sums up the funds burnt in it.
+// Terminations exceeding the per-epoch limits are processed in cron, the fee of which won't be included.
+func (t *TerminationManager) estimateFee(ctx context.Context, tsk types.TipSetKey, from, maddr address.Address, params []byte) (abi.TokenAmount, error) {
+	res, err := t.capi.StateCall(ctx, &types.Message{
+		To:     maddr,
+		From:   from,
+		Value:  big.Zero(),
+		Method: miner.Methods.TerminateSectors,
+		Params: params,
+	}, tsk)
+	if err != nil {
+		return big.Zero(), fmt.Errorf("call TerminateSectors: %w", err)
+	}
+
+	if res.MsgRct != nil && !res.MsgRct.ExitCode.IsSuccess() {
+		return big.Zero(), fmt.Errorf("call TerminateSectors failed with exit code %s: %s", res.MsgRct.ExitCode, res.Error)
+	}
+
+	return burntFunds(res.ExecutionTrace), nil
+}
+
+func burntFunds(trace types.ExecutionTrace) abi.TokenAmount {
+	burnt := big.Zero()
+	if trace.Msg != nil && trace.Msg.To == builtin.BurntFundsActorAddr {
+		burnt = big.Add(burnt, trace.Msg.Value)
+	}
+
+	for i := range trace.Subcalls {
+		burnt = big.Add(burnt, burntFunds(trace.Subcalls[i]))
+	}
+
+	return burnt
+}
+
+// batchTerminations packs the declarations into messages within the chain limits
+func batchTerminations(decls []api.SectorTermination, declMax, addrMax int) []api.SectorTerminationMessage {
+	sizes := make([]int, len(decls))
+	for i := range decls {
+		sizes[i] = len(decls[i].Sectors)
+	}
+
+	batches := batchDeclarations(sizes, declMax, addrMax)
+	msgs := make([]api.SectorTerminationMessage, len(batches))
+	for i, parts := range batches {
+		for _, p := range parts {
+			part := decls[p.decl]
+			part.Sectors = part.Sectors[p.start:p.end]
+			msgs[i].Terminations = append(msgs[i].Terminations, part)
+		}
+	}
+
+	return msgs
+}
+
+func terminationParams(decls []api.SectorTermination) *miner5.TerminateSectorsParams {
+	params := &miner5.TerminateSectorsParams{
+		Terminations: make([]miner5.TerminationDeclaration, 0, len(decls)),
+	}
+
+	for _, decl := range decls {
+		nums := make([]uint64, len(decl.Sectors))
+		for i := range decl.Sectors {
+			nums[i] = uint64(decl.Sectors[i])
+		}
+
+		params.Terminations = append(params.Terminations, miner5.TerminationDeclaration{
+			Deadline:  decl.Deadline,
+			Partition: decl.Partition,
+			Sectors:   bitfield.NewFromSet(nums),
+		})
+	}
+
+	return params
+}
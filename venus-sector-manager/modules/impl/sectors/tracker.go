@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"sync"
 
 	"github.com/filecoin-project/go-state-types/abi"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
 )
 
@@ -30,16 +32,11 @@ type Tracker struct {
 	indexer api.SectorIndexer
 }
 
-func (t *Tracker) Provable(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, strict bool) (map[abi.SectorNumber]string, error) {
+func (t *Tracker) Provable(ctx context.Context, mid abi.ActorID, sectors []builtin.ExtendedSectorInfo, strict bool) (map[abi.SectorNumber]string, error) {
 	results := make([]string, len(sectors))
 	var wg sync.WaitGroup
 	wg.Add(len(sectors))
 
-	ssize, err := pp.SectorSize()
-	if err != nil {
-		return nil, err
-	}
-
 	for ti := range sectors {
 		go func(i int) {
 			var reason string
@@ -51,15 +48,25 @@ func (t *Tracker) Provable(ctx context.Context, pp abi.RegisteredPoStProof, sect
 				wg.Done()
 			}()
 
-			sid := sectors[i].ID
-			objins, err := t.getObjInstanceForSector(ctx, sid)
+			sid := abi.SectorID{
+				Miner:  mid,
+				Number: sectors[i].SectorNumber,
+			}
+
+			ssize, err := sectors[i].SealProof.SectorSize()
+			if err != nil {
+				reason = fmt.Sprintf("get sector size for %s: %s", util.FormatSectorID(sid), err)
+				return
+			}
+
+			proveUpdate := sectors[i].SectorKey != nil
+			objins, err := t.getObjInstanceForSector(ctx, sid, proveUpdate)
 			if err != nil {
 				reason = fmt.Sprintf("get objstore instance for %s: %s", util.FormatSectorID(sid), err)
 				return
 			}
 
-			// todo: for snapdeals
-			subSealed := util.SectorPath(util.SectorPathTypeSealed, sid)
+			subCache, subSealed := sectorPaths(sid, proveUpdate)
 			_, err = objins.Stat(ctx, subSealed)
 			if err != nil {
 				reason = fmt.Sprintf("get stat info for %s: %s", util.FormatSectorID(sid), err)
@@ -70,7 +77,6 @@ func (t *Tracker) Provable(ctx context.Context, pp abi.RegisteredPoStProof, sect
 				return
 			}
 
-			subCache := util.SectorPath(util.SectorPathTypeCache, sid)
 			toCheck := map[string]int64{
 				filepath.Join(subCache, "p_aux"): 0,
 			}
@@ -103,10 +109,14 @@ func (t *Tracker) Provable(ctx context.Context, pp abi.RegisteredPoStProof, sect
 	bad := map[abi.SectorNumber]string{}
 	for ri := range results {
 		if results[ri] != "" {
-			bad[sectors[ri].ID.Number] = results[ri]
+			bad[sectors[ri].SectorNumber] = results[ri]
 		}
 	}
 
+	miner := strconv.FormatUint(uint64(mid), 10)
+	metrics.ProvableChecks.WithLabelValues(miner, "good").Add(float64(len(sectors) - len(bad)))
+	metrics.ProvableChecks.WithLabelValues(miner, "bad").Add(float64(len(bad)))
+
 	return bad, nil
 }
 
@@ -123,22 +133,14 @@ func (t *Tracker) PubToPrivate(ctx context.Context, aid abi.ActorID, sectorInfo
 			return api.SortedPrivateSectorInfo{}, fmt.Errorf("acquiring registered PoSt proof from sector info %+v: %w", sector, err)
 		}
 
-		objins, err := t.getObjInstanceForSector(ctx, sid.ID)
+		// sectors upgraded by snapup have their SectorKey set, and should be proved with the updated replica
+		proveUpdate := sector.SectorKey != nil
+		objins, err := t.getObjInstanceForSector(ctx, sid.ID, proveUpdate)
 		if err != nil {
 			return api.SortedPrivateSectorInfo{}, fmt.Errorf("get objstore instance for %s: %w", util.FormatSectorID(sid.ID), err)
 		}
 
-		// TODO: Construct paths for snap deals ?
-		proveUpdate := sector.SectorKey != nil
-		var (
-			subCache, subSealed string
-		)
-		if proveUpdate {
-
-		} else {
-			subCache = util.SectorPath(util.SectorPathTypeCache, sid.ID)
-			subSealed = util.SectorPath(util.SectorPathTypeSealed, sid.ID)
-		}
+		subCache, subSealed := sectorPaths(sid.ID, proveUpdate)
 
 		ffiInfo := ffiproof.SectorInfo{
 			SealProof:    sector.SealProof,
@@ -156,8 +158,13 @@ func (t *Tracker) PubToPrivate(ctx context.Context, aid abi.ActorID, sectorInfo
 	return api.NewSortedPrivateSectorInfo(out...), nil
 }
 
-func (t *Tracker) getObjInstanceForSector(ctx context.Context, sid abi.SectorID) (objstore.Store, error) {
-	insname, has, err := t.indexer.Find(ctx, sid)
+func (t *Tracker) getObjInstanceForSector(ctx context.Context, sid abi.SectorID, upgrade bool) (objstore.Store, error) {
+	var indexer api.SectorTypedIndexer = t.indexer
+	if upgrade {
+		indexer = t.indexer.Upgrade()
+	}
+
+	insname, has, err := indexer.Find(ctx, sid)
 	if err != nil {
 		return nil, fmt.Errorf("find objstore instance: %w", err)
 	}
@@ -174,23 +181,22 @@ func (t *Tracker) getObjInstanceForSector(ctx context.Context, sid abi.SectorID)
 	return instance, nil
 }
 
+func sectorPaths(sid abi.SectorID, upgrade bool) (string, string) {
+	if upgrade {
+		return util.SectorPath(util.SectorPathTypeUpdateCache, sid), util.SectorPath(util.SectorPathTypeUpdate, sid)
+	}
+
+	return util.SectorPath(util.SectorPathTypeCache, sid), util.SectorPath(util.SectorPathTypeSealed, sid)
+}
+
 func addCachePathsForSectorSize(chk map[string]int64, cacheDir string, ssize abi.SectorSize) {
-	switch ssize {
-	case 2 << 10:
-		fallthrough
-	case 8 << 20:
-		fallthrough
-	case 512 << 20:
-		chk[filepath.Join(cacheDir, "sc-02-data-tree-r-last.dat")] = 0
-	case 32 << 30:
-		for i := 0; i < 8; i++ {
-			chk[filepath.Join(cacheDir, fmt.Sprintf("sc-02-data-tree-r-last-%d.dat", i))] = 0
-		}
-	case 64 << 30:
-		for i := 0; i < 16; i++ {
-			chk[filepath.Join(cacheDir, fmt.Sprintf("sc-02-data-tree-r-last-%d.dat", i))] = 0
-		}
-	default:
+	files := util.TreeRLastFiles(ssize)
+	if len(files) == 0 {
 		log.Warnf("not checking cache files of %s sectors for faults", ssize)
+		return
+	}
+
+	for _, name := range files {
+		chk[filepath.Join(cacheDir, name)] = 0
 	}
 }
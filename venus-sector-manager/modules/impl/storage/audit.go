@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin/miner"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
+)
+
+var _ api.StorageAuditor = (*Auditor)(nil)
+
+func NewAuditor(scfg *modules.SafeConfig, capi chain.API, info api.MinerInfoAPI, state api.SectorStateManager, indexer api.SectorIndexer) *Auditor {
+	return &Auditor{
+		scfg:    scfg,
+		capi:    capi,
+		info:    info,
+		state:   state,
+		indexer: indexer,
+	}
+}
+
+// Auditor compares the sectors of the configured miners on chain, the index entries, and the files in the persist stores
+type Auditor struct {
+	scfg    *modules.SafeConfig
+	capi    chain.API
+	info    api.MinerInfoAPI
+	state   api.SectorStateManager
+	indexer api.SectorIndexer
+
+	// serializes the audits
+	mu   sync.Mutex
+	last *api.StorageAuditReport
+}
+
+func (a *Auditor) Audit(ctx context.Context, refresh bool) (api.StorageAuditReport, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !refresh && a.last != nil {
+		return *a.last, nil
+	}
+
+	report, err := a.audit(ctx)
+	if err != nil {
+		return api.StorageAuditReport{}, err
+	}
+
+	a.last = &report
+	return report, nil
+}
+
+func (a *Auditor) audit(ctx context.Context) (api.StorageAuditReport, error) {
+	a.scfg.Lock()
+	miners := make([]abi.ActorID, 0, len(a.scfg.Miners))
+	for _, mcfg := range a.scfg.Miners {
+		miners = append(miners, mcfg.Actor)
+	}
+	a.scfg.Unlock()
+
+	report := api.StorageAuditReport{
+		Time:       time.Now().Unix(),
+		Miners:     miners,
+		ScanErrors: map[string]string{},
+	}
+
+	ts, err := a.capi.ChainHead(ctx)
+	if err != nil {
+		return report, fmt.Errorf("get chain head: %w", err)
+	}
+
+	report.Epoch = ts.Height()
+
+	onChain := map[abi.SectorID]string{}
+	for _, mid := range miners {
+		minfo, err := a.info.Get(ctx, mid)
+		if err != nil {
+			return report, fmt.Errorf("get miner info for %d: %w", mid, err)
+		}
+
+		for dlIdx := uint64(0); dlIdx < miner.WPoStPeriodDeadlines; dlIdx++ {
+			partitions, err := a.capi.StateMinerPartitions(ctx, minfo.Addr, dlIdx, ts.Key())
+			if err != nil {
+				return report, fmt.Errorf("get partitions of %d in deadline %d: %w", mid, dlIdx, err)
+			}
+
+			for _, part := range partitions {
+				// recovering sectors are also faulty, and the faulty ones are also live
+				for _, set := range []struct {
+					bf    bitfield.BitField
+					state string
+				}{
+					{part.LiveSectors, api.StorageAuditChainLive},
+					{part.FaultySectors, api.StorageAuditChainFaulty},
+					{part.RecoveringSectors, api.StorageAuditChainRecovering},
+				} {
+					state := set.state
+					if err := set.bf.ForEach(func(num uint64) error {
+						onChain[abi.SectorID{Miner: mid, Number: abi.SectorNumber(num)}] = state
+						return nil
+					}); err != nil {
+						return report, fmt.Errorf("iterate %s sectors of %d in deadline %d: %w", state, mid, dlIdx, err)
+					}
+				}
+			}
+		}
+	}
+
+	report.OnChain = len(onChain)
+
+	sealing := map[abi.SectorID]struct{}{}
+	if err := a.state.ForEach(ctx, api.WorkerOnline, func(st api.SectorState) error {
+		sealing[st.ID] = struct{}{}
+		return nil
+	}); err != nil {
+		return report, fmt.Errorf("scan online sectors: %w", err)
+	}
+
+	storeMgr := a.indexer.StoreMgr()
+	found, err := scanStores(ctx, storeMgr, storeMgr.Instances(ctx), &report.Scanned, report.ScanErrors)
+	if err != nil {
+		return report, err
+	}
+
+	scanned := map[string]bool{}
+	for _, name := range report.Scanned {
+		scanned[name] = true
+	}
+
+	sids := make([]abi.SectorID, 0, len(onChain))
+	for sid := range onChain {
+		sids = append(sids, sid)
+	}
+
+	sort.Slice(sids, func(i, j int) bool {
+		if sids[i].Miner != sids[j].Miner {
+			return sids[i].Miner < sids[j].Miner
+		}
+
+		return sids[i].Number < sids[j].Number
+	})
+
+	for _, sid := range sids {
+		// the replica of a snapup sector is the one being proved
+		upgraded := moveTarget{sid: sid, upgrade: true}
+		_, upgradeIndexed, err := upgraded.typedIndexer(a.indexer).Find(ctx, sid)
+		if err != nil {
+			return report, fmt.Errorf("find upgrade index of %s: %w", util.FormatSectorID(sid), err)
+		}
+
+		t := moveTarget{sid: sid}
+		if upgradeIndexed || holdsSealed(found[upgraded]) {
+			t = upgraded
+		}
+
+		indexed, ok, err := t.typedIndexer(a.indexer).Find(ctx, sid)
+		if err != nil {
+			return report, fmt.Errorf("find index of %s: %w", util.FormatSectorID(sid), err)
+		}
+
+		issue := api.StorageAuditIssue{
+			ID:         sid,
+			Upgrade:    t.upgrade,
+			ChainState: onChain[sid],
+			Indexed:    indexed,
+			Instances:  sealedHolders(found[t], report.Scanned),
+		}
+
+		switch {
+		case len(issue.Instances) == 0:
+			// the files may be in the indexed store which is not scanned
+			if ok && !scanned[indexed] {
+				continue
+			}
+
+			issue.Kind = api.StorageAuditNoFiles
+
+		case !ok:
+			issue.Kind = api.StorageAuditNotIndexed
+
+		case scanned[indexed] && !contains(issue.Instances, indexed):
+			issue.Kind = api.StorageAuditWrongStore
+
+		default:
+			continue
+		}
+
+		report.Issues = append(report.Issues, issue)
+	}
+
+	audited := map[abi.ActorID]struct{}{}
+	for _, mid := range miners {
+		audited[mid] = struct{}{}
+	}
+
+	targets := make([]moveTarget, 0, len(found))
+	for t := range found {
+		targets = append(targets, t)
+	}
+
+	sortTargets(targets)
+
+	for _, t := range targets {
+		if _, ok := audited[t.sid.Miner]; !ok {
+			continue
+		}
+
+		if _, ok := onChain[t.sid]; ok {
+			continue
+		}
+
+		if _, ok := sealing[t.sid]; ok {
+			continue
+		}
+
+		indexed, _, err := t.typedIndexer(a.indexer).Find(ctx, t.sid)
+		if err != nil {
+			return report, fmt.Errorf("find index of %s: %w", util.FormatSectorID(t.sid), err)
+		}
+
+		// the cache dirs left alone are orphans as well
+		var holders []string
+		for _, name := range report.Scanned {
+			if _, ok := found[t][name]; ok {
+				holders = append(holders, name)
+			}
+		}
+
+		report.Issues = append(report.Issues, api.StorageAuditIssue{
+			Kind:      api.StorageAuditOrphan,
+			ID:        t.sid,
+			Upgrade:   t.upgrade,
+			Indexed:   indexed,
+			Instances: holders,
+		})
+	}
+
+	return report, nil
+}
+
+// Run audits the storage periodically, until the ctx is done
+func (a *Auditor) Run(ctx context.Context) {
+	for {
+		a.scfg.Lock()
+		interval := a.scfg.Common.Storage.AuditInterval.Std()
+		a.scfg.Unlock()
+
+		// check again later, in case it is enabled
+		wait := interval
+		if wait <= 0 {
+			wait = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if interval <= 0 {
+			continue
+		}
+
+		report, err := a.Audit(ctx, true)
+		if err != nil {
+			log.Warnw("audit storage", "err", err)
+			continue
+		}
+
+		counts := map[api.StorageAuditIssueKind]int{}
+		for _, issue := range report.Issues {
+			counts[issue.Kind]++
+		}
+
+		l := log.With("on-chain", report.OnChain, "scanned", len(report.Scanned), "scan-errors", len(report.ScanErrors),
+			"no-files", counts[api.StorageAuditNoFiles], "not-indexed", counts[api.StorageAuditNotIndexed],
+			"wrong-store", counts[api.StorageAuditWrongStore], "orphans", counts[api.StorageAuditOrphan])
+
+		// these ones will turn into faults in the next proving period
+		if counts[api.StorageAuditNoFiles] > 0 || counts[api.StorageAuditNotIndexed] > 0 || counts[api.StorageAuditWrongStore] > 0 || len(report.ScanErrors) > 0 {
+			l.Warn("storage audited with issues")
+			continue
+		}
+
+		l.Info("storage audited")
+	}
+}
@@ -0,0 +1,529 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin/miner"
+	"github.com/filecoin-project/venus/venus-shared/types"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+var _ api.StorageCollector = (*Collector)(nil)
+
+func NewCollector(
+	scfg *modules.SafeConfig,
+	capi chain.API,
+	info api.MinerInfoAPI,
+	state api.SectorStateManager,
+	indexer api.SectorIndexer,
+	kv kvstore.KVStore,
+) (*Collector, error) {
+	candidates, err := kvstore.NewWrappedKVStore([]byte("candidates"), kv)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := kvstore.NewWrappedKVStore([]byte("records"), kv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Collector{
+		scfg:       scfg,
+		capi:       capi,
+		info:       info,
+		state:      state,
+		indexer:    indexer,
+		candidates: candidates,
+		records:    records,
+	}, nil
+}
+
+// Collector deletes the files of the aborted sectors, and of the sectors no longer on chain.
+// A sector is collected only if it has been a candidate for the grace period, and is double-checked against the chain.
+type Collector struct {
+	scfg    *modules.SafeConfig
+	capi    chain.API
+	info    api.MinerInfoAPI
+	state   api.SectorStateManager
+	indexer api.SectorIndexer
+
+	candidates kvstore.KVStore
+	records    kvstore.KVStore
+
+	// serializes the collections
+	mu sync.Mutex
+}
+
+// candidate is persisted, so that the grace period survives the restarts
+type candidate struct {
+	Reason    string
+	FirstSeen int64
+	// the files have been collected
+	Done bool
+}
+
+func makeCandidateKey(sid abi.SectorID) kvstore.Key {
+	return []byte(fmt.Sprintf("m-%d-n-%d", sid.Miner, sid.Number))
+}
+
+// the zero-padded timestamp keeps the records in order
+func makeRecordKey(ts time.Time, sid abi.SectorID) kvstore.Key {
+	return []byte(fmt.Sprintf("%020d-m-%d-n-%d", ts.UnixNano(), sid.Miner, sid.Number))
+}
+
+func (c *Collector) Collect(ctx context.Context, dryRun bool) (api.StorageGCReport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	report := api.StorageGCReport{
+		Time:   now.Unix(),
+		DryRun: dryRun,
+	}
+
+	c.scfg.Lock()
+	grace := c.scfg.Common.Storage.GCGracePeriod.Std()
+	miners := make([]abi.ActorID, 0, len(c.scfg.Miners))
+	for _, mcfg := range c.scfg.Miners {
+		miners = append(miners, mcfg.Actor)
+	}
+	c.scfg.Unlock()
+
+	ts, err := c.capi.ChainHead(ctx)
+	if err != nil {
+		return report, fmt.Errorf("get chain head: %w", err)
+	}
+
+	found, err := c.find(ctx, miners, ts)
+	if err != nil {
+		return report, err
+	}
+
+	prev, err := c.loadCandidates(ctx)
+	if err != nil {
+		return report, fmt.Errorf("load candidates: %w", err)
+	}
+
+	// the sectors no longer being candidates, such as the restored ones, start over next time
+	if !dryRun {
+		for sid := range prev {
+			if _, ok := found[sid]; ok {
+				continue
+			}
+
+			if err := c.candidates.Del(ctx, makeCandidateKey(sid)); err != nil {
+				return report, fmt.Errorf("delete candidate %s: %w", util.FormatSectorID(sid), err)
+			}
+		}
+	}
+
+	sids := make([]abi.SectorID, 0, len(found))
+	for sid := range found {
+		sids = append(sids, sid)
+	}
+
+	sort.Slice(sids, func(i, j int) bool {
+		if sids[i].Miner != sids[j].Miner {
+			return sids[i].Miner < sids[j].Miner
+		}
+
+		return sids[i].Number < sids[j].Number
+	})
+
+	for _, sid := range sids {
+		cand := candidate{
+			Reason:    found[sid],
+			FirstSeen: now.Unix(),
+		}
+
+		if p, ok := prev[sid]; ok {
+			if p.Done {
+				continue
+			}
+
+			cand.FirstSeen = p.FirstSeen
+		}
+
+		slog := log.With("miner", sid.Miner, "num", sid.Number, "reason", cand.Reason)
+
+		gone, err := c.goneFromChain(ctx, sid, cand.Reason, ts)
+		if err != nil {
+			slog.Warnw("check sector on chain", "err", err)
+			continue
+		}
+
+		if !gone {
+			slog.Debug("sector found on chain, not collected")
+			if !dryRun {
+				if err := c.candidates.Del(ctx, makeCandidateKey(sid)); err != nil {
+					return report, fmt.Errorf("delete candidate %s: %w", util.FormatSectorID(sid), err)
+				}
+			}
+
+			continue
+		}
+
+		eligibleAt := time.Unix(cand.FirstSeen, 0).Add(grace)
+		if now.Before(eligibleAt) {
+			report.Pending = append(report.Pending, api.StorageGCCandidate{
+				ID:         sid,
+				Reason:     cand.Reason,
+				FirstSeen:  cand.FirstSeen,
+				EligibleAt: eligibleAt.Unix(),
+			})
+
+			if !dryRun {
+				if err := c.saveCandidate(ctx, sid, cand); err != nil {
+					return report, err
+				}
+			}
+
+			continue
+		}
+
+		record, err := c.collect(ctx, sid, cand.Reason, dryRun)
+		if err != nil {
+			return report, fmt.Errorf("collect %s: %w", util.FormatSectorID(sid), err)
+		}
+
+		report.Collected = append(report.Collected, record)
+		if dryRun {
+			continue
+		}
+
+		if len(record.Errors) > 0 {
+			// try again next time
+			slog.Warnw("sector collected with errors", "errors", record.Errors)
+			if err := c.saveCandidate(ctx, sid, cand); err != nil {
+				return report, err
+			}
+
+			continue
+		}
+
+		slog.Infow("sector collected", "deleted", record.Deleted)
+		cand.Done = true
+		if err := c.saveCandidate(ctx, sid, cand); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// find returns the offline sectors of the miners, which are aborted, or are not live on chain
+func (c *Collector) find(ctx context.Context, miners []abi.ActorID, ts *types.TipSet) (map[abi.SectorID]string, error) {
+	live := map[abi.SectorID]struct{}{}
+	for _, mid := range miners {
+		minfo, err := c.info.Get(ctx, mid)
+		if err != nil {
+			return nil, fmt.Errorf("get miner info for %d: %w", mid, err)
+		}
+
+		for dlIdx := uint64(0); dlIdx < miner.WPoStPeriodDeadlines; dlIdx++ {
+			partitions, err := c.capi.StateMinerPartitions(ctx, minfo.Addr, dlIdx, ts.Key())
+			if err != nil {
+				return nil, fmt.Errorf("get partitions of %d in deadline %d: %w", mid, dlIdx, err)
+			}
+
+			for _, part := range partitions {
+				if err := part.LiveSectors.ForEach(func(num uint64) error {
+					live[abi.SectorID{Miner: mid, Number: abi.SectorNumber(num)}] = struct{}{}
+					return nil
+				}); err != nil {
+					return nil, fmt.Errorf("iterate live sectors of %d in deadline %d: %w", mid, dlIdx, err)
+				}
+			}
+		}
+	}
+
+	configured := map[abi.ActorID]struct{}{}
+	for _, mid := range miners {
+		configured[mid] = struct{}{}
+	}
+
+	found := map[abi.SectorID]string{}
+	err := c.state.ForEach(ctx, api.WorkerOffline, func(st api.SectorState) error {
+		if _, ok := configured[st.ID.Miner]; !ok {
+			return nil
+		}
+
+		if _, ok := live[st.ID]; ok {
+			return nil
+		}
+
+		switch {
+		case st.AbortReason != "":
+			found[st.ID] = api.StorageGCReasonAborted
+
+		case st.TerminateInfo.Terminated:
+			found[st.ID] = api.StorageGCReasonTerminated
+
+		case bool(st.Finalized) || bool(st.Imported):
+			found[st.ID] = api.StorageGCReasonRemoved
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("scan offline sectors: %w", err)
+	}
+
+	return found, nil
+}
+
+// goneFromChain makes sure that the sector is not on chain, and an aborted one is not waiting to be proved
+func (c *Collector) goneFromChain(ctx context.Context, sid abi.SectorID, reason string, ts *types.TipSet) (bool, error) {
+	minfo, err := c.info.Get(ctx, sid.Miner)
+	if err != nil {
+		return false, fmt.Errorf("get miner info: %w", err)
+	}
+
+	onChain, err := c.capi.StateSectorGetInfo(ctx, minfo.Addr, sid.Number, ts.Key())
+	if err != nil {
+		return false, fmt.Errorf("get sector info: %w", err)
+	}
+
+	if onChain != nil {
+		return false, nil
+	}
+
+	if reason != api.StorageGCReasonAborted {
+		return true, nil
+	}
+
+	allocated, err := c.capi.StateMinerSectorAllocated(ctx, minfo.Addr, sid.Number, ts.Key())
+	if err != nil {
+		return false, fmt.Errorf("check if sector allocated: %w", err)
+	}
+
+	if !allocated {
+		return true, nil
+	}
+
+	// the pre-commit info is not available once it is expired or proved
+	if _, err := c.capi.StateSectorPreCommitInfo(ctx, minfo.Addr, sid.Number, ts.Key()); err == nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// collect deletes the files in the instances pointed by the indexers
+func (c *Collector) collect(ctx context.Context, sid abi.SectorID, reason string, dryRun bool) (api.StorageGCRecord, error) {
+	record := api.StorageGCRecord{
+		Time:    time.Now().Unix(),
+		ID:      sid,
+		Reason:  reason,
+		Deleted: map[string][]string{},
+	}
+
+	for _, upgrade := range []bool{false, true} {
+		t := moveTarget{sid: sid, upgrade: upgrade}
+		instance, ok, err := t.typedIndexer(c.indexer).Find(ctx, sid)
+		if err != nil {
+			return record, fmt.Errorf("find index: %w", err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		store, err := c.indexer.StoreMgr().GetInstance(ctx, instance)
+		if err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("get store %s: %s", instance, err))
+			continue
+		}
+
+		paths, err := sectorObjects(ctx, store, t)
+		if err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("list objects in %s: %s", instance, err))
+			continue
+		}
+
+		for _, p := range paths {
+			if !dryRun {
+				if err := store.Del(ctx, p); err != nil && !errors.Is(err, objstore.ErrObjectNotFound) {
+					record.Errors = append(record.Errors, fmt.Sprintf("delete %s in %s: %s", p, instance, err))
+					continue
+				}
+			}
+
+			record.Deleted[instance] = append(record.Deleted[instance], p)
+		}
+	}
+
+	if dryRun {
+		return record, nil
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return record, fmt.Errorf("marshal record: %w", err)
+	}
+
+	if err := c.records.Put(ctx, makeRecordKey(time.Now(), sid), b); err != nil {
+		return record, fmt.Errorf("save record: %w", err)
+	}
+
+	return record, nil
+}
+
+// sectorObjects returns the existing objects of the sector in the store, the cache dir comes after the files in it
+func sectorObjects(ctx context.Context, store objstore.Store, t moveTarget) ([]string, error) {
+	cacheDir, sealed := t.paths()
+	candidates := []string{sealed}
+	if !t.upgrade {
+		candidates = append(candidates, util.SectorPath(util.SectorPathTypeUnsealed, t.sid))
+	}
+
+	var paths []string
+	for _, p := range candidates {
+		if _, err := store.Stat(ctx, p); err != nil {
+			if errors.Is(err, objstore.ErrObjectNotFound) {
+				continue
+			}
+
+			return nil, fmt.Errorf("stat %s: %w", p, err)
+		}
+
+		paths = append(paths, p)
+	}
+
+	names, err := store.List(ctx, cacheDir)
+	if err != nil {
+		if errors.Is(err, objstore.ErrObjectNotFound) {
+			return paths, nil
+		}
+
+		return nil, fmt.Errorf("list %s: %w", cacheDir, err)
+	}
+
+	for _, name := range names {
+		paths = append(paths, filepath.Join(cacheDir, name))
+	}
+
+	return append(paths, cacheDir), nil
+}
+
+func (c *Collector) loadCandidates(ctx context.Context) (map[abi.SectorID]candidate, error) {
+	iter, err := c.candidates.Scan(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	cands := map[abi.SectorID]candidate{}
+	for iter.Next() {
+		var sid abi.SectorID
+		if _, err := fmt.Sscanf(string(iter.Key()), "m-%d-n-%d", &sid.Miner, &sid.Number); err != nil {
+			continue
+		}
+
+		var cand candidate
+		if err := iter.View(ctx, func(b []byte) error {
+			return json.Unmarshal(b, &cand)
+		}); err != nil {
+			return nil, fmt.Errorf("load candidate %s: %w", util.FormatSectorID(sid), err)
+		}
+
+		cands[sid] = cand
+	}
+
+	return cands, nil
+}
+
+func (c *Collector) saveCandidate(ctx context.Context, sid abi.SectorID, cand candidate) error {
+	b, err := json.Marshal(cand)
+	if err != nil {
+		return fmt.Errorf("marshal candidate: %w", err)
+	}
+
+	if err := c.candidates.Put(ctx, makeCandidateKey(sid), b); err != nil {
+		return fmt.Errorf("save candidate %s: %w", util.FormatSectorID(sid), err)
+	}
+
+	return nil
+}
+
+func (c *Collector) Records(ctx context.Context, limit int) ([]api.StorageGCRecord, error) {
+	iter, err := c.records.Scan(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var records []api.StorageGCRecord
+	for iter.Next() {
+		var record api.StorageGCRecord
+		if err := iter.View(ctx, func(b []byte) error {
+			return json.Unmarshal(b, &record)
+		}); err != nil {
+			return nil, fmt.Errorf("load record %s: %w", string(iter.Key()), err)
+		}
+
+		records = append(records, record)
+	}
+
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+// Run collects the files periodically, until the ctx is done
+func (c *Collector) Run(ctx context.Context) {
+	for {
+		c.scfg.Lock()
+		interval := c.scfg.Common.Storage.GCInterval.Std()
+		c.scfg.Unlock()
+
+		// check again later, in case it is enabled
+		wait := interval
+		if wait <= 0 {
+			wait = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if interval <= 0 {
+			continue
+		}
+
+		report, err := c.Collect(ctx, false)
+		if err != nil {
+			log.Warnw("collect storage", "err", err)
+			continue
+		}
+
+		log.Infow("storage collected", "collected", len(report.Collected), "pending", len(report.Pending))
+	}
+}
@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin/miner"
+	"github.com/filecoin-project/venus/venus-shared/types"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+)
+
+const gcTestMiner = abi.ActorID(1000)
+
+type idMinerInfo struct{}
+
+func (idMinerInfo) Get(ctx context.Context, mid abi.ActorID) (*api.MinerInfo, error) {
+	maddr, err := address.NewIDAddress(uint64(mid))
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.MinerInfo{ID: mid, Addr: maddr}, nil
+}
+
+// gcChain keeps the live sectors in the first partition of the first deadline
+type gcChain struct {
+	chain.API
+	live      []uint64
+	onChain   map[abi.SectorNumber]bool
+	allocated map[abi.SectorNumber]bool
+}
+
+func (c *gcChain) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	return &types.TipSet{}, nil
+}
+
+func (c *gcChain) StateMinerPartitions(ctx context.Context, maddr address.Address, dlIdx uint64, tsk types.TipSetKey) ([]chain.Partition, error) {
+	if dlIdx != 0 {
+		return nil, nil
+	}
+
+	return []chain.Partition{{LiveSectors: bitfield.NewFromSet(c.live)}}, nil
+}
+
+func (c *gcChain) StateSectorGetInfo(ctx context.Context, maddr address.Address, num abi.SectorNumber, tsk types.TipSetKey) (*miner.SectorOnChainInfo, error) {
+	if c.onChain[num] {
+		return &miner.SectorOnChainInfo{SectorNumber: num}, nil
+	}
+
+	return nil, nil
+}
+
+func (c *gcChain) StateMinerSectorAllocated(ctx context.Context, maddr address.Address, num abi.SectorNumber, tsk types.TipSetKey) (bool, error) {
+	return c.allocated[num], nil
+}
+
+type offlineStates struct {
+	api.SectorStateManager
+	offline []api.SectorState
+}
+
+func (s *offlineStates) ForEach(ctx context.Context, ws api.SectorWorkerState, fn func(api.SectorState) error) error {
+	if ws != api.WorkerOffline {
+		return nil
+	}
+
+	for _, st := range s.offline {
+		if err := fn(st); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newTestCollector(t *testing.T, grace time.Duration, capi *gcChain, states []api.SectorState) *Collector {
+	cfg := modules.DefaultConfig(false)
+	cfg.Common.Storage.GCGracePeriod = modules.Duration(grace)
+	cfg.Miners = append(cfg.Miners, modules.MinerConfig{Actor: gcTestMiner})
+
+	scfg := &modules.SafeConfig{
+		Config: &cfg,
+		Locker: &sync.Mutex{},
+	}
+
+	c, err := NewCollector(scfg, capi, idMinerInfo{}, &offlineStates{offline: states}, nil, kvstore.NewMemKVStore())
+	if err != nil {
+		t.Fatalf("construct collector: %s", err)
+	}
+
+	return c
+}
+
+func gcTestSector(num abi.SectorNumber) abi.SectorID {
+	return abi.SectorID{Miner: gcTestMiner, Number: num}
+}
+
+func TestCollectorFind(t *testing.T) {
+	states := []api.SectorState{
+		{ID: gcTestSector(1), AbortReason: "pc1 failed"},
+		{ID: gcTestSector(2), TerminateInfo: api.TerminateInfo{Terminated: true}, Finalized: true},
+		{ID: gcTestSector(3), Finalized: true},
+		{ID: gcTestSector(4), Imported: true},
+		// live on chain
+		{ID: gcTestSector(5), Finalized: true},
+		{ID: gcTestSector(6), AbortReason: "restored later"},
+		// neither finalized nor aborted
+		{ID: gcTestSector(7)},
+		// the miner is not configured
+		{ID: abi.SectorID{Miner: 1001, Number: 3}, Finalized: true},
+	}
+
+	c := newTestCollector(t, time.Hour, &gcChain{live: []uint64{5, 6}}, states)
+	found, err := c.find(context.Background(), []abi.ActorID{gcTestMiner}, &types.TipSet{})
+	if err != nil {
+		t.Fatalf("find: %s", err)
+	}
+
+	expected := map[abi.SectorID]string{
+		gcTestSector(1): api.StorageGCReasonAborted,
+		gcTestSector(2): api.StorageGCReasonTerminated,
+		gcTestSector(3): api.StorageGCReasonRemoved,
+		gcTestSector(4): api.StorageGCReasonRemoved,
+	}
+
+	if len(found) != len(expected) {
+		t.Fatalf("got candidates %v, expected %v", found, expected)
+	}
+
+	for sid, reason := range expected {
+		if found[sid] != reason {
+			t.Errorf("sector %d: got reason %q, expected %q", sid.Number, found[sid], reason)
+		}
+	}
+}
+
+func TestCollectorGoneFromChain(t *testing.T) {
+	capi := &gcChain{
+		onChain:   map[abi.SectorNumber]bool{1: true, 2: true},
+		allocated: map[abi.SectorNumber]bool{1: true, 2: true},
+	}
+
+	c := newTestCollector(t, time.Hour, capi, nil)
+
+	cases := []struct {
+		num    abi.SectorNumber
+		reason string
+		gone   bool
+	}{
+		{1, api.StorageGCReasonRemoved, false},
+		{1, api.StorageGCReasonTerminated, false},
+		{2, api.StorageGCReasonAborted, false},
+		{3, api.StorageGCReasonRemoved, true},
+		{3, api.StorageGCReasonTerminated, true},
+		// the number is never allocated, nothing to wait for
+		{3, api.StorageGCReasonAborted, true},
+	}
+
+	for _, tc := range cases {
+		gone, err := c.goneFromChain(context.Background(), gcTestSector(tc.num), tc.reason, &types.TipSet{})
+		if err != nil {
+			t.Fatalf("sector %d, %s: %s", tc.num, tc.reason, err)
+		}
+
+		if gone != tc.gone {
+			t.Errorf("sector %d, %s: got gone %v, expected %v", tc.num, tc.reason, gone, tc.gone)
+		}
+	}
+}
+
+func TestCollectorGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	states := []api.SectorState{
+		{ID: gcTestSector(1), Finalized: true},
+		{ID: gcTestSector(2), Finalized: true},
+		{ID: gcTestSector(3), Finalized: true},
+	}
+
+	// sector 2 is not live, but its info is still on chain
+	capi := &gcChain{onChain: map[abi.SectorNumber]bool{2: true}}
+	c := newTestCollector(t, time.Hour, capi, states)
+
+	// collected before
+	if err := c.saveCandidate(ctx, gcTestSector(3), candidate{Reason: api.StorageGCReasonRemoved, Done: true}); err != nil {
+		t.Fatalf("save candidate: %s", err)
+	}
+
+	// not a candidate anymore
+	if err := c.saveCandidate(ctx, gcTestSector(4), candidate{Reason: api.StorageGCReasonAborted, FirstSeen: 1}); err != nil {
+		t.Fatalf("save candidate: %s", err)
+	}
+
+	dry, err := c.Collect(ctx, true)
+	if err != nil {
+		t.Fatalf("dry run: %s", err)
+	}
+
+	if len(dry.Pending) != 1 || dry.Pending[0].ID != gcTestSector(1) || len(dry.Collected) != 0 {
+		t.Fatalf("dry run: got pending %v, collected %v, expected only sector 1 pending", dry.Pending, dry.Collected)
+	}
+
+	cands, err := c.loadCandidates(ctx)
+	if err != nil {
+		t.Fatalf("load candidates: %s", err)
+	}
+
+	if _, ok := cands[gcTestSector(1)]; ok || len(cands) != 2 {
+		t.Fatalf("candidates changed by the dry run: %v", cands)
+	}
+
+	first, err := c.Collect(ctx, false)
+	if err != nil {
+		t.Fatalf("collect: %s", err)
+	}
+
+	if len(first.Pending) != 1 || first.Pending[0].ID != gcTestSector(1) || len(first.Collected) != 0 {
+		t.Fatalf("collect: got pending %v, collected %v, expected only sector 1 pending", first.Pending, first.Collected)
+	}
+
+	pending := first.Pending[0]
+	if pending.EligibleAt != pending.FirstSeen+int64(time.Hour/time.Second) {
+		t.Fatalf("got eligible at %d, expected first seen %d plus the grace period", pending.EligibleAt, pending.FirstSeen)
+	}
+
+	cands, err = c.loadCandidates(ctx)
+	if err != nil {
+		t.Fatalf("load candidates: %s", err)
+	}
+
+	expected := map[abi.SectorID]bool{
+		gcTestSector(1): false,
+		gcTestSector(3): true,
+	}
+
+	if len(cands) != len(expected) {
+		t.Fatalf("got candidates %v, expected sectors 1 & 3", cands)
+	}
+
+	for sid, done := range expected {
+		cand, ok := cands[sid]
+		if !ok || cand.Done != done {
+			t.Errorf("sector %d: got candidate %v, %v, expected done %v", sid.Number, cand, ok, done)
+		}
+	}
+
+	// the grace period starts from the first time it is seen
+	second, err := c.Collect(ctx, false)
+	if err != nil {
+		t.Fatalf("collect again: %s", err)
+	}
+
+	if len(second.Pending) != 1 || second.Pending[0].FirstSeen != pending.FirstSeen {
+		t.Fatalf("collect again: got pending %v, expected first seen %d", second.Pending, pending.FirstSeen)
+	}
+}
@@ -0,0 +1,830 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+var log = logging.New("storage")
+
+var _ api.StorageMover = (*Mover)(nil)
+
+// the oldest finished jobs will be dropped once exceeding this number
+const maxFinishedMoveJobs = 64
+
+func NewMover(ctx context.Context, scfg *modules.SafeConfig, indexer api.SectorIndexer, placer api.PersistStorePlacer, kv kvstore.KVStore) (*Mover, error) {
+	m := &Mover{
+		ctx:     ctx,
+		scfg:    scfg,
+		indexer: indexer,
+		placer:  placer,
+		kv:      kv,
+		moving:  map[moveTarget]string{},
+	}
+
+	if err := m.load(ctx); err != nil {
+		return nil, fmt.Errorf("load move jobs: %w", err)
+	}
+
+	return m, nil
+}
+
+// Mover copies the persisted files of the sectors to another store, switches the index entries, and then deletes the sources.
+// The jobs are persisted, so that the source files of the sectors moved by the jobs interrupted by a restart can still be deleted.
+type Mover struct {
+	ctx     context.Context
+	scfg    *modules.SafeConfig
+	indexer api.SectorIndexer
+	placer  api.PersistStorePlacer
+	kv      kvstore.KVStore
+
+	mu     sync.Mutex
+	seq    uint64
+	jobs   []*moveJob
+	moving map[moveTarget]string
+	// jobs interrupted by the last restart, with the source files to be deleted
+	interrupted []*moveJob
+	reindexing  bool
+}
+
+// moveTarget is a sealed sector, or the replica of a snapup sector
+type moveTarget struct {
+	sid     abi.SectorID
+	upgrade bool
+}
+
+func (t moveTarget) typedIndexer(indexer api.SectorIndexer) api.SectorTypedIndexer {
+	if t.upgrade {
+		return indexer.Upgrade()
+	}
+
+	return indexer
+}
+
+func (t moveTarget) paths() (string, string) {
+	if t.upgrade {
+		return util.SectorPath(util.SectorPathTypeUpdateCache, t.sid), util.SectorPath(util.SectorPathTypeUpdate, t.sid)
+	}
+
+	return util.SectorPath(util.SectorPathTypeCache, t.sid), util.SectorPath(util.SectorPathTypeSealed, t.sid)
+}
+
+// movedSector is a sector copied and indexed to the destination, with the source files to be deleted
+type movedSector struct {
+	ID       abi.SectorID
+	Upgrade  bool
+	Files    []string
+	CacheDir string
+}
+
+func (ms movedSector) target() moveTarget {
+	return moveTarget{sid: ms.ID, upgrade: ms.Upgrade}
+}
+
+type moveJob struct {
+	api.StorageMoveJob
+	// moved sectors whose source files are not deleted yet
+	pending []movedSector
+	// bytes to be copied, estimated before starting
+	required uint64
+	cancel   context.CancelFunc
+}
+
+// moveJobRecord is the persisted form of a move job
+type moveJobRecord struct {
+	Job     api.StorageMoveJob
+	Pending []movedSector
+}
+
+// snapshot should be called with mu locked
+func (j *moveJob) snapshot() api.StorageMoveJob {
+	snap := j.StorageMoveJob
+	snap.Failures = append([]api.StorageMoveFailure(nil), j.Failures...)
+	if j.Current != nil {
+		current := *j.Current
+		snap.Current = &current
+	}
+
+	return snap
+}
+
+func (m *Mover) Start(ctx context.Context, spec api.StorageMoveSpec) (api.StorageMoveJob, error) {
+	if spec.From == "" || spec.To == "" {
+		return api.StorageMoveJob{}, fmt.Errorf("both of the source and the destination are required")
+	}
+
+	if spec.From == spec.To {
+		return api.StorageMoveJob{}, fmt.Errorf("the source and the destination are the same")
+	}
+
+	if len(spec.Sectors) > 0 && spec.Miner == 0 {
+		return api.StorageMoveJob{}, fmt.Errorf("miner is required for the specified sectors")
+	}
+
+	src, err := m.indexer.StoreMgr().GetInstance(ctx, spec.From)
+	if err != nil {
+		return api.StorageMoveJob{}, fmt.Errorf("get store %s: %w", spec.From, err)
+	}
+
+	if _, err := m.indexer.StoreMgr().GetInstance(ctx, spec.To); err != nil {
+		return api.StorageMoveJob{}, fmt.Errorf("get store %s: %w", spec.To, err)
+	}
+
+	available, err := m.availableSpace(ctx, spec.To)
+	if err != nil {
+		return api.StorageMoveJob{}, err
+	}
+
+	targets, err := m.collect(ctx, spec)
+	if err != nil {
+		return api.StorageMoveJob{}, fmt.Errorf("collect sectors in %s: %w", spec.From, err)
+	}
+
+	if len(targets) == 0 {
+		return api.StorageMoveJob{}, fmt.Errorf("no matched sector found in %s", spec.From)
+	}
+
+	required, err := requiredSpace(ctx, src, targets)
+	if err != nil {
+		return api.StorageMoveJob{}, fmt.Errorf("estimate the space required: %w", err)
+	}
+
+	m.scfg.Lock()
+	rateLimit := m.scfg.Common.Storage.MoveRateLimit
+	deleteDelay := m.scfg.Common.Storage.MoveDeleteDelay.Std()
+	m.scfg.Unlock()
+
+	if spec.RateLimit > 0 {
+		rateLimit = spec.RateLimit
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reindexing {
+		return api.StorageMoveJob{}, fmt.Errorf("reindexing in progress")
+	}
+
+	for _, t := range targets {
+		if jid, ok := m.moving[t]; ok {
+			return api.StorageMoveJob{}, fmt.Errorf("%s is being moved in job %s", util.FormatSectorID(t.sid), jid)
+		}
+	}
+
+	// the running jobs to the same destination are not reflected in the free space yet
+	for _, j := range m.jobs {
+		if j.State == api.StorageMoveJobRunning && j.Spec.To == spec.To && j.required > j.BytesCopied {
+			inflight := j.required - j.BytesCopied
+			if inflight > available {
+				inflight = available
+			}
+
+			available -= inflight
+		}
+	}
+
+	if required > available {
+		return api.StorageMoveJob{}, fmt.Errorf("%s required in %s, only %s available",
+			units.BytesSize(float64(required)), spec.To, units.BytesSize(float64(available)))
+	}
+
+	m.seq++
+	now := time.Now()
+	jobCtx, cancel := context.WithCancel(m.ctx)
+	job := &moveJob{
+		StorageMoveJob: api.StorageMoveJob{
+			ID:        fmt.Sprintf("move-%d-%d", now.Unix(), m.seq),
+			Spec:      spec,
+			State:     api.StorageMoveJobRunning,
+			Total:     len(targets),
+			StartedAt: now.Unix(),
+		},
+		required: required,
+		cancel:   cancel,
+	}
+
+	m.jobs = append(m.jobs, job)
+	for _, t := range targets {
+		m.moving[t] = job.ID
+	}
+
+	m.save(job)
+
+	log.Infow("move job started", "id", job.ID, "from", spec.From, "to", spec.To, "sectors", len(targets), "rate-limit", rateLimit)
+	go m.run(jobCtx, job, targets, rateLimit, deleteDelay)
+
+	return job.snapshot(), nil
+}
+
+// availableSpace returns the space available in the destination, the readonly, draining, or non-local ones are rejected
+func (m *Mover) availableSpace(ctx context.Context, name string) (uint64, error) {
+	stores, err := m.placer.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list persist stores: %w", err)
+	}
+
+	for _, st := range stores {
+		if st.Instance != name {
+			continue
+		}
+
+		if st.ReadOnly {
+			return 0, fmt.Errorf("store %s is readonly", name)
+		}
+
+		if st.Draining {
+			return 0, fmt.Errorf("store %s is draining", name)
+		}
+
+		if st.SpaceUnknown {
+			return 0, fmt.Errorf("store %s is not local, its space is unknown", name)
+		}
+
+		if st.Free <= st.Reserved {
+			return 0, nil
+		}
+
+		return st.Free - st.Reserved, nil
+	}
+
+	return 0, fmt.Errorf("store %s not found in the persist stores", name)
+}
+
+// requiredSpace sums up the sizes of the files to be copied, the missing ones are left to the moving
+func requiredSpace(ctx context.Context, src objstore.Store, targets []moveTarget) (uint64, error) {
+	var required uint64
+	for _, t := range targets {
+		cacheDir, sealed := t.paths()
+		stat, err := src.Stat(ctx, sealed)
+		if err != nil {
+			if errors.Is(err, objstore.ErrObjectNotFound) {
+				continue
+			}
+
+			return 0, fmt.Errorf("stat %s: %w", sealed, err)
+		}
+
+		required += uint64(stat.Size)
+
+		files := []string{filepath.Join(cacheDir, "p_aux"), filepath.Join(cacheDir, "t_aux")}
+		for _, name := range util.TreeRLastFiles(abi.SectorSize(stat.Size)) {
+			files = append(files, filepath.Join(cacheDir, name))
+		}
+
+		for _, p := range files {
+			fstat, err := src.Stat(ctx, p)
+			if err != nil {
+				if errors.Is(err, objstore.ErrObjectNotFound) {
+					continue
+				}
+
+				return 0, fmt.Errorf("stat %s: %w", p, err)
+			}
+
+			required += uint64(fstat.Size)
+		}
+	}
+
+	return required, nil
+}
+
+func (m *Mover) collect(ctx context.Context, spec api.StorageMoveSpec) ([]moveTarget, error) {
+	var targets []moveTarget
+	for _, upgrade := range []bool{false, true} {
+		indexer := moveTarget{upgrade: upgrade}.typedIndexer(m.indexer)
+
+		if len(spec.Sectors) > 0 {
+			for _, num := range spec.Sectors {
+				sid := abi.SectorID{Miner: spec.Miner, Number: num}
+				instance, ok, err := indexer.Find(ctx, sid)
+				if err != nil {
+					return nil, fmt.Errorf("find index for %s: %w", util.FormatSectorID(sid), err)
+				}
+
+				if ok && instance == spec.From {
+					targets = append(targets, moveTarget{sid: sid, upgrade: upgrade})
+				}
+			}
+
+			continue
+		}
+
+		sids, err := indexer.Sectors(ctx, spec.From)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sid := range sids {
+			if spec.Miner == 0 || sid.Miner == spec.Miner {
+				targets = append(targets, moveTarget{sid: sid, upgrade: upgrade})
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+func (m *Mover) run(ctx context.Context, job *moveJob, targets []moveTarget, rateLimit uint64, deleteDelay time.Duration) {
+	defer func() {
+		job.cancel()
+
+		m.mu.Lock()
+		for _, t := range targets {
+			delete(m.moving, t)
+		}
+		m.mu.Unlock()
+	}()
+
+	jlog := log.With("job", job.ID)
+
+	var moved []movedSector
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+
+		m.update(job, func(j *moveJob) {
+			sid := t.sid
+			j.Current = &sid
+		})
+
+		res, copied, err := m.moveSector(ctx, job.Spec, t, rateLimit)
+		if err != nil {
+			jlog.Warnw("move sector", "miner", t.sid.Miner, "num", t.sid.Number, "upgrade", t.upgrade, "err", err)
+		} else {
+			moved = append(moved, res)
+		}
+
+		m.update(job, func(j *moveJob) {
+			j.BytesCopied += copied
+			if err != nil {
+				j.Failures = append(j.Failures, api.StorageMoveFailure{
+					ID:      t.sid,
+					Upgrade: t.upgrade,
+					Error:   err.Error(),
+				})
+			} else {
+				j.Moved++
+				j.pending = append(j.pending, res)
+			}
+		})
+	}
+
+	m.update(job, func(j *moveJob) {
+		j.Current = nil
+	})
+
+	if len(moved) > 0 && deleteDelay > 0 && ctx.Err() == nil {
+		jlog.Infow("waiting before deleting the source files", "delay", deleteDelay, "sectors", len(moved))
+		select {
+		case <-ctx.Done():
+		case <-time.After(deleteDelay):
+		}
+	}
+
+	if ctx.Err() != nil {
+		// the index entries have been switched, the sources are kept in case of being read
+		jlog.Warnw("move job canceled, source files of the moved sectors are kept", "moved", len(moved))
+		m.finish(job, api.StorageMoveJobCanceled, fmt.Sprintf("canceled, source files of %d moved sectors are kept", len(moved)))
+		return
+	}
+
+	src, err := m.indexer.StoreMgr().GetInstance(ctx, job.Spec.From)
+	if err != nil {
+		m.finish(job, api.StorageMoveJobFailed, fmt.Sprintf("get source store: %s", err))
+		return
+	}
+
+	for _, ms := range moved {
+		if m.cleanSource(ctx, src, ms) {
+			m.cleaned(job, ms)
+		}
+	}
+
+	state := api.StorageMoveJobDone
+	errMsg := ""
+	if failed := len(targets) - len(moved); failed > 0 {
+		state = api.StorageMoveJobFailed
+		errMsg = fmt.Sprintf("%d of %d sectors failed", failed, len(targets))
+	}
+
+	m.finish(job, state, errMsg)
+	jlog.Infow("move job finished", "state", state, "moved", len(moved), "total", len(targets))
+}
+
+// moveSector copies the files, and switches the index entry to the destination
+func (m *Mover) moveSector(ctx context.Context, spec api.StorageMoveSpec, t moveTarget, rateLimit uint64) (movedSector, uint64, error) {
+	res := movedSector{ID: t.sid, Upgrade: t.upgrade}
+	storeMgr := m.indexer.StoreMgr()
+	src, err := storeMgr.GetInstance(ctx, spec.From)
+	if err != nil {
+		return res, 0, fmt.Errorf("get source store: %w", err)
+	}
+
+	dst, err := storeMgr.GetInstance(ctx, spec.To)
+	if err != nil {
+		return res, 0, fmt.Errorf("get destination store: %w", err)
+	}
+
+	cacheDir, sealed := t.paths()
+	res.CacheDir = cacheDir
+
+	stat, err := src.Stat(ctx, sealed)
+	if err != nil {
+		return res, 0, fmt.Errorf("stat sealed file: %w", err)
+	}
+
+	treeFiles := util.TreeRLastFiles(abi.SectorSize(stat.Size))
+	if len(treeFiles) == 0 {
+		return res, 0, fmt.Errorf("unknown sector size %d", stat.Size)
+	}
+
+	required := []string{sealed, filepath.Join(cacheDir, "p_aux")}
+	for _, name := range treeFiles {
+		required = append(required, filepath.Join(cacheDir, name))
+	}
+
+	optional := []string{filepath.Join(cacheDir, "t_aux")}
+
+	var copied uint64
+	success := false
+	defer func() {
+		if success {
+			return
+		}
+
+		// the partial copies are useless
+		for _, p := range res.Files {
+			if derr := dst.Del(ctx, p); derr != nil && !errors.Is(derr, objstore.ErrObjectNotFound) {
+				log.Warnw("delete copied file", "store", spec.To, "path", p, "err", derr)
+			}
+		}
+	}()
+
+	for _, p := range append(required, optional...) {
+		n, err := copyObject(ctx, src, dst, p, rateLimit, spec.Checksum)
+		copied += n
+		if err != nil {
+			if errors.Is(err, objstore.ErrObjectNotFound) && contains(optional, p) {
+				continue
+			}
+
+			return res, copied, fmt.Errorf("copy %s: %w", p, err)
+		}
+
+		res.Files = append(res.Files, p)
+	}
+
+	indexer := t.typedIndexer(m.indexer)
+	instance, ok, err := indexer.Find(ctx, t.sid)
+	if err != nil {
+		return res, copied, fmt.Errorf("find index: %w", err)
+	}
+
+	if !ok || instance != spec.From {
+		return res, copied, fmt.Errorf("index changed to %q during moving", instance)
+	}
+
+	if err := indexer.Update(ctx, t.sid, spec.To); err != nil {
+		return res, copied, fmt.Errorf("update index: %w", err)
+	}
+
+	success = true
+	log.Infow("sector moved", "miner", t.sid.Miner, "num", t.sid.Number, "upgrade", t.upgrade, "from", spec.From, "to", spec.To, "bytes", copied)
+	return res, copied, nil
+}
+
+// cleanSource deletes the source files of a moved sector, the failures are only logged
+func (m *Mover) cleanSource(ctx context.Context, src objstore.Store, ms movedSector) bool {
+	ok := true
+	for _, p := range ms.Files {
+		if err := src.Del(ctx, p); err != nil && !errors.Is(err, objstore.ErrObjectNotFound) {
+			log.Warnw("delete source file", "path", p, "err", err)
+			ok = false
+		}
+	}
+
+	// the dir may not exist in some kinds of stores, or may contain other files
+	if err := src.Del(ctx, ms.CacheDir); err != nil && !errors.Is(err, objstore.ErrObjectNotFound) {
+		log.Debugw("delete source cache dir", "path", ms.CacheDir, "err", err)
+	}
+
+	return ok
+}
+
+func (m *Mover) List(ctx context.Context) ([]api.StorageMoveJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]api.StorageMoveJob, 0, len(m.jobs))
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, m.jobs[i].snapshot())
+	}
+
+	return jobs, nil
+}
+
+func (m *Mover) Cancel(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, job := range m.jobs {
+		if job.ID != id {
+			continue
+		}
+
+		if job.State != api.StorageMoveJobRunning {
+			return fmt.Errorf("job %s is %s already", id, job.State)
+		}
+
+		job.cancel()
+		return nil
+	}
+
+	return fmt.Errorf("job %s not found", id)
+}
+
+func (m *Mover) update(job *moveJob, fn func(*moveJob)) {
+	m.mu.Lock()
+	fn(job)
+	m.save(job)
+	m.mu.Unlock()
+}
+
+// cleaned drops the moved sector from the pending ones, once its source files are deleted
+func (m *Mover) cleaned(job *moveJob, ms movedSector) {
+	m.update(job, func(j *moveJob) {
+		j.Cleaned++
+		for i := range j.pending {
+			if j.pending[i].ID == ms.ID && j.pending[i].Upgrade == ms.Upgrade {
+				j.pending = append(j.pending[:i], j.pending[i+1:]...)
+				break
+			}
+		}
+	})
+}
+
+func (m *Mover) finish(job *moveJob, state api.StorageMoveJobState, errMsg string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job.State = state
+	job.Error = errMsg
+	job.FinishedAt = time.Now().Unix()
+	m.save(job)
+
+	finished := 0
+	for _, j := range m.jobs {
+		if j.State != api.StorageMoveJobRunning {
+			finished++
+		}
+	}
+
+	kept := m.jobs[:0]
+	for _, j := range m.jobs {
+		if finished > maxFinishedMoveJobs && j.State != api.StorageMoveJobRunning {
+			finished--
+			if err := m.kv.Del(m.ctx, kvstore.Key(j.ID)); err != nil {
+				log.Warnw("delete move job", "id", j.ID, "err", err)
+			}
+
+			continue
+		}
+
+		kept = append(kept, j)
+	}
+
+	m.jobs = kept
+}
+
+// Run deletes the source files of the sectors moved by the jobs interrupted by the last restart,
+// once the delete delay has passed.
+func (m *Mover) Run(ctx context.Context) {
+	m.mu.Lock()
+	interrupted := m.interrupted
+	m.interrupted = nil
+	m.mu.Unlock()
+
+	if len(interrupted) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	var targets []moveTarget
+	for _, job := range interrupted {
+		for _, ms := range job.pending {
+			targets = append(targets, ms.target())
+		}
+	}
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		for _, t := range targets {
+			delete(m.moving, t)
+		}
+		m.mu.Unlock()
+	}()
+
+	m.scfg.Lock()
+	deleteDelay := m.scfg.Common.Storage.MoveDeleteDelay.Std()
+	m.scfg.Unlock()
+
+	log.Infow("waiting before deleting the source files of the interrupted move jobs", "jobs", len(interrupted), "delay", deleteDelay)
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(deleteDelay):
+	}
+
+	for _, job := range interrupted {
+		src, err := m.indexer.StoreMgr().GetInstance(ctx, job.Spec.From)
+		if err != nil {
+			log.Warnw("get source store of the interrupted move job", "job", job.ID, "err", err)
+			continue
+		}
+
+		m.mu.Lock()
+		pending := append([]movedSector(nil), job.pending...)
+		m.mu.Unlock()
+
+		for _, ms := range pending {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if m.cleanSource(ctx, src, ms) {
+				m.cleaned(job, ms)
+			}
+		}
+
+		m.update(job, func(j *moveJob) {
+			if len(j.pending) == 0 {
+				j.Error = "interrupted by restart, source files of the moved sectors are deleted"
+			}
+		})
+
+		log.Infow("source files of the interrupted move job deleted", "job", job.ID, "sectors", len(pending))
+	}
+}
+
+// load restores the persisted jobs, the running ones are interrupted by the restart
+func (m *Mover) load(ctx context.Context) error {
+	iter, err := m.kv.Scan(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer iter.Close()
+
+	for iter.Next() {
+		var rec moveJobRecord
+		if err := iter.View(ctx, func(b []byte) error {
+			return json.Unmarshal(b, &rec)
+		}); err != nil {
+			return fmt.Errorf("load move job %s: %w", string(iter.Key()), err)
+		}
+
+		job := &moveJob{
+			StorageMoveJob: rec.Job,
+			pending:        rec.Pending,
+			cancel:         func() {},
+		}
+
+		if job.State == api.StorageMoveJobRunning {
+			job.State = api.StorageMoveJobFailed
+			job.Error = fmt.Sprintf("interrupted by restart, source files of %d moved sectors are to be deleted", len(job.pending))
+			job.Current = nil
+			job.FinishedAt = time.Now().Unix()
+			m.save(job)
+
+			if len(job.pending) > 0 {
+				m.interrupted = append(m.interrupted, job)
+				for _, ms := range job.pending {
+					m.moving[ms.target()] = job.ID
+				}
+			}
+		}
+
+		m.jobs = append(m.jobs, job)
+	}
+
+	sort.SliceStable(m.jobs, func(i, j int) bool {
+		return m.jobs[i].StartedAt < m.jobs[j].StartedAt
+	})
+
+	return nil
+}
+
+// save persists the job, it should be called with mu locked
+func (m *Mover) save(job *moveJob) {
+	b, err := json.Marshal(moveJobRecord{
+		Job:     job.StorageMoveJob,
+		Pending: job.pending,
+	})
+	if err != nil {
+		log.Warnw("marshal move job", "id", job.ID, "err", err)
+		return
+	}
+
+	if err := m.kv.Put(m.ctx, kvstore.Key(job.ID), b); err != nil {
+		log.Warnw("save move job", "id", job.ID, "err", err)
+	}
+}
+
+// copyObject copies the object, and verifies the size of the copy, along with the checksum if required
+func copyObject(ctx context.Context, src, dst objstore.Store, p string, rateLimit uint64, checksum bool) (uint64, error) {
+	stat, err := src.Stat(ctx, p)
+	if err != nil {
+		return 0, fmt.Errorf("stat source: %w", err)
+	}
+
+	r, err := src.Get(ctx, p)
+	if err != nil {
+		return 0, fmt.Errorf("open source: %w", err)
+	}
+
+	defer r.Close()
+
+	hasher := sha256.New()
+	var reader io.Reader = r
+	if checksum {
+		reader = io.TeeReader(reader, hasher)
+	}
+
+	written, err := dst.Put(ctx, p, newThrottledReader(ctx, reader, rateLimit))
+	if err != nil {
+		return uint64(written), fmt.Errorf("write destination: %w", err)
+	}
+
+	if written != stat.Size {
+		return uint64(written), fmt.Errorf("%d bytes written, expected %d", written, stat.Size)
+	}
+
+	dstStat, err := dst.Stat(ctx, p)
+	if err != nil {
+		return uint64(written), fmt.Errorf("stat destination: %w", err)
+	}
+
+	if dstStat.Size != stat.Size {
+		return uint64(written), fmt.Errorf("size of the copy is %d, expected %d", dstStat.Size, stat.Size)
+	}
+
+	if checksum {
+		sum, err := checksumObject(ctx, dst, p)
+		if err != nil {
+			return uint64(written), fmt.Errorf("checksum destination: %w", err)
+		}
+
+		if !bytes.Equal(sum, hasher.Sum(nil)) {
+			return uint64(written), fmt.Errorf("checksum mismatch")
+		}
+	}
+
+	return uint64(written), nil
+}
+
+func checksumObject(ctx context.Context, store objstore.Store, p string) ([]byte, error) {
+	r, err := store.Get(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+// foundFiles records the files of a sector found in a store
+type foundFiles struct {
+	sealed bool
+	cache  bool
+}
+
+// Reindex scans the sealed & cache dirs, along with the update & update-cache dirs, in the persist stores,
+// and repairs the index of the sectors held by exactly one store.
+func (m *Mover) Reindex(ctx context.Context, spec api.StorageReindexSpec) (api.StorageReindexReport, error) {
+	m.mu.Lock()
+	if m.reindexing {
+		m.mu.Unlock()
+		return api.StorageReindexReport{}, fmt.Errorf("reindexing in progress")
+	}
+
+	if len(m.moving) > 0 {
+		m.mu.Unlock()
+		return api.StorageReindexReport{}, fmt.Errorf("move jobs in progress")
+	}
+
+	m.reindexing = true
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		m.reindexing = false
+		m.mu.Unlock()
+	}()
+
+	storeMgr := m.indexer.StoreMgr()
+	names := spec.Stores
+	if len(names) == 0 {
+		names = storeMgr.Instances(ctx)
+	}
+
+	report := api.StorageReindexReport{
+		DryRun:     spec.DryRun,
+		ScanErrors: map[string]string{},
+	}
+
+	found, err := scanStores(ctx, storeMgr, names, &report.Scanned, report.ScanErrors)
+	if err != nil {
+		return report, err
+	}
+
+	scanned := map[string]bool{}
+	for _, name := range report.Scanned {
+		scanned[name] = true
+	}
+
+	targets := make([]moveTarget, 0, len(found))
+	for t := range found {
+		targets = append(targets, t)
+	}
+
+	sortTargets(targets)
+
+	updates := map[moveTarget]string{}
+	for _, t := range targets {
+		holders := sealedHolders(found[t], report.Scanned)
+		for _, name := range report.Scanned {
+			files, ok := found[t][name]
+			if !ok {
+				continue
+			}
+
+			if files.sealed != files.cache {
+				report.Incomplete = append(report.Incomplete, api.StorageReindexEntry{
+					ID:        t.sid,
+					Upgrade:   t.upgrade,
+					Instances: []string{name},
+				})
+			}
+		}
+
+		if len(holders) == 0 {
+			continue
+		}
+
+		report.Found++
+
+		indexed, ok, err := t.typedIndexer(m.indexer).Find(ctx, t.sid)
+		if err != nil {
+			return report, fmt.Errorf("find index of %s: %w", util.FormatSectorID(t.sid), err)
+		}
+
+		entry := api.StorageReindexEntry{
+			ID:        t.sid,
+			Upgrade:   t.upgrade,
+			Instances: holders,
+			Indexed:   indexed,
+		}
+
+		switch {
+		case len(holders) > 1:
+			report.Duplicates = append(report.Duplicates, entry)
+
+		case !ok:
+			report.Added = append(report.Added, entry)
+			updates[t] = holders[0]
+
+		case indexed == holders[0], !scanned[indexed]:
+			// up to date, or the files may be in the indexed store which is not scanned this time
+
+		default:
+			report.Fixed = append(report.Fixed, entry)
+			updates[t] = holders[0]
+		}
+	}
+
+	for _, name := range report.Scanned {
+		for _, upgrade := range []bool{false, true} {
+			sids, err := moveTarget{upgrade: upgrade}.typedIndexer(m.indexer).Sectors(ctx, name)
+			if err != nil {
+				return report, fmt.Errorf("list sectors indexed to %s: %w", name, err)
+			}
+
+			for _, sid := range sids {
+				t := moveTarget{sid: sid, upgrade: upgrade}
+				if holdsSealed(found[t]) {
+					continue
+				}
+
+				report.Missing = append(report.Missing, api.StorageReindexEntry{
+					ID:      sid,
+					Upgrade: upgrade,
+					Indexed: name,
+				})
+			}
+		}
+	}
+
+	log.Infow("storage scanned", "scanned", len(report.Scanned), "failed", len(report.ScanErrors), "found", report.Found,
+		"added", len(report.Added), "fixed", len(report.Fixed), "duplicates", len(report.Duplicates),
+		"incomplete", len(report.Incomplete), "missing", len(report.Missing), "dry-run", spec.DryRun)
+
+	if spec.DryRun {
+		return report, nil
+	}
+
+	for t, instance := range updates {
+		if err := t.typedIndexer(m.indexer).Update(ctx, t.sid, instance); err != nil {
+			return report, fmt.Errorf("update index of %s: %w", util.FormatSectorID(t.sid), err)
+		}
+	}
+
+	return report, nil
+}
+
+// scanStores scans the given stores, the names of the stores scanned successfully are appended to scanned,
+// and the failures are recorded into scanErrs, the files found are keyed by sector and then by store.
+func scanStores(ctx context.Context, storeMgr objstore.Manager, names []string, scanned *[]string, scanErrs map[string]string) (map[moveTarget]map[string]*foundFiles, error) {
+	found := map[moveTarget]map[string]*foundFiles{}
+	for _, name := range names {
+		store, err := storeMgr.GetInstance(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get store %s: %w", name, err)
+		}
+
+		files, err := scanStore(ctx, store)
+		if err != nil {
+			log.Warnw("scan store", "store", name, "err", err)
+			scanErrs[name] = err.Error()
+			continue
+		}
+
+		for t, f := range files {
+			if found[t] == nil {
+				found[t] = map[string]*foundFiles{}
+			}
+
+			found[t][name] = f
+		}
+
+		*scanned = append(*scanned, name)
+	}
+
+	return found, nil
+}
+
+// scanStore lists the sector dirs in the store, and returns the files found
+func scanStore(ctx context.Context, store objstore.Store) (map[moveTarget]*foundFiles, error) {
+	found := map[moveTarget]*foundFiles{}
+	for _, upgrade := range []bool{false, true} {
+		cacheDir, sealedDir := string(util.SectorPathTypeCache), string(util.SectorPathTypeSealed)
+		if upgrade {
+			cacheDir, sealedDir = string(util.SectorPathTypeUpdateCache), string(util.SectorPathTypeUpdate)
+		}
+
+		for _, dir := range []string{sealedDir, cacheDir} {
+			names, err := store.List(ctx, dir)
+			if err != nil {
+				if errors.Is(err, objstore.ErrObjectNotFound) {
+					continue
+				}
+
+				return nil, fmt.Errorf("list %s: %w", dir, err)
+			}
+
+			for _, name := range names {
+				sid, ok := util.ParseSectorID(name)
+				if !ok {
+					continue
+				}
+
+				t := moveTarget{sid: sid, upgrade: upgrade}
+				files, ok := found[t]
+				if !ok {
+					files = &foundFiles{}
+					found[t] = files
+				}
+
+				if dir == sealedDir {
+					files.sealed = true
+				} else {
+					files.cache = true
+				}
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// sealedHolders returns the stores holding the sealed (or update) file, in the order of the given names
+func sealedHolders(stores map[string]*foundFiles, names []string) []string {
+	var holders []string
+	for _, name := range names {
+		if files, ok := stores[name]; ok && files.sealed {
+			holders = append(holders, name)
+		}
+	}
+
+	return holders
+}
+
+func holdsSealed(stores map[string]*foundFiles) bool {
+	for _, files := range stores {
+		if files.sealed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortTargets(targets []moveTarget) {
+	sort.Slice(targets, func(i, j int) bool {
+		a, b := targets[i], targets[j]
+		if a.upgrade != b.upgrade {
+			return !a.upgrade
+		}
+
+		if a.sid.Miner != b.sid.Miner {
+			return a.sid.Miner < b.sid.Miner
+		}
+
+		return a.sid.Number < b.sid.Number
+	})
+}
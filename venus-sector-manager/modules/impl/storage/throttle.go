@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// throttledReader limits the average reading speed, by sleeping when it is ahead of the schedule
+type throttledReader struct {
+	ctx   context.Context
+	r     io.Reader
+	limit uint64
+
+	start time.Time
+	read  uint64
+}
+
+func newThrottledReader(ctx context.Context, r io.Reader, limit uint64) io.Reader {
+	if limit == 0 {
+		return r
+	}
+
+	return &throttledReader{
+		ctx:   ctx,
+		r:     r,
+		limit: limit,
+		start: time.Now(),
+	}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	// keep each read within about a second's quota, so that the sleeps stay short
+	if uint64(len(p)) > t.limit {
+		p = p[:t.limit]
+	}
+
+	n, err := t.r.Read(p)
+	t.read += uint64(n)
+
+	expected := time.Duration(float64(t.read) / float64(t.limit) * float64(time.Second))
+	if wait := expected - time.Since(t.start); wait > 0 {
+		select {
+		case <-t.ctx.Done():
+			return n, t.ctx.Err()
+
+		case <-time.After(wait):
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,554 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/util"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+var _ api.UnsealManager = (*Unsealer)(nil)
+
+const (
+	// the oldest finished jobs will be dropped once exceeding this number
+	maxFinishedUnsealJobs = 64
+
+	// a running job will be handed out again if no progress is reported within this period
+	unsealJobTimeout = 30 * time.Minute
+
+	// the piece index is rebuilt on a miss, but not more often than this
+	pieceIndexTTL = time.Minute
+
+	// the piece index is also refreshed in the background, for the lookups not allowed to rebuild it
+	pieceIndexRefreshInterval = 10 * time.Minute
+)
+
+func NewUnsealer(state api.SectorStateManager, indexer api.SectorIndexer, kv kvstore.KVStore) *Unsealer {
+	return &Unsealer{
+		state:   state,
+		indexer: indexer,
+		jobs:    kv,
+	}
+}
+
+// Unsealer locates the pieces in the unsealed files of the sectors, and manages the unseal jobs for the sectors without one.
+// The jobs are persisted, while the piece index is kept in memory and built from the sector states.
+type Unsealer struct {
+	state   api.SectorStateManager
+	indexer api.SectorIndexer
+	jobs    kvstore.KVStore
+
+	// serializes the job modifications
+	mu  sync.Mutex
+	seq uint64
+
+	pieceMu     sync.Mutex
+	pieces      map[cid.Cid]pieceLocation
+	piecesBuilt time.Time
+}
+
+// pieceLocation is the position of a piece in the sector, the same as in the unsealed file
+type pieceLocation struct {
+	sid      abi.SectorID
+	offset   abi.PaddedPieceSize
+	size     abi.PaddedPieceSize
+	dataSize int64
+}
+
+func (u *Unsealer) Locate(ctx context.Context, c cid.Cid, unseal bool) (*api.UnsealedPiece, error) {
+	loc, ok, err := u.locatePiece(ctx, c, unseal)
+	if err != nil {
+		return nil, fmt.Errorf("locate piece: %w", err)
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	piece := &api.UnsealedPiece{
+		Sector:   loc.sid,
+		Offset:   loc.offset,
+		Size:     loc.size,
+		DataSize: loc.dataSize,
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	active, err := u.activeJob(ctx, loc.sid)
+	if err != nil {
+		return nil, err
+	}
+
+	// the unsealed file is being written, it won't be served until the job is done
+	if active == nil {
+		store, found, err := u.findUnsealed(ctx, loc.sid)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			piece.Store = store
+			piece.Path = util.SectorPath(util.SectorPathTypeUnsealed, loc.sid)
+			return piece, nil
+		}
+	}
+
+	if !unseal {
+		piece.Job = active
+		return piece, nil
+	}
+
+	job, err := u.create(ctx, c, loc.sid, active)
+	if err != nil {
+		log.Warnw("unable to unseal the sector", "piece", c.String(), "miner", loc.sid.Miner, "num", loc.sid.Number, "err", err)
+		return piece, nil
+	}
+
+	piece.Job = &job
+	return piece, nil
+}
+
+func (u *Unsealer) Create(ctx context.Context, c cid.Cid) (api.UnsealJob, error) {
+	loc, ok, err := u.locatePiece(ctx, c, true)
+	if err != nil {
+		return api.UnsealJob{}, fmt.Errorf("locate piece: %w", err)
+	}
+
+	if !ok {
+		return api.UnsealJob{}, fmt.Errorf("piece %s not found in any finalized sector", c)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	active, err := u.activeJob(ctx, loc.sid)
+	if err != nil {
+		return api.UnsealJob{}, err
+	}
+
+	return u.create(ctx, c, loc.sid, active)
+}
+
+// create adds the piece into the active job, or starts a new one for the sector
+func (u *Unsealer) create(ctx context.Context, c cid.Cid, sid abi.SectorID, active *api.UnsealJob) (api.UnsealJob, error) {
+	if active != nil {
+		for _, p := range active.Pieces {
+			if p.Equals(c) {
+				return *active, nil
+			}
+		}
+
+		active.Pieces = append(active.Pieces, c)
+		if err := u.saveJob(ctx, *active); err != nil {
+			return api.UnsealJob{}, err
+		}
+
+		return *active, nil
+	}
+
+	st, err := u.state.Load(ctx, sid)
+	if err != nil {
+		return api.UnsealJob{}, fmt.Errorf("load sector state: %w", err)
+	}
+
+	if st.Ticket == nil || st.Pre == nil {
+		return api.UnsealJob{}, fmt.Errorf("ticket or pre-commit info not found")
+	}
+
+	instance, ok, err := u.indexer.Find(ctx, sid)
+	if err != nil {
+		return api.UnsealJob{}, fmt.Errorf("find sealed file: %w", err)
+	}
+
+	if !ok {
+		return api.UnsealJob{}, fmt.Errorf("sealed file not indexed")
+	}
+
+	u.seq++
+	now := time.Now().Unix()
+	job := api.UnsealJob{
+		ID:         fmt.Sprintf("unseal-%d-%d", now, u.seq),
+		Sector:     sid,
+		SectorType: st.SectorType,
+		Ticket:     *st.Ticket,
+		CommD:      st.Pre.CommD,
+		CommR:      st.Pre.CommR,
+		Instance:   instance,
+		Pieces:     []cid.Cid{c},
+		State:      api.UnsealJobPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := u.saveJob(ctx, job); err != nil {
+		return api.UnsealJob{}, err
+	}
+
+	log.Infow("unseal job created", "id", job.ID, "miner", sid.Miner, "num", sid.Number, "instance", instance, "piece", c.String())
+	return job, nil
+}
+
+func (u *Unsealer) Acquire(ctx context.Context, worker string) (*api.UnsealJob, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	jobs, err := u.loadJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range jobs {
+		job := jobs[i]
+		switch job.State {
+		case api.UnsealJobPending:
+
+		case api.UnsealJobRunning:
+			if now.Sub(time.Unix(job.UpdatedAt, 0)) < unsealJobTimeout {
+				continue
+			}
+
+			log.Warnw("unseal job timed out, reassigned", "id", job.ID, "prev", job.Worker, "worker", worker)
+
+		default:
+			continue
+		}
+
+		job.State = api.UnsealJobRunning
+		job.Worker = worker
+		job.Progress = 0
+		job.UpdatedAt = now.Unix()
+		if err := u.saveJob(ctx, job); err != nil {
+			return nil, err
+		}
+
+		return &job, nil
+	}
+
+	return nil, nil
+}
+
+func (u *Unsealer) Report(ctx context.Context, id string, progress api.UnsealProgress) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var job api.UnsealJob
+	if err := u.jobs.View(ctx, kvstore.Key(id), func(b []byte) error {
+		return json.Unmarshal(b, &job)
+	}); err != nil {
+		if errors.Is(err, kvstore.ErrKeyNotFound) {
+			return fmt.Errorf("unseal job %s not found", id)
+		}
+
+		return fmt.Errorf("load unseal job %s: %w", id, err)
+	}
+
+	if job.State != api.UnsealJobRunning || job.Worker != progress.Worker {
+		return fmt.Errorf("unseal job %s is not assigned to %s", id, progress.Worker)
+	}
+
+	job.UpdatedAt = time.Now().Unix()
+	job.Progress = progress.Progress
+	if job.Progress > 100 {
+		job.Progress = 100
+	}
+
+	switch {
+	case progress.Error != "":
+		job.State = api.UnsealJobFailed
+		job.Error = progress.Error
+
+	case progress.Done:
+		job.State = api.UnsealJobDone
+		job.Progress = 100
+		if err := u.checkUnsealed(ctx, job); err != nil {
+			job.State = api.UnsealJobFailed
+			job.Error = err.Error()
+		}
+	}
+
+	if err := u.saveJob(ctx, job); err != nil {
+		return err
+	}
+
+	if job.State == api.UnsealJobRunning {
+		return nil
+	}
+
+	log.Infow("unseal job finished", "id", job.ID, "state", job.State, "worker", job.Worker, "err", job.Error)
+	return u.prune(ctx)
+}
+
+func (u *Unsealer) List(ctx context.Context) ([]api.UnsealJob, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.loadJobs(ctx)
+}
+
+// checkUnsealed makes sure that the reported unsealed file does exist
+func (u *Unsealer) checkUnsealed(ctx context.Context, job api.UnsealJob) error {
+	store, err := u.indexer.StoreMgr().GetInstance(ctx, job.Instance)
+	if err != nil {
+		return fmt.Errorf("get store %s: %w", job.Instance, err)
+	}
+
+	if _, err := store.Stat(ctx, util.SectorPath(util.SectorPathTypeUnsealed, job.Sector)); err != nil {
+		return fmt.Errorf("stat unsealed file in %s: %w", job.Instance, err)
+	}
+
+	return nil
+}
+
+// findUnsealed looks for the unsealed file in the indexed instance first, and then in the others
+func (u *Unsealer) findUnsealed(ctx context.Context, sid abi.SectorID) (objstore.Store, bool, error) {
+	indexed, ok, err := u.indexer.Find(ctx, sid)
+	if err != nil {
+		return nil, false, fmt.Errorf("find index: %w", err)
+	}
+
+	storeMgr := u.indexer.StoreMgr()
+	instances := storeMgr.Instances(ctx)
+	if ok {
+		candidates := make([]string, 0, len(instances))
+		candidates = append(candidates, indexed)
+		for _, ins := range instances {
+			if ins != indexed {
+				candidates = append(candidates, ins)
+			}
+		}
+
+		instances = candidates
+	}
+
+	path := util.SectorPath(util.SectorPathTypeUnsealed, sid)
+	for _, ins := range instances {
+		store, err := storeMgr.GetInstance(ctx, ins)
+		if err != nil {
+			log.Warnw("get store", "instance", ins, "err", err)
+			continue
+		}
+
+		if _, err := store.Stat(ctx, path); err != nil {
+			if !errors.Is(err, objstore.ErrObjectNotFound) {
+				log.Warnw("stat unsealed file", "instance", ins, "path", path, "err", err)
+			}
+
+			continue
+		}
+
+		return store, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// Run refreshes the piece index periodically
+func (u *Unsealer) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-timer.C:
+		}
+
+		if err := u.refreshPieceIndex(ctx); err != nil {
+			log.Warnw("refresh piece index", "err", err)
+		}
+
+		timer.Reset(pieceIndexRefreshInterval)
+	}
+}
+
+func (u *Unsealer) refreshPieceIndex(ctx context.Context) error {
+	pieces, err := u.buildPieceIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	u.pieceMu.Lock()
+	u.pieces = pieces
+	u.piecesBuilt = time.Now()
+	u.pieceMu.Unlock()
+
+	return nil
+}
+
+// locatePiece looks up the piece index, which is rebuilt on a miss if it is out of date and rebuild is set
+func (u *Unsealer) locatePiece(ctx context.Context, c cid.Cid, rebuild bool) (pieceLocation, bool, error) {
+	u.pieceMu.Lock()
+	defer u.pieceMu.Unlock()
+
+	if loc, ok := u.pieces[c]; ok {
+		return loc, true, nil
+	}
+
+	if !rebuild || (u.pieces != nil && time.Since(u.piecesBuilt) < pieceIndexTTL) {
+		return pieceLocation{}, false, nil
+	}
+
+	pieces, err := u.buildPieceIndex(ctx)
+	if err != nil {
+		return pieceLocation{}, false, err
+	}
+
+	u.pieces = pieces
+	u.piecesBuilt = time.Now()
+
+	loc, ok := u.pieces[c]
+	return loc, ok, nil
+}
+
+// buildPieceIndex collects the pieces of the finalized sectors.
+// The pieces are aligned to their own sizes in the sector, in the same way as they are added by the workers.
+// The snapup sectors are not included, since their data lives in the update files.
+func (u *Unsealer) buildPieceIndex(ctx context.Context) (map[cid.Cid]pieceLocation, error) {
+	pieces := map[cid.Cid]pieceLocation{}
+	err := u.state.ForEach(ctx, api.WorkerOffline, func(st api.SectorState) error {
+		if st.AbortReason != "" || bool(st.Upgraded) || !(bool(st.Finalized) || bool(st.Imported)) {
+			return nil
+		}
+
+		var cur abi.PaddedPieceSize
+		for _, deal := range st.Deals {
+			size := deal.Piece.Size
+			if size == 0 {
+				continue
+			}
+
+			offset := (cur + size - 1) / size * size
+			cur = offset + size
+
+			if !deal.Piece.Cid.Defined() {
+				continue
+			}
+
+			dataSize := int64(size.Unpadded())
+			if deal.PayloadSize > 0 && int64(deal.PayloadSize) < dataSize {
+				dataSize = int64(deal.PayloadSize)
+			}
+
+			pieces[deal.Piece.Cid] = pieceLocation{
+				sid:      st.ID,
+				offset:   offset,
+				size:     size,
+				dataSize: dataSize,
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("scan offline sectors: %w", err)
+	}
+
+	return pieces, nil
+}
+
+func (u *Unsealer) activeJob(ctx context.Context, sid abi.SectorID) (*api.UnsealJob, error) {
+	jobs, err := u.loadJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range jobs {
+		if jobs[i].Sector != sid {
+			continue
+		}
+
+		if jobs[i].State == api.UnsealJobPending || jobs[i].State == api.UnsealJobRunning {
+			return &jobs[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// loadJobs returns all the jobs, the oldest first
+func (u *Unsealer) loadJobs(ctx context.Context) ([]api.UnsealJob, error) {
+	iter, err := u.jobs.Scan(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer iter.Close()
+
+	var jobs []api.UnsealJob
+	for iter.Next() {
+		var job api.UnsealJob
+		if err := iter.View(ctx, func(b []byte) error {
+			return json.Unmarshal(b, &job)
+		}); err != nil {
+			return nil, fmt.Errorf("load unseal job %s: %w", string(iter.Key()), err)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt < jobs[j].CreatedAt
+	})
+
+	return jobs, nil
+}
+
+func (u *Unsealer) saveJob(ctx context.Context, job api.UnsealJob) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshal unseal job: %w", err)
+	}
+
+	if err := u.jobs.Put(ctx, kvstore.Key(job.ID), b); err != nil {
+		return fmt.Errorf("save unseal job %s: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+// prune drops the oldest finished jobs
+func (u *Unsealer) prune(ctx context.Context) error {
+	jobs, err := u.loadJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var finished []api.UnsealJob
+	for _, job := range jobs {
+		if job.State == api.UnsealJobDone || job.State == api.UnsealJobFailed {
+			finished = append(finished, job)
+		}
+	}
+
+	if len(finished) <= maxFinishedUnsealJobs {
+		return nil
+	}
+
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finished[i].UpdatedAt < finished[j].UpdatedAt
+	})
+
+	for _, job := range finished[:len(finished)-maxFinishedUnsealJobs] {
+		if err := u.jobs.Del(ctx, kvstore.Key(job.ID)); err != nil {
+			return fmt.Errorf("delete unseal job %s: %w", job.ID, err)
+		}
+	}
+
+	return nil
+}
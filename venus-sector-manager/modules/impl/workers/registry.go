@@ -0,0 +1,251 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/kvstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
+)
+
+var log = logging.New("workers")
+
+var _ api.WorkerRegistry = (*Registry)(nil)
+
+const minCheckInterval = 5 * time.Second
+
+func NewRegistry(ctx context.Context, store kvstore.KVStore, scfg *modules.SafeConfig, state api.SectorStateManager) (*Registry, error) {
+	reg := &Registry{
+		store:   store,
+		scfg:    scfg,
+		state:   state,
+		workers: map[string]*api.WorkerStatus{},
+	}
+
+	if err := reg.loadAll(ctx); err != nil {
+		return nil, fmt.Errorf("load registered workers: %w", err)
+	}
+
+	return reg, nil
+}
+
+// Registry keeps the info & latest heartbeats of the workers, persisted in the kv store.
+// Only the server side is implemented for now, venus-worker does not call RegisterWorker / WorkerHeartbeat yet.
+type Registry struct {
+	store kvstore.KVStore
+	scfg  *modules.SafeConfig
+	state api.SectorStateManager
+
+	mu      sync.Mutex
+	workers map[string]*api.WorkerStatus
+}
+
+func (r *Registry) Register(ctx context.Context, info api.WorkerInfo) error {
+	if info.Name == "" {
+		return fmt.Errorf("worker name is required")
+	}
+
+	if info.Instance == "" {
+		info.Instance = info.Name
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the in-flight sectors are matched by the instance, which should not be claimed by two workers
+	for name, other := range r.workers {
+		if name != info.Name && workerInstance(other) == info.Instance {
+			return fmt.Errorf("instance %s already registered by worker %s", info.Instance, name)
+		}
+	}
+
+	now := time.Now().Unix()
+	ws, ok := r.workers[info.Name]
+	if !ok {
+		ws = &api.WorkerStatus{}
+		r.workers[info.Name] = ws
+	}
+
+	ws.Info = info
+	ws.RegisteredAt = now
+	ws.LastHeartbeat = now
+	ws.Offline = false
+
+	log.Infow("worker registered", "name", info.Name, "instance", info.Instance, "version", info.Version, "dest", info.Dest)
+	return r.save(ctx, ws)
+}
+
+// Heartbeat from an unknown worker will be accepted, with only the name recorded
+func (r *Registry) Heartbeat(ctx context.Context, name string, res api.WorkerResources) error {
+	if name == "" {
+		return fmt.Errorf("worker name is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ws, ok := r.workers[name]
+	if !ok {
+		log.Warnw("heartbeat from unregistered worker", "name", name)
+		ws = &api.WorkerStatus{
+			Info: api.WorkerInfo{
+				Name: name,
+			},
+		}
+		r.workers[name] = ws
+	}
+
+	if ws.Offline {
+		log.Infow("worker back online", "name", name)
+	}
+
+	ws.Resources = res
+	ws.LastHeartbeat = time.Now().Unix()
+	ws.Offline = false
+
+	return r.save(ctx, ws)
+}
+
+func (r *Registry) List(ctx context.Context) ([]api.WorkerStatus, error) {
+	sectors := map[string][]abi.SectorID{}
+	err := r.state.ForEach(ctx, api.WorkerOnline, func(st api.SectorState) error {
+		if st.LatestState != nil && st.LatestState.Worker.Instance != "" {
+			name := st.LatestState.Worker.Instance
+			sectors[name] = append(sectors[name], st.ID)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("scan online sectors: %w", err)
+	}
+
+	timeout := r.offlineTimeout()
+	now := time.Now()
+
+	r.mu.Lock()
+	res := make([]api.WorkerStatus, 0, len(r.workers))
+	for _, ws := range r.workers {
+		st := *ws
+		st.Offline = isOffline(ws, now, timeout)
+		st.Sectors = sectors[workerInstance(ws)]
+		res = append(res, st)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Info.Name < res[j].Info.Name
+	})
+
+	return res, nil
+}
+
+// Run checks the heartbeats periodically, until the ctx is done.
+// The interval is derived from the offline timeout, so that an offline worker is detected in time.
+func (r *Registry) Run(ctx context.Context) {
+	for {
+		interval := r.offlineTimeout() / 3
+		if interval < minCheckInterval {
+			interval = minCheckInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		r.check()
+	}
+}
+
+func (r *Registry) check() {
+	timeout := r.offlineTimeout()
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	online, offline := 0, 0
+	for name, ws := range r.workers {
+		if !isOffline(ws, now, timeout) {
+			online++
+			continue
+		}
+
+		offline++
+		if !ws.Offline {
+			ws.Offline = true
+			log.Warnw("worker missed heartbeats, marked as offline", "name", name, "last", time.Unix(ws.LastHeartbeat, 0).Format(time.RFC3339))
+		}
+	}
+
+	metrics.Workers.WithLabelValues("online").Set(float64(online))
+	metrics.Workers.WithLabelValues("offline").Set(float64(offline))
+}
+
+func (r *Registry) offlineTimeout() time.Duration {
+	r.scfg.Lock()
+	defer r.scfg.Unlock()
+
+	return time.Duration(r.scfg.Common.Workers.OfflineTimeout)
+}
+
+// workerInstance returns the instance name used in the reported states, the records saved before it was added have only the name
+func workerInstance(ws *api.WorkerStatus) string {
+	if ws.Info.Instance != "" {
+		return ws.Info.Instance
+	}
+
+	return ws.Info.Name
+}
+
+func isOffline(ws *api.WorkerStatus, now time.Time, timeout time.Duration) bool {
+	return now.Sub(time.Unix(ws.LastHeartbeat, 0)) > timeout
+}
+
+// save should be called with mu locked
+func (r *Registry) save(ctx context.Context, ws *api.WorkerStatus) error {
+	b, err := json.Marshal(ws)
+	if err != nil {
+		return fmt.Errorf("marshal worker status: %w", err)
+	}
+
+	if err := r.store.Put(ctx, kvstore.Key(ws.Info.Name), b); err != nil {
+		return fmt.Errorf("save worker status: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Registry) loadAll(ctx context.Context) error {
+	iter, err := r.store.Scan(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer iter.Close()
+
+	for iter.Next() {
+		var ws api.WorkerStatus
+		if err := iter.View(ctx, func(data []byte) error {
+			return json.Unmarshal(data, &ws)
+		}); err != nil {
+			return fmt.Errorf("load worker of key %s: %w", string(iter.Key()), err)
+		}
+
+		r.workers[ws.Info.Name] = &ws
+	}
+
+	return nil
+}
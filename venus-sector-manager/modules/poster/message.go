@@ -6,12 +6,14 @@ import (
 
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/cbor"
+	"github.com/filecoin-project/go-state-types/exitcode"
 
 	"github.com/filecoin-project/venus/venus-shared/actors"
 	"github.com/filecoin-project/venus/venus-shared/types"
 	mtypes "github.com/filecoin-project/venus/venus-shared/types/messager"
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
 )
 
 type msgOrErr struct {
@@ -55,6 +57,7 @@ func (s *scheduler) publishMessage(ctx context.Context, method abi.MethodNum, pa
 		mid = fmt.Sprintf("%s-%v-%v", msg.Cid().String(), di.index, di.open)
 	}
 	uid, err := s.msg.PushMessageWithId(ctx, mid, &msg, &spec)
+	metrics.MessagePushes.WithLabelValues(method.String(), metrics.Result(err)).Inc()
 	if err != nil {
 		return "", nil, fmt.Errorf("push msg with id %s: %w", mid, err)
 	}
@@ -78,5 +81,18 @@ func (s *scheduler) publishMessage(ctx context.Context, method abi.MethodNum, pa
 }
 
 func (s *scheduler) waitMessage(ctx context.Context, mid string, confidence uint64) (*mtypes.Message, error) {
-	return s.msg.WaitMessage(ctx, mid, confidence)
+	msg, err := s.msg.WaitMessage(ctx, mid, confidence)
+	if err != nil {
+		return nil, err
+	}
+
+	result := "landed"
+	if msg.Receipt == nil {
+		result = "receipt_not_found"
+	} else if msg.Receipt.ExitCode != exitcode.Ok {
+		result = "exit_code_error"
+	}
+
+	metrics.MessageLandings.WithLabelValues(msg.Method.String(), result).Inc()
+	return msg, nil
 }
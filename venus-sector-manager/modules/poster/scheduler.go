@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/filecoin-project/go-address"
@@ -11,7 +12,6 @@ import (
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/dline"
 	"github.com/filecoin-project/go-state-types/network"
-	"github.com/filecoin-project/specs-storage/storage"
 	"github.com/hashicorp/go-multierror"
 	"golang.org/x/xerrors"
 
@@ -27,6 +27,7 @@ import (
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/chain"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/messager"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
 )
 
 func newScheduler(
@@ -200,8 +201,12 @@ func (s *scheduler) runPost(ctx context.Context, di dline.Info, ts *types.TipSet
 		return nil, err
 	}
 
+	minerLabel := strconv.FormatUint(uint64(s.actor.ID), 10)
+	deadlineLabel := strconv.FormatUint(di.Index, 10)
+
 	// Generate proofs in batches
 	posts := make([]miner.SubmitWindowedPoStParams, 0, len(partitionBatches))
+	skippedTotal := uint64(0)
 	for batchIdx, batch := range partitionBatches {
 		batchPartitionStartIdx := 0
 		for _, batch := range partitionBatches[:batchIdx] {
@@ -292,6 +297,7 @@ func (s *scheduler) runPost(ctx context.Context, di dline.Info, ts *types.TipSet
 
 			postOut, ps, err := s.prover.GenerateWindowPoSt(ctx, s.actor.ID, privSectors, append(abi.PoStRandomness{}, rand.Rand...))
 			elapsed := time.Since(tsStart)
+			metrics.WdPoStDuration.WithLabelValues(minerLabel, deadlineLabel, metrics.Result(err)).Observe(elapsed.Seconds())
 
 			s.log.Infow("computing window post", "batch", batchIdx, "elapsed", elapsed)
 
@@ -373,6 +379,8 @@ func (s *scheduler) runPost(ctx context.Context, di dline.Info, ts *types.TipSet
 			}
 		}
 
+		skippedTotal += skipCount
+
 		// Nothing to prove for this batch, try the next batch
 		if !somethingToProve {
 			continue
@@ -381,6 +389,7 @@ func (s *scheduler) runPost(ctx context.Context, di dline.Info, ts *types.TipSet
 		posts = append(posts, params)
 	}
 
+	metrics.WdPoStSkipped.WithLabelValues(minerLabel, deadlineLabel).Set(float64(skippedTotal))
 	return posts, nil
 }
 
@@ -535,19 +544,18 @@ func (s *scheduler) checkSectors(ctx context.Context, check bitfield.BitField, t
 	}
 
 	sectors := make(map[abi.SectorNumber]struct{})
-	var tocheck []storage.SectorRef
+	var tocheck []builtin.ExtendedSectorInfo
 	for _, info := range sectorInfos {
 		sectors[info.SectorNumber] = struct{}{}
-		tocheck = append(tocheck, storage.SectorRef{
-			ProofType: info.SealProof,
-			ID: abi.SectorID{
-				Miner:  s.actor.ID,
-				Number: info.SectorNumber,
-			},
+		tocheck = append(tocheck, builtin.ExtendedSectorInfo{
+			SealProof:    info.SealProof,
+			SectorNumber: info.SectorNumber,
+			SealedCID:    info.SealedCID,
+			SectorKey:    info.SectorKeyCID,
 		})
 	}
 
-	bad, err := s.sectorTracker.Provable(ctx, s.proofType, tocheck, mcfg.PoSt.StrictCheck)
+	bad, err := s.sectorTracker.Provable(ctx, s.actor.ID, tocheck, mcfg.PoSt.StrictCheck)
 	if err != nil {
 		return bitfield.BitField{}, fmt.Errorf("checking provable sectors: %w", err)
 	}
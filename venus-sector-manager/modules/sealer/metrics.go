@@ -0,0 +1,257 @@
+package sealer
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
+)
+
+var _ api.SealerAPI = (*metricedSealer)(nil)
+
+// Metriced wraps the given SealerAPI, recording the count & latency of the calls to each method
+func Metriced(inner api.SealerAPI) api.SealerAPI {
+	return &metricedSealer{inner: inner}
+}
+
+type metricedSealer struct {
+	inner api.SealerAPI
+}
+
+func observeRPC(method string, start time.Time, err *error) {
+	metrics.RPCCalls.WithLabelValues(method, metrics.Result(*err)).Inc()
+	metrics.RPCDuration.WithLabelValues(method).Observe(metrics.SinceInSeconds(start))
+}
+
+func (m *metricedSealer) AllocateSector(ctx context.Context, spec api.AllocateSectorSpec) (res *api.AllocatedSector, err error) {
+	defer observeRPC("AllocateSector", time.Now(), &err)
+	return m.inner.AllocateSector(ctx, spec)
+}
+
+func (m *metricedSealer) AcquireDeals(ctx context.Context, sid abi.SectorID, spec api.AcquireDealsSpec) (res api.Deals, err error) {
+	defer observeRPC("AcquireDeals", time.Now(), &err)
+	return m.inner.AcquireDeals(ctx, sid, spec)
+}
+
+func (m *metricedSealer) AssignTicket(ctx context.Context, sid abi.SectorID) (res api.Ticket, err error) {
+	defer observeRPC("AssignTicket", time.Now(), &err)
+	return m.inner.AssignTicket(ctx, sid)
+}
+
+func (m *metricedSealer) SubmitPreCommit(ctx context.Context, sector api.AllocatedSector, info api.PreCommitOnChainInfo, hardReset bool) (res api.SubmitPreCommitResp, err error) {
+	defer observeRPC("SubmitPreCommit", time.Now(), &err)
+	return m.inner.SubmitPreCommit(ctx, sector, info, hardReset)
+}
+
+func (m *metricedSealer) PollPreCommitState(ctx context.Context, sid abi.SectorID) (res api.PollPreCommitStateResp, err error) {
+	defer observeRPC("PollPreCommitState", time.Now(), &err)
+	return m.inner.PollPreCommitState(ctx, sid)
+}
+
+func (m *metricedSealer) AllocatePersistStore(ctx context.Context, sid abi.SectorID) (res *api.PersistStoreAllocation, err error) {
+	defer observeRPC("AllocatePersistStore", time.Now(), &err)
+	return m.inner.AllocatePersistStore(ctx, sid)
+}
+
+func (m *metricedSealer) SubmitPersisted(ctx context.Context, sid abi.SectorID, instance string) (res bool, err error) {
+	defer observeRPC("SubmitPersisted", time.Now(), &err)
+	return m.inner.SubmitPersisted(ctx, sid, instance)
+}
+
+func (m *metricedSealer) WaitSeed(ctx context.Context, sid abi.SectorID) (res api.WaitSeedResp, err error) {
+	defer observeRPC("WaitSeed", time.Now(), &err)
+	return m.inner.WaitSeed(ctx, sid)
+}
+
+func (m *metricedSealer) SubmitProof(ctx context.Context, sid abi.SectorID, info api.ProofOnChainInfo, hardReset bool) (res api.SubmitProofResp, err error) {
+	defer observeRPC("SubmitProof", time.Now(), &err)
+	return m.inner.SubmitProof(ctx, sid, info, hardReset)
+}
+
+func (m *metricedSealer) PollProofState(ctx context.Context, sid abi.SectorID) (res api.PollProofStateResp, err error) {
+	defer observeRPC("PollProofState", time.Now(), &err)
+	return m.inner.PollProofState(ctx, sid)
+}
+
+func (m *metricedSealer) ListSectors(ctx context.Context, ws api.SectorWorkerState, query api.SectorListQuery) (res api.SectorListPage, err error) {
+	defer observeRPC("ListSectors", time.Now(), &err)
+	return m.inner.ListSectors(ctx, ws, query)
+}
+
+func (m *metricedSealer) ReportState(ctx context.Context, sid abi.SectorID, req api.ReportStateReq) (res api.Meta, err error) {
+	defer observeRPC("ReportState", time.Now(), &err)
+	return m.inner.ReportState(ctx, sid, req)
+}
+
+func (m *metricedSealer) ReportFinalized(ctx context.Context, sid abi.SectorID) (res api.Meta, err error) {
+	defer observeRPC("ReportFinalized", time.Now(), &err)
+	return m.inner.ReportFinalized(ctx, sid)
+}
+
+func (m *metricedSealer) ReportAborted(ctx context.Context, sid abi.SectorID, reason string) (res api.Meta, err error) {
+	defer observeRPC("ReportAborted", time.Now(), &err)
+	return m.inner.ReportAborted(ctx, sid, reason)
+}
+
+func (m *metricedSealer) CheckProvable(ctx context.Context, mid abi.ActorID, sectors []builtin.ExtendedSectorInfo, strict bool) (res map[abi.SectorNumber]string, err error) {
+	defer observeRPC("CheckProvable", time.Now(), &err)
+	return m.inner.CheckProvable(ctx, mid, sectors, strict)
+}
+
+func (m *metricedSealer) SimulateWdPoSt(ctx context.Context, maddr address.Address, sis []builtin.ExtendedSectorInfo, rand abi.PoStRandomness) (err error) {
+	defer observeRPC("SimulateWdPoSt", time.Now(), &err)
+	return m.inner.SimulateWdPoSt(ctx, maddr, sis, rand)
+}
+
+func (m *metricedSealer) AllocateSnapUpSector(ctx context.Context, spec api.AllocateSnapUpSpec) (res *api.AllocatedSnapUpSector, err error) {
+	defer observeRPC("AllocateSnapUpSector", time.Now(), &err)
+	return m.inner.AllocateSnapUpSector(ctx, spec)
+}
+
+func (m *metricedSealer) SubmitSnapUpProof(ctx context.Context, sid abi.SectorID, info api.SnapUpOnChainInfo, hardReset bool) (res api.SubmitProofResp, err error) {
+	defer observeRPC("SubmitSnapUpProof", time.Now(), &err)
+	return m.inner.SubmitSnapUpProof(ctx, sid, info, hardReset)
+}
+
+func (m *metricedSealer) PollSnapUpProofState(ctx context.Context, sid abi.SectorID) (res api.PollProofStateResp, err error) {
+	defer observeRPC("PollSnapUpProofState", time.Now(), &err)
+	return m.inner.PollSnapUpProofState(ctx, sid)
+}
+
+func (m *metricedSealer) ExtendSectors(ctx context.Context, mid abi.ActorID, spec api.ExtendSectorsSpec) (res []api.SectorExtensionMessage, err error) {
+	defer observeRPC("ExtendSectors", time.Now(), &err)
+	return m.inner.ExtendSectors(ctx, mid, spec)
+}
+
+func (m *metricedSealer) TerminateSectors(ctx context.Context, mid abi.ActorID, spec api.TerminateSectorsSpec) (res []api.SectorTerminationMessage, err error) {
+	defer observeRPC("TerminateSectors", time.Now(), &err)
+	return m.inner.TerminateSectors(ctx, mid, spec)
+}
+
+func (m *metricedSealer) ImportSectors(ctx context.Context, sectors []api.SectorImportInfo, override bool) (res []api.SectorImportResult, err error) {
+	defer observeRPC("ImportSectors", time.Now(), &err)
+	return m.inner.ImportSectors(ctx, sectors, override)
+}
+
+func (m *metricedSealer) InspectSectorNumber(ctx context.Context, mid abi.ActorID, refresh bool) (res api.SectorNumberInfo, err error) {
+	defer observeRPC("InspectSectorNumber", time.Now(), &err)
+	return m.inner.InspectSectorNumber(ctx, mid, refresh)
+}
+
+func (m *metricedSealer) ResetSectorNumber(ctx context.Context, mid abi.ActorID, num uint64) (res api.Meta, err error) {
+	defer observeRPC("ResetSectorNumber", time.Now(), &err)
+	return m.inner.ResetSectorNumber(ctx, mid, num)
+}
+
+func (m *metricedSealer) SectorEvents(ctx context.Context, filter api.SectorEventFilter) (res <-chan api.SectorEvent, err error) {
+	defer observeRPC("SectorEvents", time.Now(), &err)
+	return m.inner.SectorEvents(ctx, filter)
+}
+
+func (m *metricedSealer) SectorHistory(ctx context.Context, sid abi.SectorID) (res []api.SectorStateRecord, err error) {
+	defer observeRPC("SectorHistory", time.Now(), &err)
+	return m.inner.SectorHistory(ctx, sid)
+}
+
+func (m *metricedSealer) RestoreSector(ctx context.Context, sid abi.SectorID) (res api.Meta, err error) {
+	defer observeRPC("RestoreSector", time.Now(), &err)
+	return m.inner.RestoreSector(ctx, sid)
+}
+
+func (m *metricedSealer) RegisterWorker(ctx context.Context, info api.WorkerInfo) (res api.Meta, err error) {
+	defer observeRPC("RegisterWorker", time.Now(), &err)
+	return m.inner.RegisterWorker(ctx, info)
+}
+
+func (m *metricedSealer) WorkerHeartbeat(ctx context.Context, name string, resources api.WorkerResources) (res api.Meta, err error) {
+	defer observeRPC("WorkerHeartbeat", time.Now(), &err)
+	return m.inner.WorkerHeartbeat(ctx, name, resources)
+}
+
+func (m *metricedSealer) ListWorkers(ctx context.Context) (res []api.WorkerStatus, err error) {
+	defer observeRPC("ListWorkers", time.Now(), &err)
+	return m.inner.ListWorkers(ctx)
+}
+
+func (m *metricedSealer) ListStuckSectors(ctx context.Context) (res []api.StuckSector, err error) {
+	defer observeRPC("ListStuckSectors", time.Now(), &err)
+	return m.inner.ListStuckSectors(ctx)
+}
+
+func (m *metricedSealer) CheckDeals(ctx context.Context) (res api.DealReconcileReport, err error) {
+	defer observeRPC("CheckDeals", time.Now(), &err)
+	return m.inner.CheckDeals(ctx)
+}
+
+func (m *metricedSealer) ListPersistStores(ctx context.Context) (res []api.PersistStoreStatus, err error) {
+	defer observeRPC("ListPersistStores", time.Now(), &err)
+	return m.inner.ListPersistStores(ctx)
+}
+
+func (m *metricedSealer) MoveStorage(ctx context.Context, spec api.StorageMoveSpec) (res api.StorageMoveJob, err error) {
+	defer observeRPC("MoveStorage", time.Now(), &err)
+	return m.inner.MoveStorage(ctx, spec)
+}
+
+func (m *metricedSealer) ListStorageMoveJobs(ctx context.Context) (res []api.StorageMoveJob, err error) {
+	defer observeRPC("ListStorageMoveJobs", time.Now(), &err)
+	return m.inner.ListStorageMoveJobs(ctx)
+}
+
+func (m *metricedSealer) CancelStorageMove(ctx context.Context, id string) (res api.Meta, err error) {
+	defer observeRPC("CancelStorageMove", time.Now(), &err)
+	return m.inner.CancelStorageMove(ctx, id)
+}
+
+func (m *metricedSealer) ListStorageSectors(ctx context.Context, instance string) (res api.StorageSectors, err error) {
+	defer observeRPC("ListStorageSectors", time.Now(), &err)
+	return m.inner.ListStorageSectors(ctx, instance)
+}
+
+func (m *metricedSealer) ReindexStorage(ctx context.Context, spec api.StorageReindexSpec) (res api.StorageReindexReport, err error) {
+	defer observeRPC("ReindexStorage", time.Now(), &err)
+	return m.inner.ReindexStorage(ctx, spec)
+}
+
+func (m *metricedSealer) AuditStorage(ctx context.Context, refresh bool) (res api.StorageAuditReport, err error) {
+	defer observeRPC("AuditStorage", time.Now(), &err)
+	return m.inner.AuditStorage(ctx, refresh)
+}
+
+func (m *metricedSealer) CollectStorage(ctx context.Context, dryRun bool) (res api.StorageGCReport, err error) {
+	defer observeRPC("CollectStorage", time.Now(), &err)
+	return m.inner.CollectStorage(ctx, dryRun)
+}
+
+func (m *metricedSealer) ListStorageGCRecords(ctx context.Context, limit int) (res []api.StorageGCRecord, err error) {
+	defer observeRPC("ListStorageGCRecords", time.Now(), &err)
+	return m.inner.ListStorageGCRecords(ctx, limit)
+}
+
+func (m *metricedSealer) UnsealPiece(ctx context.Context, pieceCid cid.Cid) (res api.UnsealJob, err error) {
+	defer observeRPC("UnsealPiece", time.Now(), &err)
+	return m.inner.UnsealPiece(ctx, pieceCid)
+}
+
+func (m *metricedSealer) ListUnsealJobs(ctx context.Context) (res []api.UnsealJob, err error) {
+	defer observeRPC("ListUnsealJobs", time.Now(), &err)
+	return m.inner.ListUnsealJobs(ctx)
+}
+
+func (m *metricedSealer) AcquireUnsealJob(ctx context.Context, worker string) (res *api.UnsealJob, err error) {
+	defer observeRPC("AcquireUnsealJob", time.Now(), &err)
+	return m.inner.AcquireUnsealJob(ctx, worker)
+}
+
+func (m *metricedSealer) ReportUnsealProgress(ctx context.Context, id string, progress api.UnsealProgress) (res api.Meta, err error) {
+	defer observeRPC("ReportUnsealProgress", time.Now(), &err)
+	return m.inner.ReportUnsealProgress(ctx, id, progress)
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-commp-utils/zerocomm"
 	"github.com/filecoin-project/go-state-types/abi"
-	"github.com/filecoin-project/specs-storage/storage"
+	"github.com/ipfs/go-cid"
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules/policy"
@@ -44,6 +44,20 @@ func New(
 	sectorIdxer api.SectorIndexer,
 	sectorfaultTracker api.SectorTracker,
 	prover api.Prover,
+	snapup api.SnapUpSectorManager,
+	extension api.SectorExtensionManager,
+	terminate api.SectorTerminationManager,
+	importer api.SectorImporter,
+	numAlloc api.SectorNumberAllocator,
+	events api.SectorEventHub,
+	workers api.WorkerRegistry,
+	stuck api.StuckSectorDetector,
+	dealReconciler api.DealReconciler,
+	placer api.PersistStorePlacer,
+	mover api.StorageMover,
+	auditor api.StorageAuditor,
+	collector api.StorageCollector,
+	unseal api.UnsealManager,
 ) (*Sealer, error) {
 	return &Sealer{
 		capi:        capi,
@@ -56,6 +70,22 @@ func New(
 
 		sectorTracker: sectorfaultTracker,
 		prover:        prover,
+
+		snapup:    snapup,
+		extension: extension,
+		terminate: terminate,
+		importer:  importer,
+		numAlloc:  numAlloc,
+		events:    events,
+		workers:   workers,
+		stuck:     stuck,
+
+		dealReconciler: dealReconciler,
+		placer:         placer,
+		mover:          mover,
+		auditor:        auditor,
+		collector:      collector,
+		unseal:         unseal,
 	}, nil
 }
 
@@ -70,6 +100,37 @@ type Sealer struct {
 
 	sectorTracker api.SectorTracker
 	prover        api.Prover
+
+	snapup    api.SnapUpSectorManager
+	extension api.SectorExtensionManager
+	terminate api.SectorTerminationManager
+	importer  api.SectorImporter
+	numAlloc  api.SectorNumberAllocator
+	events    api.SectorEventHub
+	workers   api.WorkerRegistry
+	stuck     api.StuckSectorDetector
+
+	dealReconciler api.DealReconciler
+	placer         api.PersistStorePlacer
+	mover          api.StorageMover
+	auditor        api.StorageAuditor
+	collector      api.StorageCollector
+	unseal         api.UnsealManager
+}
+
+func (s *Sealer) publish(ctx context.Context, typ api.SectorEventType, sid abi.SectorID, detail string) {
+	s.publishEvent(ctx, api.SectorEvent{
+		Type:   typ,
+		ID:     sid,
+		Detail: detail,
+	})
+}
+
+// publishEvent never fails the sealing procedure, the events are only for the observers
+func (s *Sealer) publishEvent(ctx context.Context, evt api.SectorEvent) {
+	if err := s.events.Publish(ctx, evt); err != nil {
+		sectorLogger(evt.ID).Warnw("publish sector event", "type", evt.Type, "err", err)
+	}
 }
 
 func (s *Sealer) checkSectorNumber(ctx context.Context, sid abi.SectorID) (bool, error) {
@@ -103,17 +164,21 @@ func (s *Sealer) AllocateSector(ctx context.Context, spec api.AllocateSectorSpec
 
 	allocated, err := s.checkSectorNumber(ctx, sector.ID)
 	if err != nil {
+		s.sector.Release(ctx, sector.ID)
 		return nil, err
 	}
 
 	if allocated {
+		s.sector.Release(ctx, sector.ID)
 		return nil, fmt.Errorf("%w: m-%d-s-%d", ErrSectorAllocated, sector.ID.Miner, sector.ID.Number)
 	}
 
 	if err := s.state.Init(ctx, sector.ID, sector.ProofType); err != nil {
+		s.sector.Release(ctx, sector.ID)
 		return nil, err
 	}
 
+	s.publish(ctx, api.SectorEventAllocated, sector.ID, "")
 	return sector, nil
 }
 
@@ -165,6 +230,7 @@ func (s *Sealer) AcquireDeals(ctx context.Context, sid abi.SectorID, spec api.Ac
 	}
 
 	success = true
+	s.publish(ctx, api.SectorEventDealsAcquired, sid, fmt.Sprintf("%d pieces", len(deals)))
 
 	return deals, nil
 }
@@ -185,6 +251,7 @@ func (s *Sealer) AssignTicket(ctx context.Context, sid abi.SectorID) (api.Ticket
 		return api.Ticket{}, err
 	}
 
+	s.publish(ctx, api.SectorEventTicketAssigned, sid, fmt.Sprintf("epoch %d", ticket.Epoch))
 	return ticket, nil
 }
 
@@ -193,14 +260,60 @@ func (s *Sealer) SubmitPreCommit(ctx context.Context, sector api.AllocatedSector
 	if err != nil {
 		return api.SubmitPreCommitResp{}, err
 	}
-	return s.commit.SubmitPreCommit(ctx, sector.ID, pinfo, hardReset)
+
+	resp, err := s.commit.SubmitPreCommit(ctx, sector.ID, pinfo, hardReset)
+	if err == nil && resp.Res == api.SubmitAccepted {
+		s.publish(ctx, api.SectorEventPreCommitSubmitted, sector.ID, "")
+	}
+
+	return resp, err
 }
 
 func (s *Sealer) PollPreCommitState(ctx context.Context, sid abi.SectorID) (api.PollPreCommitStateResp, error) {
 	return s.commit.PreCommitState(ctx, sid)
 }
 
+// AllocatePersistStore recommends a persist store for the sector, and reserves the space of the sector in it
+func (s *Sealer) AllocatePersistStore(ctx context.Context, sid abi.SectorID) (*api.PersistStoreAllocation, error) {
+	state, err := s.state.Load(ctx, sid)
+	if err != nil {
+		return nil, fmt.Errorf("load sector state: %w", err)
+	}
+
+	ssize, err := state.SectorType.SectorSize()
+	if err != nil {
+		return nil, fmt.Errorf("get sector size: %w", err)
+	}
+
+	alloc, err := s.placer.Allocate(ctx, sid, uint64(ssize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &alloc, nil
+}
+
 func (s *Sealer) SubmitPersisted(ctx context.Context, sid abi.SectorID, instance string) (bool, error) {
+	// check for sealed file existance
+	ok, err := s.checkPersistedFile(ctx, sid, instance, util.SectorPath(util.SectorPathTypeSealed, sid))
+	if err != nil || !ok {
+		return false, err
+	}
+
+	err = s.sectorIdxer.Update(ctx, sid, instance)
+	if err != nil {
+		return false, fmt.Errorf("unable to update sector indexer for sector id %d instance %s %w", sid, instance, err)
+	}
+
+	// the space is taken now, no matter which instance it is in
+	if err := s.placer.Release(ctx, sid); err != nil {
+		sectorLogger(sid).Warnw("release persist reservation", "err", err)
+	}
+
+	return true, nil
+}
+
+func (s *Sealer) checkPersistedFile(ctx context.Context, sid abi.SectorID, instance string, sub string) (bool, error) {
 	ins, err := s.sectorIdxer.StoreMgr().GetInstance(ctx, instance)
 	if err != nil {
 		if errors.Is(err, objstore.ErrObjectStoreInstanceNotFound) {
@@ -210,23 +323,16 @@ func (s *Sealer) SubmitPersisted(ctx context.Context, sid abi.SectorID, instance
 		return false, fmt.Errorf("unable to get instance of sector id %d instance %s %w", sid, instance, err)
 	}
 
-	// check for sealed file existance
-	reader, err := ins.Get(ctx, util.SectorPath(util.SectorPathTypeSealed, sid))
+	reader, err := ins.Get(ctx, sub)
 	if err != nil {
 		if errors.Is(err, objstore.ErrObjectNotFound) {
 			return false, nil
 		}
 
-		return false, fmt.Errorf("failed to check sealed file for sector id %d instance %s %w", sid, instance, err)
+		return false, fmt.Errorf("failed to check file %s for sector id %d instance %s %w", sub, sid, instance, err)
 	}
 
 	reader.Close()
-
-	err = s.sectorIdxer.Update(ctx, sid, instance)
-	if err != nil {
-		return false, fmt.Errorf("unable to update sector indexer for sector id %d instance %s %w", sid, instance, err)
-	}
-
 	return true, nil
 }
 
@@ -275,15 +381,20 @@ func (s *Sealer) WaitSeed(ctx context.Context, sid abi.SectorID) (api.WaitSeedRe
 }
 
 func (s *Sealer) SubmitProof(ctx context.Context, sid abi.SectorID, info api.ProofOnChainInfo, hardReset bool) (api.SubmitProofResp, error) {
-	return s.commit.SubmitProof(ctx, sid, info, hardReset)
+	resp, err := s.commit.SubmitProof(ctx, sid, info, hardReset)
+	if err == nil && resp.Res == api.SubmitAccepted {
+		s.publish(ctx, api.SectorEventProofSubmitted, sid, "")
+	}
+
+	return resp, err
 }
 
 func (s *Sealer) PollProofState(ctx context.Context, sid abi.SectorID) (api.PollProofStateResp, error) {
 	return s.commit.ProofState(ctx, sid)
 }
 
-func (s *Sealer) ListSectors(ctx context.Context, ws api.SectorWorkerState) ([]*api.SectorState, error) {
-	return s.state.All(ctx, ws)
+func (s *Sealer) ListSectors(ctx context.Context, ws api.SectorWorkerState, query api.SectorListQuery) (api.SectorListPage, error) {
+	return s.state.List(ctx, ws, query)
 }
 
 func (s *Sealer) ReportState(ctx context.Context, sid abi.SectorID, req api.ReportStateReq) (api.Meta, error) {
@@ -291,6 +402,16 @@ func (s *Sealer) ReportState(ctx context.Context, sid abi.SectorID, req api.Repo
 		return api.Empty, err
 	}
 
+	if err := s.state.AppendHistory(ctx, sid, req); err != nil {
+		return api.Empty, fmt.Errorf("append state history: %w", err)
+	}
+
+	s.publishEvent(ctx, api.SectorEvent{
+		Type:  api.SectorEventStateReported,
+		ID:    sid,
+		State: &req,
+	})
+
 	return api.Empty, nil
 }
 
@@ -300,6 +421,9 @@ func (s *Sealer) ReportFinalized(ctx context.Context, sid abi.SectorID) (api.Met
 		return api.Empty, err
 	}
 
+	s.sector.Release(ctx, sid)
+
+	s.publish(ctx, api.SectorEventFinalized, sid, "")
 	return api.Empty, nil
 }
 
@@ -314,6 +438,21 @@ func (s *Sealer) ReportAborted(ctx context.Context, sid abi.SectorID, reason str
 			sectorLogger(sid).Debugw("deals released", "count", dealCount)
 		}
 
+		// an aborted snapup leaves the cc sector untouched, so we just roll back the upgrade
+		if st.Upgraded {
+			if err := s.checkUpgradeRollback(ctx, sid); err != nil {
+				return fmt.Errorf("roll back snapup: %w", err)
+			}
+
+			sectorLogger(sid).Warnw("snapup aborted", "reason", reason)
+			st.Deals = nil
+			st.Upgraded = false
+			st.UpgradePublic = nil
+			st.UpgradedInfo = nil
+			st.UpgradeMessageInfo = api.UpgradeMessageInfo{}
+			return nil
+		}
+
 		st.AbortReason = reason
 		return nil
 	})
@@ -322,12 +461,42 @@ func (s *Sealer) ReportAborted(ctx context.Context, sid abi.SectorID, reason str
 		return api.Empty, err
 	}
 
+	s.sector.Release(ctx, sid)
+	if err := s.placer.Release(ctx, sid); err != nil {
+		sectorLogger(sid).Warnw("release persist reservation", "err", err)
+	}
+
+	s.publish(ctx, api.SectorEventAborted, sid, reason)
 	return api.Empty, nil
 }
 
-func (s *Sealer) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, strict bool) (map[abi.SectorNumber]string, error) {
+// checkUpgradeRollback makes sure that the replica update has not landed on chain,
+// otherwise the sealed file of the cc sector is no longer the one to be proved.
+func (s *Sealer) checkUpgradeRollback(ctx context.Context, sid abi.SectorID) error {
+	maddr, err := address.NewIDAddress(uint64(sid.Miner))
+	if err != nil {
+		return err
+	}
+
+	ts, err := s.capi.ChainHead(ctx)
+	if err != nil {
+		return fmt.Errorf("get chain head: %w", err)
+	}
 
-	return s.sectorTracker.Provable(ctx, pp, sectors, strict)
+	onChain, err := s.capi.StateSectorGetInfo(ctx, maddr, sid.Number, ts.Key())
+	if err != nil {
+		return fmt.Errorf("get sector info: %w", err)
+	}
+
+	if onChain != nil && onChain.SectorKeyCID != nil {
+		return fmt.Errorf("replica update has landed on chain with sealed cid %s", onChain.SealedCID)
+	}
+
+	return nil
+}
+
+func (s *Sealer) CheckProvable(ctx context.Context, mid abi.ActorID, sectors []builtin.ExtendedSectorInfo, strict bool) (map[abi.SectorNumber]string, error) {
+	return s.sectorTracker.Provable(ctx, mid, sectors, strict)
 }
 
 func (s *Sealer) SimulateWdPoSt(ctx context.Context, maddr address.Address, sis []builtin.ExtendedSectorInfo, rand abi.PoStRandomness) error {
@@ -359,3 +528,362 @@ func (s *Sealer) SimulateWdPoSt(ctx context.Context, maddr address.Address, sis
 
 	return nil
 }
+
+func (s *Sealer) AllocateSnapUpSector(ctx context.Context, spec api.AllocateSnapUpSpec) (*api.AllocatedSnapUpSector, error) {
+	candidate, err := s.snapup.Allocate(ctx, spec.Sector)
+	if err != nil {
+		return nil, fmt.Errorf("allocate snapup sector: %w", err)
+	}
+
+	if candidate == nil {
+		return nil, nil
+	}
+
+	sid := candidate.Sector.ID
+	slog := sectorLogger(sid).With("stage", "snapup")
+
+	defer func() {
+		if rerr := s.snapup.Release(ctx, candidate); rerr != nil {
+			slog.Errorf("failed to release snapup candidate: %v", rerr)
+		}
+	}()
+
+	deals, err := s.deal.Acquire(ctx, sid, spec.Deals.MaxDeals)
+	if err != nil {
+		return nil, fmt.Errorf("acquire deals: %w", err)
+	}
+
+	success := false
+	defer func() {
+		if !success && len(deals) > 0 {
+			if rerr := s.deal.Release(ctx, sid, deals); rerr != nil {
+				slog.Errorf("failed to release deals %v", rerr)
+			}
+		}
+	}()
+
+	dealCount := 0
+	for di := range deals {
+		dinfo := deals[di]
+		if dinfo.ID != 0 {
+			dealCount++
+			continue
+		}
+
+		expected := zerocomm.ZeroPieceCommitment(dinfo.Piece.Size.Unpadded())
+		if !expected.Equals(dinfo.Piece.Cid) {
+			slog.Errorw("got unexpected non-deal piece", "piece-seq", di, "piece-size", dinfo.Piece.Size, "piece-cid", dinfo.Piece.Cid)
+			return nil, fmt.Errorf("got unexpected non-deal piece")
+		}
+	}
+
+	// no need to upgrade a sector with pledge pieces only
+	if dealCount == 0 {
+		slog.Debug("no deals available for snapup")
+		return nil, nil
+	}
+
+	public := candidate.Public
+	err = s.state.Restore(ctx, sid, func(st *api.SectorState) error {
+		st.Deals = deals
+		st.Upgraded = true
+		st.UpgradePublic = &public
+		st.UpgradedInfo = nil
+		st.UpgradeMessageInfo = api.UpgradeMessageInfo{}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("restore sector for snapup: %w", err)
+	}
+
+	s.sector.Acquire(ctx, sid)
+
+	success = true
+	slog.Infow("sector allocated for snapup", "deals", dealCount)
+	s.publish(ctx, api.SectorEventDealsAcquired, sid, fmt.Sprintf("snapup with %d deals", dealCount))
+
+	return &api.AllocatedSnapUpSector{
+		Sector:  candidate.Sector,
+		Pieces:  deals,
+		Public:  candidate.Public,
+		Private: candidate.Private,
+	}, nil
+}
+
+func (s *Sealer) SubmitSnapUpProof(ctx context.Context, sid abi.SectorID, info api.SnapUpOnChainInfo, hardReset bool) (api.SubmitProofResp, error) {
+	rinfo, err := info.IntoReplicaUpdateInfo()
+	if err != nil {
+		return api.SubmitProofResp{}, err
+	}
+
+	// check for updated file existance
+	ok, err := s.checkPersistedFile(ctx, sid, info.AccessInstance, util.SectorPath(util.SectorPathTypeUpdate, sid))
+	if err != nil {
+		return api.SubmitProofResp{}, err
+	}
+
+	if !ok {
+		desc := fmt.Sprintf("updated file not found in instance %s", info.AccessInstance)
+		return api.SubmitProofResp{Res: api.SubmitRejected, Desc: &desc}, nil
+	}
+
+	resp, err := s.commit.SubmitReplicaUpdate(ctx, sid, rinfo, hardReset)
+	if err != nil || resp.Res != api.SubmitAccepted {
+		return resp, err
+	}
+
+	err = s.sectorIdxer.Upgrade().Update(ctx, sid, info.AccessInstance)
+	if err != nil {
+		return api.SubmitProofResp{}, fmt.Errorf("unable to update upgrade indexer for sector id %d instance %s %w", sid, info.AccessInstance, err)
+	}
+
+	s.publish(ctx, api.SectorEventProofSubmitted, sid, "snapup")
+	return resp, nil
+}
+
+func (s *Sealer) PollSnapUpProofState(ctx context.Context, sid abi.SectorID) (api.PollProofStateResp, error) {
+	return s.commit.ReplicaUpdateState(ctx, sid)
+}
+
+func (s *Sealer) ExtendSectors(ctx context.Context, mid abi.ActorID, spec api.ExtendSectorsSpec) ([]api.SectorExtensionMessage, error) {
+	return s.extension.Extend(ctx, mid, spec)
+}
+
+func (s *Sealer) TerminateSectors(ctx context.Context, mid abi.ActorID, spec api.TerminateSectorsSpec) ([]api.SectorTerminationMessage, error) {
+	return s.terminate.Terminate(ctx, mid, spec)
+}
+
+func (s *Sealer) ImportSectors(ctx context.Context, sectors []api.SectorImportInfo, override bool) ([]api.SectorImportResult, error) {
+	return s.importer.Import(ctx, sectors, override)
+}
+
+func (s *Sealer) InspectSectorNumber(ctx context.Context, mid abi.ActorID, refresh bool) (api.SectorNumberInfo, error) {
+	return s.numAlloc.Inspect(ctx, mid, refresh)
+}
+
+func (s *Sealer) ResetSectorNumber(ctx context.Context, mid abi.ActorID, num uint64) (api.Meta, error) {
+	if err := s.numAlloc.Reset(ctx, mid, num); err != nil {
+		return api.Empty, err
+	}
+
+	return api.Empty, nil
+}
+
+func (s *Sealer) SectorEvents(ctx context.Context, filter api.SectorEventFilter) (<-chan api.SectorEvent, error) {
+	return s.events.Subscribe(ctx, filter)
+}
+
+func (s *Sealer) SectorHistory(ctx context.Context, sid abi.SectorID) ([]api.SectorStateRecord, error) {
+	return s.state.History(ctx, sid)
+}
+
+// RestoreSector moves a finalized or aborted sector back to the online store, so that it could be sealed again
+func (s *Sealer) RestoreSector(ctx context.Context, sid abi.SectorID) (api.Meta, error) {
+	slog := sectorLogger(sid)
+	err := s.state.Restore(ctx, sid, func(st *api.SectorState) error {
+		if st.Imported {
+			return fmt.Errorf("imported sectors can not be restored")
+		}
+
+		if st.TerminateInfo.Terminated {
+			return fmt.Errorf("sector has been terminated")
+		}
+
+		if err := s.checkRestorable(ctx, st); err != nil {
+			return fmt.Errorf("contradicted with the on-chain state: %w", err)
+		}
+
+		// deals are released when the sector is aborted
+		if st.AbortReason != "" && len(st.Deals) > 0 {
+			if err := s.deal.Reacquire(ctx, sid, st.Deals); err != nil {
+				return fmt.Errorf("reacquire deals: %w", err)
+			}
+
+			slog.Debugw("deals reacquired", "count", len(st.Deals))
+		}
+
+		slog.Infow("sector restored", "abort-reason", st.AbortReason)
+		st.AbortReason = ""
+		return nil
+	})
+
+	if err != nil {
+		return api.Empty, err
+	}
+
+	s.sector.Acquire(ctx, sid)
+	s.publish(ctx, api.SectorEventRestored, sid, "")
+	return api.Empty, nil
+}
+
+func (s *Sealer) checkRestorable(ctx context.Context, st *api.SectorState) error {
+	sid := st.ID
+	maddr, err := address.NewIDAddress(uint64(sid.Miner))
+	if err != nil {
+		return err
+	}
+
+	ts, err := s.capi.ChainHead(ctx)
+	if err != nil {
+		return fmt.Errorf("get chain head: %w", err)
+	}
+
+	tsk := ts.Key()
+	onChain, err := s.capi.StateSectorGetInfo(ctx, maddr, sid.Number, tsk)
+	if err != nil {
+		return fmt.Errorf("get sector info: %w", err)
+	}
+
+	if onChain != nil {
+		sealed := onChain.SealedCID
+		if onChain.SectorKeyCID != nil {
+			sealed = *onChain.SectorKeyCID
+		}
+
+		if st.Pre == nil || !st.Pre.CommR.Equals(sealed) {
+			return fmt.Errorf("sector with a different sealed cid %s has been proved", sealed)
+		}
+
+		// the deals are all in the proved sector
+		return nil
+	}
+
+	allocated, err := s.capi.StateMinerSectorAllocated(ctx, maddr, sid.Number, tsk)
+	if err != nil {
+		return fmt.Errorf("check if sector allocated: %w", err)
+	}
+
+	if allocated {
+		pci, err := s.capi.StateSectorPreCommitInfo(ctx, maddr, sid.Number, tsk)
+		if err != nil {
+			return fmt.Errorf("sector number allocated, but the pre-commit info is not available: %w", err)
+		}
+
+		if st.Pre == nil || !st.Pre.CommR.Equals(pci.Info.SealedCID) {
+			return fmt.Errorf("sector with a different sealed cid %s has been pre-committed", pci.Info.SealedCID)
+		}
+	}
+
+	for _, deal := range st.Deals {
+		if deal.ID == 0 {
+			continue
+		}
+
+		mdeal, err := s.capi.StateMarketStorageDeal(ctx, deal.ID, tsk)
+		if err != nil {
+			return fmt.Errorf("get deal %d: %w", deal.ID, err)
+		}
+
+		if mdeal.State.SectorStartEpoch > 0 {
+			return fmt.Errorf("deal %d has been activated in another sector", deal.ID)
+		}
+
+		if mdeal.Proposal.StartEpoch <= ts.Height() {
+			return fmt.Errorf("start epoch of deal %d has passed", deal.ID)
+		}
+	}
+
+	return nil
+}
+
+func (s *Sealer) RegisterWorker(ctx context.Context, info api.WorkerInfo) (api.Meta, error) {
+	if err := s.workers.Register(ctx, info); err != nil {
+		return api.Empty, err
+	}
+
+	return api.Empty, nil
+}
+
+func (s *Sealer) WorkerHeartbeat(ctx context.Context, name string, res api.WorkerResources) (api.Meta, error) {
+	if err := s.workers.Heartbeat(ctx, name, res); err != nil {
+		return api.Empty, err
+	}
+
+	return api.Empty, nil
+}
+
+func (s *Sealer) ListWorkers(ctx context.Context) ([]api.WorkerStatus, error) {
+	return s.workers.List(ctx)
+}
+
+func (s *Sealer) CheckDeals(ctx context.Context) (api.DealReconcileReport, error) {
+	return s.dealReconciler.Check(ctx)
+}
+
+func (s *Sealer) ListPersistStores(ctx context.Context) ([]api.PersistStoreStatus, error) {
+	return s.placer.List(ctx)
+}
+
+func (s *Sealer) MoveStorage(ctx context.Context, spec api.StorageMoveSpec) (api.StorageMoveJob, error) {
+	return s.mover.Start(ctx, spec)
+}
+
+func (s *Sealer) ListStorageMoveJobs(ctx context.Context) ([]api.StorageMoveJob, error) {
+	return s.mover.List(ctx)
+}
+
+func (s *Sealer) CancelStorageMove(ctx context.Context, id string) (api.Meta, error) {
+	if err := s.mover.Cancel(ctx, id); err != nil {
+		return api.Empty, err
+	}
+
+	return api.Empty, nil
+}
+
+func (s *Sealer) ListStorageSectors(ctx context.Context, instance string) (api.StorageSectors, error) {
+	if _, err := s.sectorIdxer.StoreMgr().GetInstance(ctx, instance); err != nil {
+		return api.StorageSectors{}, err
+	}
+
+	sealed, err := s.sectorIdxer.Sectors(ctx, instance)
+	if err != nil {
+		return api.StorageSectors{}, fmt.Errorf("list sealed sectors: %w", err)
+	}
+
+	upgraded, err := s.sectorIdxer.Upgrade().Sectors(ctx, instance)
+	if err != nil {
+		return api.StorageSectors{}, fmt.Errorf("list upgraded sectors: %w", err)
+	}
+
+	return api.StorageSectors{
+		Instance: instance,
+		Sealed:   sealed,
+		Upgraded: upgraded,
+	}, nil
+}
+
+func (s *Sealer) ReindexStorage(ctx context.Context, spec api.StorageReindexSpec) (api.StorageReindexReport, error) {
+	return s.mover.Reindex(ctx, spec)
+}
+
+func (s *Sealer) AuditStorage(ctx context.Context, refresh bool) (api.StorageAuditReport, error) {
+	return s.auditor.Audit(ctx, refresh)
+}
+
+func (s *Sealer) CollectStorage(ctx context.Context, dryRun bool) (api.StorageGCReport, error) {
+	return s.collector.Collect(ctx, dryRun)
+}
+
+func (s *Sealer) ListStorageGCRecords(ctx context.Context, limit int) ([]api.StorageGCRecord, error) {
+	return s.collector.Records(ctx, limit)
+}
+
+func (s *Sealer) UnsealPiece(ctx context.Context, pieceCid cid.Cid) (api.UnsealJob, error) {
+	return s.unseal.Create(ctx, pieceCid)
+}
+
+func (s *Sealer) ListUnsealJobs(ctx context.Context) ([]api.UnsealJob, error) {
+	return s.unseal.List(ctx)
+}
+
+func (s *Sealer) AcquireUnsealJob(ctx context.Context, worker string) (*api.UnsealJob, error) {
+	return s.unseal.Acquire(ctx, worker)
+}
+
+func (s *Sealer) ReportUnsealProgress(ctx context.Context, id string, progress api.UnsealProgress) (api.Meta, error) {
+	if err := s.unseal.Report(ctx, id, progress); err != nil {
+		return api.Empty, err
+	}
+
+	return api.Empty, nil
+}
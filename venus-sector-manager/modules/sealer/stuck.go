@@ -0,0 +1,64 @@
+package sealer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/modules"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
+)
+
+func (s *Sealer) ListStuckSectors(ctx context.Context) ([]api.StuckSector, error) {
+	return s.stuck.Check(ctx)
+}
+
+// RunStuckWatcher reports the stuck sectors periodically, and aborts the ones in the configured phases, until the ctx is done
+func (s *Sealer) RunStuckWatcher(ctx context.Context, scfg *modules.SafeConfig) {
+	for {
+		scfg.Lock()
+		interval := scfg.Common.StuckSectors.CheckInterval.Std()
+		autoAbort := map[string]struct{}{}
+		for _, phase := range scfg.Common.StuckSectors.AutoAbort {
+			autoAbort[phase] = struct{}{}
+		}
+		scfg.Unlock()
+
+		if interval <= 0 {
+			interval = 5 * time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		stuck, err := s.stuck.Check(ctx)
+		if err != nil {
+			log.Warnw("check stuck sectors", "err", err)
+			continue
+		}
+
+		metrics.StuckSectors.Reset()
+		for _, item := range stuck {
+			metrics.StuckSectors.WithLabelValues(strconv.FormatUint(uint64(item.ID.Miner), 10), item.Phase).Inc()
+
+			slog := sectorLogger(item.ID).With("phase", item.Phase, "worker", item.Worker.Instance, "since", time.Unix(item.Since, 0).Format(time.RFC3339))
+			if _, ok := autoAbort[item.Phase]; !ok {
+				slog.Warnw("sector stuck", "timeout", item.Timeout)
+				continue
+			}
+
+			reason := fmt.Sprintf("stuck in %s for more than %s", item.Phase, item.Timeout)
+			if _, err := s.ReportAborted(ctx, item.ID, reason); err != nil {
+				slog.Errorw("abort stuck sector", "err", err)
+				continue
+			}
+
+			slog.Warnw("stuck sector aborted", "timeout", item.Timeout)
+		}
+	}
+}
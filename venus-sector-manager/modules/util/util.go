@@ -3,6 +3,8 @@ package util
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/filecoin-project/go-state-types/abi"
 )
@@ -42,15 +44,73 @@ func SectorSize2SealProofType(size uint64) (abi.RegisteredSealProof, error) {
 type pathType string
 
 const (
-	SectorPathTypeCache    = "cache"
-	SectorPathTypeSealed   = "sealed"
-	SectorPathTypeUnsealed = "unsealed"
+	SectorPathTypeCache       = "cache"
+	SectorPathTypeSealed      = "sealed"
+	SectorPathTypeUnsealed    = "unsealed"
+	SectorPathTypeUpdate      = "update"
+	SectorPathTypeUpdateCache = "update-cache"
 )
 
 func SectorPath(typ pathType, sid abi.SectorID) string {
 	return filepath.Join(string(typ), FormatSectorID(sid))
 }
 
+// TreeRLastFiles returns the names of the tree-r-last files in the cache dir, nil for the unknown sector sizes
+func TreeRLastFiles(ssize abi.SectorSize) []string {
+	count := 0
+	switch ssize {
+	case ss2KiB, ss8MiB, ss512MiB:
+		return []string{"sc-02-data-tree-r-last.dat"}
+
+	case ss32GiB:
+		count = 8
+
+	case ss64GiB:
+		count = 16
+
+	default:
+		return nil
+	}
+
+	files := make([]string, count)
+	for i := range files {
+		files[i] = fmt.Sprintf("sc-02-data-tree-r-last-%d.dat", i)
+	}
+
+	return files
+}
+
 func FormatSectorID(sid abi.SectorID) string {
 	return fmt.Sprintf("s-t0%d-%d", sid.Miner, sid.Number)
 }
+
+// ParseSectorID parses the names formatted by FormatSectorID
+func ParseSectorID(name string) (abi.SectorID, bool) {
+	const prefix = "s-t0"
+	if !strings.HasPrefix(name, prefix) {
+		return abi.SectorID{}, false
+	}
+
+	parts := strings.Split(name[len(prefix):], "-")
+	if len(parts) != 2 {
+		return abi.SectorID{}, false
+	}
+
+	miner, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return abi.SectorID{}, false
+	}
+
+	num, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return abi.SectorID{}, false
+	}
+
+	sid := abi.SectorID{Miner: abi.ActorID(miner), Number: abi.SectorNumber(num)}
+	// reject the names in other forms, such as with leading zeros
+	if FormatSectorID(sid) != name {
+		return abi.SectorID{}, false
+	}
+
+	return sid, true
+}
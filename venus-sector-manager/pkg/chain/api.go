@@ -2,11 +2,14 @@ package chain
 
 import (
 	"context"
+	"crypto/tls"
 
 	"github.com/filecoin-project/go-jsonrpc"
 
 	v1 "github.com/filecoin-project/venus/venus-shared/api/chain/v1"
 	"github.com/filecoin-project/venus/venus-shared/types"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/tlsutil"
 )
 
 const (
@@ -22,8 +25,14 @@ type (
 
 type API = v1.FullNode
 
-func New(ctx context.Context, api, token string) (API, jsonrpc.ClientCloser, error) {
-	client, closer, err := v1.DialFullNodeRPC(ctx, api, token, nil)
+// New dials the full node api, the client tls config will be used for the wss:// & https:// endpoints
+func New(ctx context.Context, api, token string, tlsConf *tls.Config) (API, jsonrpc.ClientCloser, error) {
+	api, header, err := tlsutil.DialURL(ctx, api, tlsConf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, closer, err := v1.DialFullNodeRPC(ctx, api, token, header)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1,6 +1,7 @@
 package kvstore
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
@@ -153,6 +154,22 @@ func (bi *BadgerIter) Next() bool {
 		bi.seeked = true
 	}
 
+	return bi.checkValid()
+}
+
+func (bi *BadgerIter) Seek(key Key) bool {
+	// never seek to somewhere before the prefix
+	if bytes.Compare(key, bi.prefix) < 0 {
+		key = bi.prefix
+	}
+
+	bi.iter.Seek(key)
+	bi.seeked = true
+
+	return bi.checkValid()
+}
+
+func (bi *BadgerIter) checkValid() bool {
 	if len(bi.prefix) == 0 {
 		bi.valid = bi.iter.Valid()
 	} else {
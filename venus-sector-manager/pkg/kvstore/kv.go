@@ -24,6 +24,8 @@ type (
 // Iter is not guaranteed to be thread-safe
 type Iter interface {
 	Next() bool
+	// Seek moves to the first item whose key is not less than the given one
+	Seek(Key) bool
 	Key() Key
 	View(context.Context, Callback) error
 	Close()
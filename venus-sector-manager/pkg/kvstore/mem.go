@@ -0,0 +1,148 @@
+package kvstore
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+)
+
+var _ KVStore = (*MemKVStore)(nil)
+
+// NewMemKVStore creates an in-memory kv store, which is mainly used in the tests
+func NewMemKVStore() *MemKVStore {
+	return &MemKVStore{
+		kvs: map[string][]byte{},
+	}
+}
+
+type MemKVStore struct {
+	mu  sync.RWMutex
+	kvs map[string][]byte
+}
+
+func (m *MemKVStore) Get(ctx context.Context, key Key) (Val, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	val, ok := m.kvs[string(key)]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return append(Val(nil), val...), nil
+}
+
+func (m *MemKVStore) Has(ctx context.Context, key Key) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.kvs[string(key)]
+	return ok, nil
+}
+
+func (m *MemKVStore) View(ctx context.Context, key Key, cb Callback) error {
+	val, err := m.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return cb(val)
+}
+
+func (m *MemKVStore) Put(ctx context.Context, key Key, val Val) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.kvs[string(key)] = append(Val(nil), val...)
+	return nil
+}
+
+func (m *MemKVStore) Del(ctx context.Context, key Key) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.kvs, string(key))
+	return nil
+}
+
+// Scan iterates over a snapshot of the items with the given prefix, in the order of the keys
+func (m *MemKVStore) Scan(ctx context.Context, prefix Prefix) (Iter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	iter := &MemIter{
+		prefix: prefix,
+		pos:    -1,
+	}
+
+	for k, v := range m.kvs {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			iter.keys = append(iter.keys, []byte(k))
+			iter.vals = append(iter.vals, append(Val(nil), v...))
+		}
+	}
+
+	sort.Sort(iter)
+	return iter, nil
+}
+
+func (m *MemKVStore) Run(context.Context) error { return nil }
+
+func (m *MemKVStore) Close(context.Context) error { return nil }
+
+var _ Iter = (*MemIter)(nil)
+
+type MemIter struct {
+	prefix []byte
+	keys   []Key
+	vals   []Val
+	pos    int
+}
+
+func (mi *MemIter) Len() int           { return len(mi.keys) }
+func (mi *MemIter) Less(i, j int) bool { return bytes.Compare(mi.keys[i], mi.keys[j]) < 0 }
+func (mi *MemIter) Swap(i, j int) {
+	mi.keys[i], mi.keys[j] = mi.keys[j], mi.keys[i]
+	mi.vals[i], mi.vals[j] = mi.vals[j], mi.vals[i]
+}
+
+func (mi *MemIter) Next() bool {
+	mi.pos++
+	return mi.valid()
+}
+
+func (mi *MemIter) Seek(key Key) bool {
+	// never seek to somewhere before the prefix
+	if bytes.Compare(key, mi.prefix) < 0 {
+		key = mi.prefix
+	}
+
+	mi.pos = sort.Search(len(mi.keys), func(i int) bool {
+		return bytes.Compare(mi.keys[i], key) >= 0
+	})
+
+	return mi.valid()
+}
+
+func (mi *MemIter) valid() bool {
+	return mi.pos >= 0 && mi.pos < len(mi.keys)
+}
+
+func (mi *MemIter) Key() Key {
+	if !mi.valid() {
+		return nil
+	}
+
+	return mi.keys[mi.pos]
+}
+
+func (mi *MemIter) View(ctx context.Context, cb Callback) error {
+	if !mi.valid() {
+		return ErrIterItemNotValid
+	}
+
+	return cb(mi.vals[mi.pos])
+}
+
+func (mi *MemIter) Close() {}
@@ -72,6 +72,7 @@ func (w *WrappedKVStore) Scan(ctx context.Context, prefix Prefix) (Iter, error)
 	}
 
 	return &WrappedIter{
+		store:     w,
 		prefixLen: w.prefixLen,
 		inner:     iter,
 	}, nil
@@ -86,6 +87,7 @@ func (w *WrappedKVStore) Close(ctx context.Context) error {
 }
 
 type WrappedIter struct {
+	store     *WrappedKVStore
 	prefixLen int
 	inner     Iter
 }
@@ -93,6 +95,7 @@ type WrappedIter struct {
 func (wi *WrappedIter) Next() bool                                  { return wi.inner.Next() }
 func (wi *WrappedIter) View(ctx context.Context, cb Callback) error { return wi.inner.View(ctx, cb) }
 func (wi *WrappedIter) Close()                                      { wi.inner.Close() }
+func (wi *WrappedIter) Seek(key Key) bool                           { return wi.inner.Seek(wi.store.makeKey(key)) }
 
 func (wi *WrappedIter) Key() Key {
 	key := wi.inner.Key()
@@ -2,7 +2,9 @@ package market
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/filecoin-project/go-jsonrpc"
@@ -10,6 +12,8 @@ import (
 	"github.com/filecoin-project/venus/venus-shared/api/market"
 	mtypes "github.com/filecoin-project/venus/venus-shared/types/market"
 	"github.com/ipfs/go-cid"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/tlsutil"
 )
 
 const (
@@ -28,9 +32,13 @@ type API interface {
 type (
 	GetDealSpec         = mtypes.GetDealSpec
 	DealInfoIncludePath = mtypes.DealInfoIncludePath
+	DealStatus          = mtypes.PieceStatus
+	MinerDeal           = mtypes.MinerDeal
 )
 
-func New(ctx context.Context, addr, token string) (API, jsonrpc.ClientCloser, error) {
+// New dials the market api, the client tls config will be used for the wss:// & https:// endpoints.
+// The piece resource urls are always built from the original address, since they are used by the workers.
+func New(ctx context.Context, addr, token string, tlsConf *tls.Config) (API, jsonrpc.ClientCloser, error) {
 	ainfo := api.NewAPIInfo(addr, token)
 
 	dialAddr, err := ainfo.DialArgs(api.VerString(market.MajorVersion))
@@ -38,7 +46,21 @@ func New(ctx context.Context, addr, token string) (API, jsonrpc.ClientCloser, er
 		return nil, nil, fmt.Errorf("get dial args for connecting: %w", err)
 	}
 
-	cli, closer, err := market.NewIMarketRPC(ctx, dialAddr, ainfo.AuthHeader())
+	connectAddr, header, err := tlsutil.DialURL(ctx, dialAddr, tlsConf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authHeader := ainfo.AuthHeader()
+	if authHeader == nil {
+		authHeader = http.Header{}
+	}
+
+	for k := range header {
+		authHeader.Set(k, header.Get(k))
+	}
+
+	cli, closer, err := market.NewIMarketRPC(ctx, connectAddr, authHeader)
 	if err != nil {
 		return nil, nil, fmt.Errorf("construct market api client: %w", err)
 	}
@@ -2,12 +2,15 @@ package messager
 
 import (
 	"context"
+	"crypto/tls"
 
 	"github.com/filecoin-project/go-jsonrpc"
 
 	mapi "github.com/filecoin-project/venus/venus-shared/api/messager"
 	"github.com/filecoin-project/venus/venus-shared/types"
 	mtypes "github.com/filecoin-project/venus/venus-shared/types/messager"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/tlsutil"
 )
 
 type (
@@ -39,6 +42,12 @@ var MessageState = struct {
 
 type API = mapi.IMessager
 
-func New(ctx context.Context, api, token string) (API, jsonrpc.ClientCloser, error) {
-	return mapi.DialIMessagerRPC(ctx, api, token, nil)
+// New dials the messager api, the client tls config will be used for the wss:// & https:// endpoints
+func New(ctx context.Context, api, token string, tlsConf *tls.Config) (API, jsonrpc.ClientCloser, error) {
+	api, header, err := tlsutil.DialURL(ctx, api, tlsConf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mapi.DialIMessagerRPC(ctx, api, token, header)
 }
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "venus_sector_manager"
+
+// rpc
+var (
+	RPCCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "calls_total",
+		Help:      "Number of the sealer rpc calls.",
+	}, []string{"method", "result"})
+
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "duration_seconds",
+		Help:      "Latency of the sealer rpc calls.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"method"})
+)
+
+// sectors
+var (
+	Sectors = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sectors",
+		Name:      "count",
+		Help:      "Number of the online sectors.",
+	}, []string{"miner", "state"})
+
+	ProvableChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sectors",
+		Name:      "provable_checks_total",
+		Help:      "Number of the sectors checked by the tracker.",
+	}, []string{"miner", "result"})
+
+	StuckSectors = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sectors",
+		Name:      "stuck",
+		Help:      "Number of the online sectors exceeding the timeout of their phases.",
+	}, []string{"miner", "phase"})
+)
+
+// workers
+var (
+	Workers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "workers",
+		Name:      "count",
+		Help:      "Number of the registered workers, online or offline.",
+	}, []string{"state"})
+)
+
+// commitment
+var (
+	BatcherPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "commitment",
+		Name:      "batcher_pending",
+		Help:      "Number of the sectors waiting in the batcher.",
+	}, []string{"kind", "miner"})
+
+	BatcherFlushes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "commitment",
+		Name:      "batcher_flushes_total",
+		Help:      "Number of the batcher flushes, with the reason.",
+	}, []string{"kind", "miner", "reason"})
+)
+
+// messages
+var (
+	MessagePushes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "message",
+		Name:      "pushes_total",
+		Help:      "Number of the messages pushed to the messager.",
+	}, []string{"method", "result"})
+
+	MessageLandings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "message",
+		Name:      "landings_total",
+		Help:      "Number of the messages reaching a final state.",
+	}, []string{"method", "result"})
+)
+
+// window post
+var (
+	WdPoStDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "wdpost",
+		Name:      "generate_duration_seconds",
+		Help:      "Duration of the window post generation for each batch.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"miner", "deadline", "result"})
+
+	WdPoStSkipped = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "wdpost",
+		Name:      "skipped_sectors",
+		Help:      "Number of the sectors skipped in the latest window post of the deadline.",
+	}, []string{"miner", "deadline"})
+)
+
+// objstore
+var (
+	ObjstoreDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "objstore",
+		Name:      "op_duration_seconds",
+		Help:      "Latency of the objstore operations.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 10),
+	}, []string{"instance", "op", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		RPCCalls,
+		RPCDuration,
+		Sectors,
+		ProvableChecks,
+		StuckSectors,
+		Workers,
+		BatcherPending,
+		BatcherFlushes,
+		MessagePushes,
+		MessageLandings,
+		WdPoStDuration,
+		WdPoStSkipped,
+		ObjstoreDuration,
+	)
+}
+
+// Handler serves the metrics in the prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func SinceInSeconds(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
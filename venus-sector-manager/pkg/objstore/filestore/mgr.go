@@ -3,6 +3,7 @@ package filestore
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
 )
@@ -39,3 +40,13 @@ func (m *Manager) GetInstance(ctx context.Context, name string) (objstore.Store,
 
 	return store, nil
 }
+
+func (m *Manager) Instances(ctx context.Context) []string {
+	names := make([]string, 0, len(m.stores))
+	for name := range m.stores {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
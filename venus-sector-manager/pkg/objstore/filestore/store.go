@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
@@ -37,6 +38,10 @@ type Config struct {
 	Path     string
 	Strict   bool
 	ReadOnly bool
+	// weight in the persist store placement, 0 is treated as 1
+	Weight uint
+	// a draining store stays readable, but won't be chosen for new sectors
+	Draining bool
 }
 
 func OpenMany(cfgs []Config) ([]*Store, error) {
@@ -187,6 +192,22 @@ func (s *Store) open(p string, r *objstore.Range) (io.ReadCloser, error) {
 
 func (s *Store) Instance(context.Context) string { return s.cfg.Name }
 
+// Config returns the normalized config
+func (s *Store) Config() Config { return s.cfg }
+
+// Space reports the space of the filesystem holding the dir
+func (s *Store) Space(ctx context.Context) (objstore.Space, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(s.cfg.Path, &st); err != nil {
+		return objstore.Space{}, fmt.Errorf("statfs %s: %w", s.cfg.Path, err)
+	}
+
+	return objstore.Space{
+		Total: uint64(st.Blocks) * uint64(st.Bsize),
+		Free:  uint64(st.Bavail) * uint64(st.Bsize),
+	}, nil
+}
+
 func (s *Store) Get(ctx context.Context, p string) (io.ReadCloser, error) {
 	res := s.openWithContext(ctx, p, nil)
 	return res.ReadCloser, res.Err
@@ -218,18 +239,31 @@ func (s *Store) Stat(ctx context.Context, p string) (objstore.Stat, error) {
 	}
 }
 
+func (s *Store) objPath(p string) (string, error) {
+	fpath, err := filepath.Abs(filepath.Join(s.cfg.Path, p))
+	if err != nil {
+		return "", fmt.Errorf("obj %s: %w", p, objstore.ErrInvalidObjectPath)
+	}
+
+	if !strings.HasPrefix(fpath, s.cfg.Path) {
+		return "", fmt.Errorf("obj %s: %w: outside of the dir", p, objstore.ErrInvalidObjectPath)
+	}
+
+	return fpath, nil
+}
+
 func (s *Store) Put(ctx context.Context, p string, r io.Reader) (int64, error) {
 	if s.cfg.ReadOnly {
 		return 0, objstore.ErrReadOnlyStore
 	}
 
-	fpath, err := filepath.Abs(filepath.Join(s.cfg.Path, p))
+	fpath, err := s.objPath(p)
 	if err != nil {
-		return 0, fmt.Errorf("obj %s: %w", p, objstore.ErrInvalidObjectPath)
+		return 0, err
 	}
 
-	if !strings.HasPrefix(fpath, s.cfg.Path) {
-		return 0, fmt.Errorf("obj %s: %w: outside of the dir", p, objstore.ErrInvalidObjectPath)
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		return 0, fmt.Errorf("obj %s: create parent dir: %w", p, err)
 	}
 
 	file, err := os.OpenFile(fpath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
@@ -242,6 +276,55 @@ func (s *Store) Put(ctx context.Context, p string, r io.Reader) (int64, error) {
 	return io.Copy(file, r)
 }
 
+// Del removes the file, or the dir if it is empty
+func (s *Store) Del(ctx context.Context, p string) error {
+	if s.cfg.ReadOnly {
+		return objstore.ErrReadOnlyStore
+	}
+
+	fpath, err := s.objPath(p)
+	if err != nil {
+		return err
+	}
+
+	if fpath == s.cfg.Path {
+		return fmt.Errorf("obj %s: %w: the root dir", p, objstore.ErrInvalidObjectPath)
+	}
+
+	if err := os.Remove(fpath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("obj %s: %w", p, objstore.ErrObjectNotFound)
+		}
+
+		return fmt.Errorf("obj %s: remove: %w", p, err)
+	}
+
+	return nil
+}
+
+func (s *Store) List(ctx context.Context, dir string) ([]string, error) {
+	fpath, err := s.objPath(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(fpath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("obj %s: %w", dir, objstore.ErrObjectNotFound)
+		}
+
+		return nil, fmt.Errorf("obj %s: read dir: %w", dir, err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names, nil
+}
+
 func (s *Store) GetChunks(ctx context.Context, p string, ranges []objstore.Range) ([]objstore.ReaderResult, error) {
 	origin := s.openWithContext(ctx, p, nil)
 	if origin.Err != nil {
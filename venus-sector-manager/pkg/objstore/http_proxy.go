@@ -2,6 +2,7 @@ package objstore
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -9,14 +10,25 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/filecoin-project/go-jsonrpc/auth"
+
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/rpcauth"
 )
 
 var httpLog = logging.New("objstore-http")
 
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// ServeHTTP registers the store into the mux, under the path of /<instance name>/.
+// GET requests with a single range are supported, so that the clients can read the chunks of an object,
+// and GET requests with the list query return the entries in the dir.
+// The tokens should be verified by the wrapping handler, e.g. rpcauth.Protect, while PUT & DELETE requests are
+// always checked here, and require the sign & admin permissions respectively, so that the objects can never be
+// modified by the requests without a verified token.
 func ServeHTTP(ctx context.Context, mux *http.ServeMux, store Store) {
 	instanceName := strings.Trim(store.Instance(ctx), "/")
-	prefix := instanceName + "/"
+	prefix := "/" + instanceName + "/"
 
 	mux.Handle(prefix, http.StripPrefix(prefix, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
@@ -50,8 +62,22 @@ func ServeHTTP(ctx context.Context, mux *http.ServeMux, store Store) {
 
 		switch r.Method {
 		case http.MethodGet:
+			if _, ok := r.URL.Query()["list"]; ok {
+				statusCode, herr = serveList(rw, r, store, p)
+				return
+			}
+
+			if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+				statusCode, herr = serveRange(rw, r, store, p, rangeHeader)
+				return
+			}
+
 			obj, err := store.Get(r.Context(), p)
 			if err != nil {
+				if errors.Is(err, ErrObjectNotFound) {
+					statusCode = http.StatusNotFound
+				}
+
 				herr = fmt.Errorf("get object: %w", err)
 				return
 			}
@@ -64,12 +90,34 @@ func ServeHTTP(ctx context.Context, mux *http.ServeMux, store Store) {
 			}
 
 		case http.MethodPut:
+			if !auth.HasPerm(r.Context(), nil, rpcauth.PermSign) {
+				statusCode = http.StatusUnauthorized
+				herr = fmt.Errorf("put object: %s permission required", rpcauth.PermSign)
+				return
+			}
+
 			_, err := store.Put(r.Context(), p, r.Body)
 			if err != nil {
 				herr = fmt.Errorf("put object: %w", err)
 				return
 			}
 
+		case http.MethodDelete:
+			if !auth.HasPerm(r.Context(), nil, rpcauth.PermAdmin) {
+				statusCode = http.StatusUnauthorized
+				herr = fmt.Errorf("delete object: %s permission required", rpcauth.PermAdmin)
+				return
+			}
+
+			if err := store.Del(r.Context(), p); err != nil {
+				if errors.Is(err, ErrObjectNotFound) {
+					statusCode = http.StatusNotFound
+				}
+
+				herr = fmt.Errorf("delete object: %w", err)
+				return
+			}
+
 		case http.MethodHead:
 			stat, err := store.Stat(r.Context(), p)
 			if err != nil {
@@ -90,3 +138,114 @@ func ServeHTTP(ctx context.Context, mux *http.ServeMux, store Store) {
 		}
 	})))
 }
+
+// serveList sends the names of the entries in the dir as a json array
+func serveList(rw http.ResponseWriter, r *http.Request, store Store, p string) (int, error) {
+	names, err := store.List(r.Context(), p)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return http.StatusNotFound, fmt.Errorf("list dir: %w", err)
+		}
+
+		return http.StatusInternalServerError, fmt.Errorf("list dir: %w", err)
+	}
+
+	if names == nil {
+		names = []string{}
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(names); err != nil {
+		httpLog.Warnw("send entries of dir", "path", p, "err", err)
+	}
+
+	return 0, nil
+}
+
+// serveRange sends a chunk of the object, a non-zero status code and an error will be returned on failure
+func serveRange(rw http.ResponseWriter, r *http.Request, store Store, p string, rangeHeader string) (int, error) {
+	stat, err := store.Stat(r.Context(), p)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return http.StatusNotFound, fmt.Errorf("get stat of object: %w", err)
+		}
+
+		return http.StatusInternalServerError, fmt.Errorf("get stat of object: %w", err)
+	}
+
+	rg, err := parseSingleRange(rangeHeader, stat.Size)
+	if err != nil {
+		rw.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", stat.Size))
+		return http.StatusRequestedRangeNotSatisfiable, err
+	}
+
+	results, err := store.GetChunks(r.Context(), p, []Range{rg})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("get chunk of object: %w", err)
+	}
+
+	chunk := results[0]
+	if chunk.Err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("get chunk of object: %w", chunk.Err)
+	}
+
+	defer chunk.Close()
+
+	rw.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", rg.Offset, rg.Offset+rg.Size-1, stat.Size))
+	rw.Header().Set("Content-Length", strconv.FormatInt(rg.Size, 10))
+	rw.WriteHeader(http.StatusPartialContent)
+
+	// the header has been sent, nothing to do but logging
+	if _, err := io.Copy(rw, chunk); err != nil {
+		httpLog.Warnw("send chunk of object", "path", p, "offset", rg.Offset, "size", rg.Size, "err", err)
+	}
+
+	return 0, nil
+}
+
+// parseSingleRange parses the Range header in the forms of bytes=<start>-<end>, bytes=<start>- and bytes=-<suffix>
+func parseSingleRange(s string, size int64) (Range, error) {
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) || strings.Contains(s, ",") {
+		return Range{}, fmt.Errorf("%w: unsupported range %q", errRangeNotSatisfiable, s)
+	}
+
+	spec := strings.TrimSpace(s[len(b):])
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return Range{}, fmt.Errorf("%w: invalid range %q", errRangeNotSatisfiable, s)
+	}
+
+	start, end := spec[:i], spec[i+1:]
+	if start == "" {
+		n, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || n <= 0 {
+			return Range{}, fmt.Errorf("%w: invalid range %q", errRangeNotSatisfiable, s)
+		}
+
+		if n > size {
+			n = size
+		}
+
+		return Range{Offset: size - n, Size: n}, nil
+	}
+
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || offset < 0 || offset >= size {
+		return Range{}, fmt.Errorf("%w: invalid range %q for size %d", errRangeNotSatisfiable, s, size)
+	}
+
+	last := size - 1
+	if end != "" {
+		last, err = strconv.ParseInt(end, 10, 64)
+		if err != nil || last < offset {
+			return Range{}, fmt.Errorf("%w: invalid range %q", errRangeNotSatisfiable, s)
+		}
+
+		if last >= size {
+			last = size - 1
+		}
+	}
+
+	return Range{Offset: offset, Size: last - offset + 1}, nil
+}
@@ -0,0 +1,137 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/rpcauth"
+)
+
+type memStore struct {
+	Store
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *memStore) Instance(context.Context) string { return "mem" }
+
+func (s *memStore) Get(ctx context.Context, p string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.objects[p]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStore) Put(ctx context.Context, p string, r io.Reader) (int64, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[p] = data
+	return int64(len(data)), nil
+}
+
+func (s *memStore) Del(ctx context.Context, p string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.objects[p]; !ok {
+		return ErrObjectNotFound
+	}
+
+	delete(s.objects, p)
+	return nil
+}
+
+func TestServeHTTPPermissions(t *testing.T) {
+	store := &memStore{objects: map[string][]byte{}}
+	mux := http.NewServeMux()
+	ServeHTTP(context.Background(), mux, store)
+
+	cases := []struct {
+		method string
+		perms  []auth.Permission
+		code   int
+	}{
+		// no verified token at all, e.g. the rpc auth is disabled
+		{http.MethodPut, nil, http.StatusUnauthorized},
+		{http.MethodPut, []auth.Permission{rpcauth.PermRead, rpcauth.PermWorker}, http.StatusUnauthorized},
+		{http.MethodPut, []auth.Permission{rpcauth.PermRead, rpcauth.PermWorker, rpcauth.PermSign}, http.StatusOK},
+		{http.MethodGet, nil, http.StatusOK},
+		{http.MethodDelete, nil, http.StatusUnauthorized},
+		{http.MethodDelete, []auth.Permission{rpcauth.PermRead, rpcauth.PermWorker, rpcauth.PermSign}, http.StatusUnauthorized},
+		{http.MethodDelete, rpcauth.AllPermissions, http.StatusOK},
+		{http.MethodGet, nil, http.StatusNotFound},
+	}
+
+	for i, c := range cases {
+		req := httptest.NewRequest(c.method, "/mem/sealed/s-t01000-1", strings.NewReader("data"))
+		if c.perms != nil {
+			req = req.WithContext(auth.WithPerm(req.Context(), c.perms))
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != c.code {
+			t.Fatalf("#%d %s with %v: got status %d, expected %d", i, c.method, c.perms, rec.Code, c.code)
+		}
+	}
+}
+
+func TestParseSingleRange(t *testing.T) {
+	const size = 1000
+
+	cases := []struct {
+		header   string
+		expected Range
+		valid    bool
+	}{
+		{"bytes=0-499", Range{Offset: 0, Size: 500}, true},
+		{"bytes=500-", Range{Offset: 500, Size: 500}, true},
+		{"bytes=-200", Range{Offset: 800, Size: 200}, true},
+		{"bytes=-2000", Range{Offset: 0, Size: 1000}, true},
+		{"bytes=900-1999", Range{Offset: 900, Size: 100}, true},
+		{"bytes=999-999", Range{Offset: 999, Size: 1}, true},
+		{"bytes=1000-", Range{}, false},
+		{"bytes=-0", Range{}, false},
+		{"bytes=10-5", Range{}, false},
+		{"bytes=0-9,20-29", Range{}, false},
+		{"bytes=10", Range{}, false},
+		{"bytes=a-9", Range{}, false},
+		{"items=0-9", Range{}, false},
+	}
+
+	for _, c := range cases {
+		got, err := parseSingleRange(c.header, size)
+		if !c.valid {
+			if !errors.Is(err, errRangeNotSatisfiable) {
+				t.Errorf("%q: got %v, %v, expected errRangeNotSatisfiable", c.header, got, err)
+			}
+
+			continue
+		}
+
+		if err != nil || got != c.expected {
+			t.Errorf("%q: got %v, %v, expected %v", c.header, got, err, c.expected)
+		}
+	}
+}
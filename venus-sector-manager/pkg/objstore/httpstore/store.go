@@ -0,0 +1,237 @@
+package httpstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+var log = logging.New("objstore-httpstore")
+
+var _ objstore.Store = (*Store)(nil)
+var _ objstore.RemoteStore = (*Store)(nil)
+
+// max size of the error message read from the response body
+const maxErrorBody = 1 << 10
+
+type Config struct {
+	Name string
+	// base url of the store served by objstore.ServeHTTP, e.g. http://10.0.0.14:1789/objstore/store1
+	URL      string
+	Token    string
+	ReadOnly bool
+
+	// optional, http.DefaultClient will be used if nil
+	Client *http.Client
+}
+
+func Open(cfg Config) (*Store, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url %s: %w", cfg.URL, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q in url %s", u.Scheme, cfg.URL)
+	}
+
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.Name == "" {
+		cfg.Name = cfg.URL
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Store{
+		cfg:    cfg,
+		client: client,
+	}, nil
+}
+
+// Store is a client of the object store served by another venus-sector-manager
+type Store struct {
+	cfg    Config
+	client *http.Client
+}
+
+func (s *Store) objURL(p string) string {
+	return s.cfg.URL + (&url.URL{Path: "/" + strings.TrimLeft(p, "/")}).EscapedPath()
+}
+
+// do sends the request, and checks the status code of the response, the body should be closed by the caller if no error returned
+func (s *Store) do(ctx context.Context, method string, p string, body io.Reader, header http.Header, expected int) (*http.Response, error) {
+	return s.doURL(ctx, method, p, s.objURL(p), body, header, expected)
+}
+
+func (s *Store) doURL(ctx context.Context, method string, p string, u string, body io.Reader, header http.Header, expected int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("obj %s: construct request: %w", p, err)
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("obj %s: %s: %w", p, method, err)
+	}
+
+	if resp.StatusCode == expected {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("obj %s: %w", p, objstore.ErrObjectNotFound)
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return nil, fmt.Errorf("obj %s: %s: unexpected status %s: %s", p, method, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func (s *Store) Instance(context.Context) string { return s.cfg.Name }
+
+func (s *Store) Get(ctx context.Context, p string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, p, nil, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (s *Store) Stat(ctx context.Context, p string) (objstore.Stat, error) {
+	resp, err := s.do(ctx, http.MethodHead, p, nil, nil, http.StatusOK)
+	if err != nil {
+		return objstore.Stat{}, err
+	}
+
+	resp.Body.Close()
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return objstore.Stat{}, fmt.Errorf("obj %s: parse content length: %w", p, err)
+	}
+
+	return objstore.Stat{Size: size}, nil
+}
+
+func (s *Store) Put(ctx context.Context, p string, r io.Reader) (int64, error) {
+	if s.cfg.ReadOnly {
+		return 0, objstore.ErrReadOnlyStore
+	}
+
+	cr := &countingReader{Reader: r}
+	resp, err := s.do(ctx, http.MethodPut, p, cr, nil, http.StatusOK)
+	if err != nil {
+		return 0, err
+	}
+
+	resp.Body.Close()
+	return cr.n, nil
+}
+
+func (s *Store) Del(ctx context.Context, p string) error {
+	if s.cfg.ReadOnly {
+		return objstore.ErrReadOnlyStore
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, p, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	return nil
+}
+
+func (s *Store) List(ctx context.Context, dir string) ([]string, error) {
+	resp, err := s.doURL(ctx, http.MethodGet, dir, s.objURL(dir)+"?list", nil, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var names []string
+	if err := json.NewDecoder(resp.Body).Decode(&names); err != nil {
+		return nil, fmt.Errorf("obj %s: decode entries: %w", dir, err)
+	}
+
+	return names, nil
+}
+
+func (s *Store) GetChunks(ctx context.Context, p string, ranges []objstore.Range) ([]objstore.ReaderResult, error) {
+	// make sure that the object exists, as the local stores do
+	if _, err := s.Stat(ctx, p); err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(ranges))
+
+	results := make([]objstore.ReaderResult, len(ranges))
+	for i := range ranges {
+		go func(i int) {
+			defer wg.Done()
+
+			rg := ranges[i]
+			header := http.Header{}
+			header.Set("Range", fmt.Sprintf("bytes=%d-%d", rg.Offset, rg.Offset+rg.Size-1))
+
+			resp, err := s.do(ctx, http.MethodGet, p, nil, header, http.StatusPartialContent)
+			if err != nil {
+				log.Debugw("get chunk", "url", s.objURL(p), "offset", rg.Offset, "size", rg.Size, "err", err)
+				results[i] = objstore.ReaderResult{Err: err}
+				return
+			}
+
+			results[i] = objstore.ReaderResult{ReadCloser: resp.Body}
+		}(i)
+	}
+
+	wg.Wait()
+
+	return results, nil
+}
+
+// FullPath returns the url of the object, which can not be used as a local path
+func (s *Store) FullPath(ctx context.Context, sub string) string {
+	return s.objURL(sub)
+}
+
+// Remote is always true, see FullPath
+func (s *Store) Remote() bool {
+	return true
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.Reader.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,49 @@
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+var _ Manager = (*StoreManager)(nil)
+
+// NewManager combines the given stores, of any kind, into a Manager, their instance names should be unique
+func NewManager(ctx context.Context, stores []Store) (*StoreManager, error) {
+	named := make(map[string]Store, len(stores))
+	for _, store := range stores {
+		name := store.Instance(ctx)
+		if _, ok := named[name]; ok {
+			return nil, fmt.Errorf("duplicate store instance %s", name)
+		}
+
+		named[name] = store
+	}
+
+	return &StoreManager{
+		stores: named,
+	}, nil
+}
+
+type StoreManager struct {
+	stores map[string]Store
+}
+
+func (m *StoreManager) GetInstance(ctx context.Context, name string) (Store, error) {
+	store, ok := m.stores[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrObjectStoreInstanceNotFound, name)
+	}
+
+	return store, nil
+}
+
+func (m *StoreManager) Instances(ctx context.Context) []string {
+	names := make([]string, 0, len(m.stores))
+	for name := range m.stores {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
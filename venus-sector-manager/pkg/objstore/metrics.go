@@ -0,0 +1,92 @@
+package objstore
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/metrics"
+)
+
+var _ Manager = (*metricedManager)(nil)
+
+// Metriced wraps the given Manager, recording the latency of the operations on each instance
+func Metriced(inner Manager) Manager {
+	return &metricedManager{inner: inner}
+}
+
+type metricedManager struct {
+	inner Manager
+}
+
+func (m *metricedManager) GetInstance(ctx context.Context, name string) (Store, error) {
+	store, err := m.inner.GetInstance(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metricedStore{Store: store, name: name}, nil
+}
+
+func (m *metricedManager) Instances(ctx context.Context) []string {
+	return m.inner.Instances(ctx)
+}
+
+type metricedStore struct {
+	Store
+	name string
+}
+
+func (s *metricedStore) Unwrap() Store {
+	return s.Store
+}
+
+func (s *metricedStore) Remote() bool {
+	return IsRemote(s.Store)
+}
+
+func (s *metricedStore) observe(op string, start time.Time, err error) {
+	metrics.ObjstoreDuration.WithLabelValues(s.name, op, metrics.Result(err)).Observe(metrics.SinceInSeconds(start))
+}
+
+func (s *metricedStore) Get(ctx context.Context, p string) (io.ReadCloser, error) {
+	start := time.Now()
+	r, err := s.Store.Get(ctx, p)
+	s.observe("get", start, err)
+	return r, err
+}
+
+func (s *metricedStore) Stat(ctx context.Context, p string) (Stat, error) {
+	start := time.Now()
+	st, err := s.Store.Stat(ctx, p)
+	s.observe("stat", start, err)
+	return st, err
+}
+
+func (s *metricedStore) Put(ctx context.Context, p string, r io.Reader) (int64, error) {
+	start := time.Now()
+	written, err := s.Store.Put(ctx, p, r)
+	s.observe("put", start, err)
+	return written, err
+}
+
+func (s *metricedStore) Del(ctx context.Context, p string) error {
+	start := time.Now()
+	err := s.Store.Del(ctx, p)
+	s.observe("del", start, err)
+	return err
+}
+
+func (s *metricedStore) List(ctx context.Context, dir string) ([]string, error) {
+	start := time.Now()
+	names, err := s.Store.List(ctx, dir)
+	s.observe("list", start, err)
+	return names, err
+}
+
+func (s *metricedStore) GetChunks(ctx context.Context, p string, ranges []Range) ([]ReaderResult, error) {
+	start := time.Now()
+	res, err := s.Store.GetChunks(ctx, p, ranges)
+	s.observe("get_chunks", start, err)
+	return res, err
+}
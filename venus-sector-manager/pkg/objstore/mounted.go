@@ -0,0 +1,34 @@
+package objstore
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// Mounted wraps a remote store whose objects are also mounted at the given local path, e.g. by nfs, s3fs or rclone.
+// FullPath of the wrapped store returns the paths under the mount point, so that the proving procedures can read
+// the sectors in it, while all the other operations still go through the store itself.
+func Mounted(store Store, path string) Store {
+	return &mountedStore{
+		Store: store,
+		path:  path,
+	}
+}
+
+type mountedStore struct {
+	Store
+	path string
+}
+
+func (s *mountedStore) Unwrap() Store {
+	return s.Store
+}
+
+func (s *mountedStore) FullPath(ctx context.Context, sub string) string {
+	return filepath.Join(s.path, sub)
+}
+
+// Remote is always false, since the objects are accessible through the local paths
+func (s *mountedStore) Remote() bool {
+	return false
+}
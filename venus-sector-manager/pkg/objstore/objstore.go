@@ -30,15 +30,53 @@ type Stat struct {
 	Size int64
 }
 
+// Space is the capacity of a store, in bytes, Free is the space available to the unprivileged users
+type Space struct {
+	Total uint64
+	Free  uint64
+}
+
 type Store interface {
 	Instance(context.Context) string
 	Get(context.Context, string) (io.ReadCloser, error)
 	Stat(context.Context, string) (Stat, error)
 	Put(context.Context, string, io.Reader) (int64, error)
+	Del(context.Context, string) error
+	// List returns the names of the entries in the dir, ErrObjectNotFound if the dir does not exist
+	List(context.Context, string) ([]string, error)
 	GetChunks(context.Context, string, []Range) ([]ReaderResult, error)
 	FullPath(context.Context, string) string
 }
 
+// RemoteStore is implemented by the stores whose objects are not on the local file system.
+// FullPath of such a store returns an url, which can not be used by the proving procedures.
+type RemoteStore interface {
+	Remote() bool
+}
+
+// IsRemote returns true if the objects of the store can not be accessed through local paths
+func IsRemote(store Store) bool {
+	if rs, ok := store.(RemoteStore); ok {
+		return rs.Remote()
+	}
+
+	return false
+}
+
+// Unwrap returns the innermost store wrapped by the helpers such as Metriced
+func Unwrap(store Store) Store {
+	for {
+		wrapper, ok := store.(interface{ Unwrap() Store })
+		if !ok {
+			return store
+		}
+
+		store = wrapper.Unwrap()
+	}
+}
+
 type Manager interface {
 	GetInstance(ctx context.Context, name string) (Store, error)
+	// Instances returns the names of all the instances, sorted
+	Instances(ctx context.Context) []string
 }
@@ -0,0 +1,554 @@
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+var log = logging.New("objstore-s3")
+
+var _ objstore.Store = (*Store)(nil)
+var _ objstore.RemoteStore = (*Store)(nil)
+
+const (
+	DefaultRegion   = "us-east-1"
+	DefaultPartSize = 64 << 20
+
+	// limited by the s3 api
+	minPartSize = 5 << 20
+
+	maxErrorBody = 4 << 10
+)
+
+type Config struct {
+	// url of the s3 service, e.g. http://10.0.0.1:9000
+	Endpoint string
+	Region   string
+	Bucket   string
+	// all the objects will be kept under this prefix, if set
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	// use the virtual hosted style urls, i.e. http://<bucket>.<host>/<key>, instead of the path style urls
+	VirtualHostStyle bool
+	// size of each part in the multipart uploads
+	PartSize int64
+}
+
+// Open checks the config, http.DefaultClient will be used if client is nil
+func Open(name string, readOnly bool, cfg Config, client *http.Client) (*Store, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint %s: %w", cfg.Endpoint, err)
+	}
+
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q in endpoint %s", endpoint.Scheme, cfg.Endpoint)
+	}
+
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required")
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = DefaultRegion
+	}
+
+	if cfg.PartSize == 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+
+	if cfg.PartSize < minPartSize {
+		return nil, fmt.Errorf("part size %d is less than the minimum %d", cfg.PartSize, minPartSize)
+	}
+
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("s3://%s/%s", cfg.Bucket, cfg.Prefix)
+	}
+
+	return &Store{
+		name:     name,
+		readOnly: readOnly,
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   client,
+	}, nil
+}
+
+// Store keeps the objects in a bucket of an S3 compatible service
+type Store struct {
+	name     string
+	readOnly bool
+	cfg      Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func (s *Store) key(p string) string {
+	p = strings.TrimLeft(p, "/")
+	if s.cfg.Prefix == "" {
+		return p
+	}
+
+	return s.cfg.Prefix + "/" + p
+}
+
+func (s *Store) newRequest(ctx context.Context, method string, p string, query url.Values, body io.Reader) (*http.Request, error) {
+	return s.newKeyRequest(ctx, method, p, s.key(p), query, body)
+}
+
+// newKeyRequest constructs the signed request for the key, an empty key is for the bucket itself
+func (s *Store) newKeyRequest(ctx context.Context, method string, p string, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	host := s.endpoint.Host
+	objPath := "/" + s.cfg.Bucket + "/" + key
+	if s.cfg.VirtualHostStyle {
+		host = s.cfg.Bucket + "." + host
+		objPath = "/" + key
+	}
+
+	canonicalURI := uriEncode(objPath, false)
+	u := &url.URL{
+		Scheme:   s.endpoint.Scheme,
+		Host:     host,
+		Path:     objPath,
+		RawPath:  canonicalURI,
+		RawQuery: canonicalQuery(query),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("obj %s: construct request: %w", p, err)
+	}
+
+	signV4(req, canonicalURI, s.cfg.AccessKey, s.cfg.SecretKey, s.cfg.Region, time.Now())
+	return req, nil
+}
+
+// do sends the request, and checks the status code of the response, the body should be closed by the caller if no error returned
+func (s *Store) do(req *http.Request, p string, expected int) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("obj %s: %s: %w", p, req.Method, err)
+	}
+
+	if resp.StatusCode == expected {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && req.URL.RawQuery == "" {
+		return nil, fmt.Errorf("obj %s: %w", p, objstore.ErrObjectNotFound)
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if serr, ok := parseError(body); ok {
+		return nil, fmt.Errorf("obj %s: %s: %s: %w", p, req.Method, resp.Status, serr)
+	}
+
+	return nil, fmt.Errorf("obj %s: %s: unexpected status %s", p, req.Method, resp.Status)
+}
+
+func (s *Store) Instance(context.Context) string { return s.name }
+
+func (s *Store) Get(ctx context.Context, p string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, p, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, p, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (s *Store) Stat(ctx context.Context, p string) (objstore.Stat, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, p, nil, nil)
+	if err != nil {
+		return objstore.Stat{}, err
+	}
+
+	resp, err := s.do(req, p, http.StatusOK)
+	if err != nil {
+		return objstore.Stat{}, err
+	}
+
+	resp.Body.Close()
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return objstore.Stat{}, fmt.Errorf("obj %s: parse content length: %w", p, err)
+	}
+
+	return objstore.Stat{Size: size}, nil
+}
+
+// GetChunks reads the ranges concurrently, each with a ranged GET request
+func (s *Store) GetChunks(ctx context.Context, p string, ranges []objstore.Range) ([]objstore.ReaderResult, error) {
+	// make sure that the object exists, as the local stores do
+	if _, err := s.Stat(ctx, p); err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(ranges))
+
+	results := make([]objstore.ReaderResult, len(ranges))
+	for i := range ranges {
+		go func(i int) {
+			defer wg.Done()
+			results[i] = s.getRange(ctx, p, ranges[i])
+		}(i)
+	}
+
+	wg.Wait()
+
+	return results, nil
+}
+
+func (s *Store) getRange(ctx context.Context, p string, rg objstore.Range) objstore.ReaderResult {
+	req, err := s.newRequest(ctx, http.MethodGet, p, nil, nil)
+	if err != nil {
+		return objstore.ReaderResult{Err: err}
+	}
+
+	// not included in the signed headers
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", rg.Offset, rg.Offset+rg.Size-1))
+
+	resp, err := s.do(req, p, http.StatusPartialContent)
+	if err != nil {
+		log.Debugw("get chunk", "key", s.key(p), "offset", rg.Offset, "size", rg.Size, "err", err)
+		return objstore.ReaderResult{Err: err}
+	}
+
+	return objstore.ReaderResult{ReadCloser: resp.Body}
+}
+
+// Put uploads the object in a single request if it fits into one part, otherwise a multipart upload will be used
+func (s *Store) Put(ctx context.Context, p string, r io.Reader) (int64, error) {
+	if s.readOnly {
+		return 0, objstore.ErrReadOnlyStore
+	}
+
+	buf := make([]byte, s.cfg.PartSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return int64(n), s.putObject(ctx, p, buf[:n])
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("obj %s: read data: %w", p, err)
+	}
+
+	uploadID, err := s.createMultipartUpload(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := s.uploadParts(ctx, p, uploadID, r, buf)
+	if err != nil {
+		if aerr := s.abortMultipartUpload(p, uploadID); aerr != nil {
+			log.Warnw("abort multipart upload", "key", s.key(p), "upload", uploadID, "err", aerr)
+		}
+
+		return 0, err
+	}
+
+	return written, nil
+}
+
+// Del removes the object, it is not an error if the object does not exist, as the s3 services behave
+func (s *Store) Del(ctx context.Context, p string) error {
+	if s.readOnly {
+		return objstore.ErrReadOnlyStore
+	}
+
+	req, err := s.newRequest(ctx, http.MethodDelete, p, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, p, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	return nil
+}
+
+type listBucketResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key string
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
+
+// List lists the objects and the common prefixes under the dir, the prefixes are treated as sub dirs
+func (s *Store) List(ctx context.Context, dir string) ([]string, error) {
+	prefix := strings.TrimRight(s.key(dir), "/") + "/"
+	if prefix == "/" {
+		prefix = ""
+	}
+
+	var names []string
+	token := ""
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"delimiter": {"/"},
+			"prefix":    {prefix},
+		}
+
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := s.newKeyRequest(ctx, http.MethodGet, dir, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.do(req, dir, http.StatusOK)
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %w", err)
+		}
+
+		var res listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("obj %s: decode list result: %w", dir, err)
+		}
+
+		for _, c := range res.Contents {
+			if name := strings.TrimPrefix(c.Key, prefix); name != "" {
+				names = append(names, name)
+			}
+		}
+
+		for _, cp := range res.CommonPrefixes {
+			if name := strings.TrimSuffix(strings.TrimPrefix(cp.Prefix, prefix), "/"); name != "" {
+				names = append(names, name)
+			}
+		}
+
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+
+		token = res.NextContinuationToken
+	}
+
+	// there is no dir in s3, an empty prefix is treated as not existing
+	if len(names) == 0 {
+		return nil, fmt.Errorf("obj %s: %w", dir, objstore.ErrObjectNotFound)
+	}
+
+	return names, nil
+}
+
+func (s *Store) putObject(ctx context.Context, p string, data []byte) error {
+	req, err := s.newRequest(ctx, http.MethodPut, p, nil, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, p, http.StatusOK)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	return nil
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+func (s *Store) createMultipartUpload(ctx context.Context, p string) (string, error) {
+	req, err := s.newRequest(ctx, http.MethodPost, p, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.do(req, p, http.StatusOK)
+	if err != nil {
+		return "", fmt.Errorf("create multipart upload: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	var res initiateMultipartUploadResult
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("obj %s: decode multipart upload result: %w", p, err)
+	}
+
+	if res.UploadID == "" {
+		return "", fmt.Errorf("obj %s: empty upload id", p)
+	}
+
+	return res.UploadID, nil
+}
+
+// uploadParts uploads the data already in buf as the first part, and then the rest read from r
+func (s *Store) uploadParts(ctx context.Context, p string, uploadID string, r io.Reader, buf []byte) (int64, error) {
+	var parts []completedPart
+	var written int64
+
+	size := len(buf)
+	last := false
+	for num := 1; ; num++ {
+		query := url.Values{
+			"partNumber": {strconv.Itoa(num)},
+			"uploadId":   {uploadID},
+		}
+
+		req, err := s.newRequest(ctx, http.MethodPut, p, query, bytes.NewReader(buf[:size]))
+		if err != nil {
+			return 0, err
+		}
+
+		resp, err := s.do(req, p, http.StatusOK)
+		if err != nil {
+			return 0, fmt.Errorf("upload part %d: %w", num, err)
+		}
+
+		resp.Body.Close()
+
+		parts = append(parts, completedPart{
+			PartNumber: num,
+			ETag:       resp.Header.Get("ETag"),
+		})
+		written += int64(size)
+
+		if last {
+			break
+		}
+
+		size, err = io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+
+		if err == io.ErrUnexpectedEOF {
+			last = true
+		} else if err != nil {
+			return 0, fmt.Errorf("obj %s: read data: %w", p, err)
+		}
+	}
+
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return 0, fmt.Errorf("obj %s: marshal completed parts: %w", p, err)
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPost, p, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.do(req, p, http.StatusOK)
+	if err != nil {
+		return 0, fmt.Errorf("complete multipart upload: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	// the service may report an error after the 200 OK status
+	res, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err != nil {
+		return 0, fmt.Errorf("obj %s: read complete multipart upload result: %w", p, err)
+	}
+
+	if serr, ok := parseError(res); ok {
+		return 0, fmt.Errorf("obj %s: complete multipart upload: %w", p, serr)
+	}
+
+	return written, nil
+}
+
+func (s *Store) abortMultipartUpload(p string, uploadID string) error {
+	// the ctx of the upload may have been canceled
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	req, err := s.newRequest(ctx, http.MethodDelete, p, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, p, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	return nil
+}
+
+// FullPath returns the s3 url of the object, which can not be used as a local path
+func (s *Store) FullPath(ctx context.Context, sub string) string {
+	return fmt.Sprintf("s3://%s/%s", s.cfg.Bucket, s.key(sub))
+}
+
+// Remote is always true, see FullPath
+func (s *Store) Remote() bool {
+	return true
+}
+
+type errorResponse struct {
+	XMLName xml.Name
+	Code    string
+	Message string
+}
+
+func (e *errorResponse) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func parseError(body []byte) (*errorResponse, bool) {
+	var eres errorResponse
+	if err := xml.Unmarshal(body, &eres); err != nil || eres.XMLName.Local != "Error" {
+		return nil, false
+	}
+
+	return &eres, true
+}
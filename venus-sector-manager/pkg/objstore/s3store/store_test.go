@@ -0,0 +1,464 @@
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+const (
+	testBucket    = "sectors"
+	testAccessKey = "access"
+	testSecretKey = "secret"
+)
+
+// fakeS3 serves the subset of the s3 api used by the Store, with the signatures checked
+type fakeS3 struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int][]byte
+	nextID   int
+	pageSize int
+	// the part number to fail, if not 0
+	failPart int
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	return &fakeS3{
+		t:        t,
+		objects:  map[string][]byte{},
+		uploads:  map[string]map[int][]byte{},
+		pageSize: 2,
+	}
+}
+
+func (f *fakeS3) writeError(rw http.ResponseWriter, code int, errCode string) {
+	rw.WriteHeader(code)
+	fmt.Fprintf(rw, "<Error><Code>%s</Code><Message>%s</Message></Error>", errCode, http.StatusText(code))
+}
+
+func (f *fakeS3) checkSignature(req *http.Request) bool {
+	now, err := time.Parse(amzDateFormat, req.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	expected, err := http.NewRequest(req.Method, "http://"+req.Host+req.URL.RequestURI(), nil)
+	if err != nil {
+		return false
+	}
+
+	expected.Header.Set("X-Amz-Content-Sha256", req.Header.Get("X-Amz-Content-Sha256"))
+	sign(expected, req.URL.EscapedPath(), []string{"host", "x-amz-content-sha256", "x-amz-date"}, unsignedPayload, testAccessKey, testSecretKey, DefaultRegion, signService, now)
+	return expected.Header.Get("Authorization") == req.Header.Get("Authorization")
+}
+
+func (f *fakeS3) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if !f.checkSignature(req) {
+		f.writeError(rw, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	if !strings.HasPrefix(req.URL.Path, "/"+testBucket+"/") {
+		f.writeError(rw, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	key := strings.TrimPrefix(req.URL.Path, "/"+testBucket+"/")
+	query := req.URL.Query()
+	has := func(k string) bool {
+		_, ok := query[k]
+		return ok
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case key == "" && req.Method == http.MethodGet:
+		f.list(rw, query)
+
+	case req.Method == http.MethodPost && has("uploads"):
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(rw, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+
+	case req.Method == http.MethodPost && has("uploadId"):
+		f.complete(rw, req, key, query.Get("uploadId"))
+
+	case req.Method == http.MethodPut && has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			f.writeError(rw, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+
+		num, _ := strconv.Atoi(query.Get("partNumber"))
+		if num == f.failPart {
+			f.writeError(rw, http.StatusInternalServerError, "InternalError")
+			return
+		}
+
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			f.writeError(rw, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		parts[num] = data
+		rw.Header().Set("ETag", fmt.Sprintf("%q", fmt.Sprintf("etag-%d", num)))
+
+	case req.Method == http.MethodPut:
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			f.writeError(rw, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		f.objects[key] = data
+
+	case req.Method == http.MethodDelete && has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		rw.WriteHeader(http.StatusNoContent)
+
+	case req.Method == http.MethodDelete:
+		delete(f.objects, key)
+		rw.WriteHeader(http.StatusNoContent)
+
+	case req.Method == http.MethodGet, req.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			f.writeError(rw, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		http.ServeContent(rw, req, "", time.Time{}, bytes.NewReader(data))
+
+	default:
+		f.writeError(rw, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) complete(rw http.ResponseWriter, req *http.Request, key string, uploadID string) {
+	parts, ok := f.uploads[uploadID]
+	if !ok {
+		f.writeError(rw, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	var completed completeMultipartUpload
+	if err := xml.NewDecoder(req.Body).Decode(&completed); err != nil {
+		f.writeError(rw, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+
+	var data []byte
+	for i, p := range completed.Parts {
+		part, ok := parts[p.PartNumber]
+		if !ok || p.PartNumber != i+1 || p.ETag != fmt.Sprintf("%q", fmt.Sprintf("etag-%d", p.PartNumber)) {
+			f.writeError(rw, http.StatusBadRequest, "InvalidPart")
+			return
+		}
+
+		data = append(data, part...)
+	}
+
+	delete(f.uploads, uploadID)
+	f.objects[key] = data
+	fmt.Fprintf(rw, "<CompleteMultipartUploadResult><Key>%s</Key></CompleteMultipartUploadResult>", key)
+}
+
+func (f *fakeS3) list(rw http.ResponseWriter, query map[string][]string) {
+	get := func(k string) string {
+		if v := query[k]; len(v) > 0 {
+			return v[0]
+		}
+
+		return ""
+	}
+
+	prefix := get("prefix")
+	delimiter := get("delimiter")
+
+	// contents & common prefixes, in the order of the keys
+	var entries []string
+	seen := map[string]bool{}
+	keys := make([]string, 0, len(f.objects))
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+
+		entry := k
+		if idx := strings.Index(k[len(prefix):], delimiter); delimiter != "" && idx >= 0 {
+			entry = k[:len(prefix)+idx+len(delimiter)]
+		}
+
+		if !seen[entry] {
+			seen[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+
+	start, _ := strconv.Atoi(get("continuation-token"))
+	end := start + f.pageSize
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	var res listBucketResult
+	for _, entry := range entries[start:end] {
+		if strings.HasSuffix(entry, delimiter) {
+			res.CommonPrefixes = append(res.CommonPrefixes, struct{ Prefix string }{entry})
+		} else {
+			res.Contents = append(res.Contents, struct{ Key string }{entry})
+		}
+	}
+
+	if end < len(entries) {
+		res.IsTruncated = true
+		res.NextContinuationToken = strconv.Itoa(end)
+	}
+
+	if err := xml.NewEncoder(rw).Encode(res); err != nil {
+		f.t.Errorf("encode list result: %s", err)
+	}
+}
+
+func openTestStore(t *testing.T, fake *fakeS3, cfg Config) *Store {
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	cfg.Endpoint = srv.URL
+	cfg.Bucket = testBucket
+	cfg.AccessKey = testAccessKey
+	cfg.SecretKey = testSecretKey
+
+	store, err := Open("", false, cfg, srv.Client())
+	if err != nil {
+		t.Fatalf("open store: %s", err)
+	}
+
+	return store
+}
+
+func hashOf(r io.Reader) ([]byte, int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	return h.Sum(nil), n, err
+}
+
+func TestStorePut(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3(t)
+	store := openTestStore(t, fake, Config{Prefix: "t01000"})
+
+	cases := []struct {
+		name string
+		size int64
+	}{
+		{"empty", 0},
+		{"single", 1 << 10},
+		{"one part", DefaultPartSize},
+		{"multipart", 2*DefaultPartSize + 123},
+	}
+
+	for i, c := range cases {
+		if c.size > DefaultPartSize && testing.Short() {
+			continue
+		}
+
+		expected, _, err := hashOf(io.LimitReader(rand.New(rand.NewSource(int64(i))), c.size))
+		if err != nil {
+			t.Fatalf("%s: hash data: %s", c.name, err)
+		}
+
+		p := "sealed/s-t01000-" + strconv.Itoa(i)
+		written, err := store.Put(ctx, p, io.LimitReader(rand.New(rand.NewSource(int64(i))), c.size))
+		if err != nil {
+			t.Fatalf("%s: put: %s", c.name, err)
+		}
+
+		if written != c.size {
+			t.Fatalf("%s: %d bytes written, expected %d", c.name, written, c.size)
+		}
+
+		stat, err := store.Stat(ctx, p)
+		if err != nil {
+			t.Fatalf("%s: stat: %s", c.name, err)
+		}
+
+		if stat.Size != c.size {
+			t.Fatalf("%s: got size %d, expected %d", c.name, stat.Size, c.size)
+		}
+
+		r, err := store.Get(ctx, p)
+		if err != nil {
+			t.Fatalf("%s: get: %s", c.name, err)
+		}
+
+		got, n, err := hashOf(r)
+		r.Close()
+		if err != nil || n != c.size || !bytes.Equal(got, expected) {
+			t.Fatalf("%s: got %d bytes, err %v, content mismatched: %v", c.name, n, err, !bytes.Equal(got, expected))
+		}
+
+		if err := store.Del(ctx, p); err != nil {
+			t.Fatalf("%s: del: %s", c.name, err)
+		}
+
+		if _, err := store.Stat(ctx, p); !errors.Is(err, objstore.ErrObjectNotFound) {
+			t.Fatalf("%s: expected ErrObjectNotFound after del, got %v", c.name, err)
+		}
+	}
+
+	if len(fake.uploads) != 0 {
+		t.Fatalf("%d multipart uploads left", len(fake.uploads))
+	}
+}
+
+func TestStorePutAbort(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3(t)
+	fake.failPart = 2
+	store := openTestStore(t, fake, Config{PartSize: minPartSize})
+
+	if _, err := store.Put(ctx, "aborted", bytes.NewReader(make([]byte, 3*minPartSize))); err == nil {
+		t.Fatal("expected an error from the failed part")
+	}
+
+	if len(fake.uploads) != 0 {
+		t.Fatal("the failed multipart upload is not aborted")
+	}
+
+	if _, err := store.Stat(ctx, "aborted"); !errors.Is(err, objstore.ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func TestStoreGetChunks(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t, newFakeS3(t), Config{})
+
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	if _, err := store.Put(ctx, "cache/p_aux", bytes.NewReader(data)); err != nil {
+		t.Fatalf("put: %s", err)
+	}
+
+	ranges := []objstore.Range{{Offset: 0, Size: 4}, {Offset: 10, Size: 6}, {Offset: 35, Size: 1}}
+	results, err := store.GetChunks(ctx, "cache/p_aux", ranges)
+	if err != nil {
+		t.Fatalf("get chunks: %s", err)
+	}
+
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatalf("chunk #%d: %s", i, res.Err)
+		}
+
+		got, err := ioutil.ReadAll(res)
+		res.Close()
+		if err != nil {
+			t.Fatalf("chunk #%d: read: %s", i, err)
+		}
+
+		rg := ranges[i]
+		if expected := data[rg.Offset : rg.Offset+rg.Size]; !bytes.Equal(got, expected) {
+			t.Fatalf("chunk #%d: got %q, expected %q", i, got, expected)
+		}
+	}
+
+	if _, err := store.GetChunks(ctx, "cache/missing", ranges); !errors.Is(err, objstore.ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound for the missing object, got %v", err)
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t, newFakeS3(t), Config{Prefix: "/t01000/"})
+
+	for _, p := range []string{"sealed/s-t01000-1", "sealed/s-t01000-2", "sealed/s-t01000-3", "cache/s-t01000-1/p_aux", "cache/s-t01000-1/t_aux", "cache/s-t01000-2/p_aux", "a b+c"} {
+		if _, err := store.Put(ctx, p, strings.NewReader(p)); err != nil {
+			t.Fatalf("put %s: %s", p, err)
+		}
+	}
+
+	cases := []struct {
+		dir      string
+		expected []string
+	}{
+		{"", []string{"a b+c", "cache", "sealed"}},
+		{"/", []string{"a b+c", "cache", "sealed"}},
+		{"sealed", []string{"s-t01000-1", "s-t01000-2", "s-t01000-3"}},
+		{"cache/", []string{"s-t01000-1", "s-t01000-2"}},
+		{"cache/s-t01000-1", []string{"p_aux", "t_aux"}},
+	}
+
+	for _, c := range cases {
+		names, err := store.List(ctx, c.dir)
+		if err != nil {
+			t.Fatalf("list %q: %s", c.dir, err)
+		}
+
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(c.expected, ",") {
+			t.Fatalf("list %q: got %v, expected %v", c.dir, names, c.expected)
+		}
+	}
+
+	if _, err := store.List(ctx, "update"); !errors.Is(err, objstore.ErrObjectNotFound) {
+		t.Fatalf("expected ErrObjectNotFound for the empty dir, got %v", err)
+	}
+
+	r, err := store.Get(ctx, "a b+c")
+	if err != nil {
+		t.Fatalf("get the escaped key: %s", err)
+	}
+
+	defer r.Close()
+	if got, _ := ioutil.ReadAll(r); string(got) != "a b+c" {
+		t.Fatalf("got %q for the escaped key", got)
+	}
+}
+
+func TestStoreReadOnly(t *testing.T) {
+	srv := httptest.NewServer(newFakeS3(t))
+	defer srv.Close()
+
+	store, err := Open("", true, Config{Endpoint: srv.URL, Bucket: testBucket}, srv.Client())
+	if err != nil {
+		t.Fatalf("open store: %s", err)
+	}
+
+	if _, err := store.Put(context.Background(), "sealed/s-t01000-1", strings.NewReader("data")); !errors.Is(err, objstore.ErrReadOnlyStore) {
+		t.Fatalf("expected ErrReadOnlyStore for put, got %v", err)
+	}
+
+	if err := store.Del(context.Background(), "sealed/s-t01000-1"); !errors.Is(err, objstore.ErrReadOnlyStore) {
+		t.Fatalf("expected ErrReadOnlyStore for del, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package piecestore
+
+import (
+	"fmt"
+	"io"
+)
+
+// the sealed data is fr32 padded: every 127 bytes are stored as 4 field elements of 254 bits in 128 bytes
+const (
+	fr32PaddedChunk   = 128
+	fr32UnpaddedChunk = 127
+
+	// number of the chunks read from the underlying reader at a time
+	fr32ReadChunks = 1024
+)
+
+// unpadChunk restores the 127 bytes of data from a padded chunk
+func unpadChunk(out []byte, in []byte) {
+	for i := range out[:fr32UnpaddedChunk] {
+		out[i] = 0
+	}
+
+	for e := 0; e < 4; e++ {
+		base := 254 * e / 8
+		shift := uint(254*e) % 8
+		elem := in[32*e : 32*e+32]
+		for i, b := range elem {
+			// the highest 2 bits of each element are always zero
+			if i == 31 {
+				b &= 0x3f
+			}
+
+			v := uint16(b) << shift
+			out[base+i] |= byte(v)
+			if base+i+1 < fr32UnpaddedChunk {
+				out[base+i+1] |= byte(v >> 8)
+			}
+		}
+	}
+}
+
+// paddedRange returns the chunk-aligned range of the padded data covering the unpadded range [offset, offset+size)
+func paddedRange(offset, size int64) (int64, int64) {
+	first := offset / fr32UnpaddedChunk
+	last := (offset + size + fr32UnpaddedChunk - 1) / fr32UnpaddedChunk
+	return first * fr32PaddedChunk, (last - first) * fr32PaddedChunk
+}
+
+func newUnpadReader(r io.ReadCloser, offset, size int64) *unpadReader {
+	return &unpadReader{
+		r:         r,
+		padded:    make([]byte, fr32ReadChunks*fr32PaddedChunk),
+		unpadded:  make([]byte, fr32ReadChunks*fr32UnpaddedChunk),
+		skip:      offset % fr32UnpaddedChunk,
+		remaining: size,
+	}
+}
+
+// unpadReader reads the unpadded data from the padded data starting at a chunk boundary,
+// the leading bytes before the requested offset are skipped.
+type unpadReader struct {
+	r         io.ReadCloser
+	padded    []byte
+	unpadded  []byte
+	buf       []byte
+	skip      int64
+	remaining int64
+}
+
+func (u *unpadReader) Read(p []byte) (int, error) {
+	if u.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	for len(u.buf) == 0 {
+		if err := u.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	if int64(len(p)) > u.remaining {
+		p = p[:u.remaining]
+	}
+
+	n := copy(p, u.buf)
+	u.buf = u.buf[n:]
+	u.remaining -= int64(n)
+	return n, nil
+}
+
+func (u *unpadReader) fill() error {
+	n, err := io.ReadFull(u.r, u.padded)
+	chunks := n / fr32PaddedChunk
+	if chunks == 0 {
+		if err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%d bytes left unread: %w", u.remaining, io.ErrUnexpectedEOF)
+		}
+
+		return err
+	}
+
+	out := u.unpadded[:chunks*fr32UnpaddedChunk]
+	for i := 0; i < chunks; i++ {
+		unpadChunk(out[i*fr32UnpaddedChunk:], u.padded[i*fr32PaddedChunk:])
+	}
+
+	if u.skip > 0 {
+		skip := u.skip
+		if skip > int64(len(out)) {
+			skip = int64(len(out))
+		}
+
+		out = out[skip:]
+		u.skip -= skip
+	}
+
+	u.buf = out
+	return nil
+}
+
+func (u *unpadReader) Close() error {
+	return u.r.Close()
+}
@@ -0,0 +1,143 @@
+package piecestore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// padChunk is the reference padding, every 254 bits of the data are followed by 2 zero bits
+func padChunk(out []byte, in []byte) {
+	for i := range out[:fr32PaddedChunk] {
+		out[i] = 0
+	}
+
+	for i := 0; i < fr32UnpaddedChunk*8; i++ {
+		if in[i/8]&(1<<uint(i%8)) == 0 {
+			continue
+		}
+
+		pos := i + 2*(i/254)
+		out[pos/8] |= 1 << uint(pos%8)
+	}
+}
+
+func padData(data []byte) []byte {
+	chunks := (len(data) + fr32UnpaddedChunk - 1) / fr32UnpaddedChunk
+	src := make([]byte, chunks*fr32UnpaddedChunk)
+	copy(src, data)
+
+	padded := make([]byte, chunks*fr32PaddedChunk)
+	for i := 0; i < chunks; i++ {
+		padChunk(padded[i*fr32PaddedChunk:], src[i*fr32UnpaddedChunk:])
+	}
+
+	return padded
+}
+
+func randData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	return data
+}
+
+func TestUnpadChunk(t *testing.T) {
+	inputs := [][]byte{
+		make([]byte, fr32UnpaddedChunk),
+		bytes.Repeat([]byte{0xff}, fr32UnpaddedChunk),
+		randData(fr32UnpaddedChunk),
+	}
+
+	for i, in := range inputs {
+		padded := make([]byte, fr32PaddedChunk)
+		padChunk(padded, in)
+
+		for e := 0; e < 4; e++ {
+			if padded[32*e+31]&0xc0 != 0 {
+				t.Fatalf("#%d: highest 2 bits of element %d are not zero", i, e)
+			}
+		}
+
+		// the output should be overwritten
+		out := bytes.Repeat([]byte{0xaa}, fr32UnpaddedChunk)
+		unpadChunk(out, padded)
+		if !bytes.Equal(out, in) {
+			t.Fatalf("#%d: unpadded data mismatch:\n got %x\nwant %x", i, out, in)
+		}
+	}
+}
+
+func TestPaddedRange(t *testing.T) {
+	cases := []struct {
+		offset, size       int64
+		padOffset, padSize int64
+	}{
+		{0, 0, 0, 0},
+		{0, 1, 0, 128},
+		{0, 127, 0, 128},
+		{0, 128, 0, 256},
+		{126, 2, 0, 256},
+		{127, 127, 128, 128},
+		{200, 10, 128, 128},
+		{254, 254, 256, 256},
+		{1000, 1000, 896, 1152},
+	}
+
+	for _, c := range cases {
+		offset, size := paddedRange(c.offset, c.size)
+		if offset != c.padOffset || size != c.padSize {
+			t.Errorf("paddedRange(%d, %d) = (%d, %d), want (%d, %d)", c.offset, c.size, offset, size, c.padOffset, c.padSize)
+		}
+	}
+}
+
+func TestUnpadReader(t *testing.T) {
+	// more than one batch of the chunks read at a time
+	data := randData((fr32ReadChunks*2 + 10) * fr32UnpaddedChunk)
+	padded := padData(data)
+
+	ranges := []struct {
+		offset, size int64
+	}{
+		{0, 1},
+		{0, int64(len(data))},
+		{5, 300},
+		{127, 127},
+		{126, 2},
+		{fr32ReadChunks*fr32UnpaddedChunk - 3, 10},
+		{int64(len(data)) - 1000, 1000},
+		{int64(len(data)) - 1, 1},
+	}
+
+	for _, r := range ranges {
+		offset, size := paddedRange(r.offset, r.size)
+		src := io.NopCloser(bytes.NewReader(padded[offset : offset+size]))
+
+		got, err := io.ReadAll(newUnpadReader(src, r.offset, r.size))
+		if err != nil {
+			t.Fatalf("read range (%d, %d): %s", r.offset, r.size, err)
+		}
+
+		if want := data[r.offset : r.offset+r.size]; !bytes.Equal(got, want) {
+			t.Fatalf("range (%d, %d): data mismatch, got %d bytes, want %d bytes", r.offset, r.size, len(got), len(want))
+		}
+	}
+}
+
+func TestUnpadReaderTruncated(t *testing.T) {
+	data := randData(10 * fr32UnpaddedChunk)
+	padded := padData(data)
+
+	// the last chunk is incomplete
+	src := io.NopCloser(bytes.NewReader(padded[:len(padded)-1]))
+	got, err := io.ReadAll(newUnpadReader(src, 0, int64(len(data))))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+	}
+
+	if want := data[:9*fr32UnpaddedChunk]; !bytes.Equal(got, want) {
+		t.Fatalf("data mismatch before the truncation, got %d bytes, want %d bytes", len(got), len(want))
+	}
+}
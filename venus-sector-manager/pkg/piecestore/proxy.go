@@ -1,33 +1,74 @@
 package piecestore
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
 
+	"github.com/filecoin-project/go-jsonrpc/auth"
 	"github.com/ipfs/go-cid"
 
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/api"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/logging"
 	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/market"
-	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore/filestore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/rpcauth"
 )
 
 var log = logging.New("piecestore")
 
-func NewProxy(locals []*filestore.Store, mapi market.API) *Proxy {
+const contentTypeOctetStream = "application/octet-stream"
+
+// clients are asked to retry after this many seconds while the piece is being unsealed
+const unsealRetryAfter = 60
+
+// UnsealedLocator finds the pieces in the unsealed files of the sectors
+type UnsealedLocator interface {
+	Locate(ctx context.Context, c cid.Cid, unseal bool) (*api.UnsealedPiece, error)
+}
+
+// NewProxy creates the piece store proxy, unsealed could be nil if the unsealed files should not be served.
+// Only the requests with an admin token are allowed to start unsealing, which requires the signer,
+// the others are served with the existing unsealed files.
+func NewProxy(stores []objstore.Store, mapi market.API, unsealed UnsealedLocator, signer *rpcauth.Signer) *Proxy {
 	return &Proxy{
-		locals: locals,
-		market: mapi,
+		stores:   stores,
+		market:   mapi,
+		unsealed: unsealed,
+		signer:   signer,
 	}
 }
 
 type Proxy struct {
-	locals []*filestore.Store
-	market market.API
+	stores   []objstore.Store
+	market   market.API
+	unsealed UnsealedLocator
+	signer   *rpcauth.Signer
 }
 
 func (p *Proxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	if req.Method != http.MethodGet {
+	if p.signer == nil {
+		p.servePiece(rw, req)
+		return
+	}
+
+	// the permissions of the token, if any, are attached to the request context
+	hdl := &auth.Handler{
+		Verify: p.signer.Verify,
+		Next:   p.servePiece,
+	}
+
+	hdl.ServeHTTP(rw, req)
+}
+
+func (p *Proxy) servePiece(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
 		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		return
 	}
@@ -39,16 +80,319 @@ func (p *Proxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	for _, store := range p.locals {
-		if r, err := store.Get(req.Context(), path); err == nil {
-			defer r.Close()
-			_, err := io.Copy(rw, r)
-			if err != nil {
-				log.Warnw("transfer piece data for %s: %s", path, err)
-			}
+	for _, store := range p.stores {
+		if stat, err := store.Stat(req.Context(), path); err == nil {
+			p.serve(rw, req, objectSource{store: store, path: path}, c, stat.Size)
 			return
 		}
 	}
 
+	if p.serveUnsealed(rw, req, c) {
+		return
+	}
+
 	http.Redirect(rw, req, p.market.PieceResourceURL(c), http.StatusFound)
 }
+
+// serveUnsealed serves the piece from the unsealed file of the sector, or asks the client to retry later if an unseal job is in progress.
+// false is returned if the piece is not found in any of the sectors, or can not be unsealed.
+// The anonymous requests neither start unsealing, nor rebuild the piece index on a miss.
+func (p *Proxy) serveUnsealed(rw http.ResponseWriter, req *http.Request, c cid.Cid) bool {
+	if p.unsealed == nil {
+		return false
+	}
+
+	unseal := p.signer != nil && auth.HasPerm(req.Context(), nil, rpcauth.PermAdmin)
+	piece, err := p.unsealed.Locate(req.Context(), c, unseal)
+	if err != nil {
+		log.Warnw("locate unsealed piece", "piece", c.String(), "err", err)
+		return false
+	}
+
+	if piece == nil {
+		return false
+	}
+
+	if piece.Store != nil {
+		p.serve(rw, req, unsealedSource{piece: piece}, c, piece.DataSize)
+		return true
+	}
+
+	if piece.Job != nil {
+		header := rw.Header()
+		header.Set("Retry-After", strconv.Itoa(unsealRetryAfter))
+		header.Set("X-Unseal-Job", piece.Job.ID)
+		http.Error(rw, fmt.Sprintf("piece is being unsealed in job %s, progress %d%%", piece.Job.ID, piece.Job.Progress), http.StatusServiceUnavailable)
+		return true
+	}
+
+	return false
+}
+
+// serve writes the piece data, or the requested ranges of it.
+// The piece cid is used as the strong ETag, since the content of a piece never changes,
+// so that an interrupted download can be resumed with Range & If-Range.
+func (p *Proxy) serve(rw http.ResponseWriter, req *http.Request, src source, c cid.Cid, size int64) {
+	path := req.URL.Path
+	etag := `"` + c.String() + `"`
+
+	header := rw.Header()
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("ETag", etag)
+
+	if etagMatch(req.Header.Get("If-None-Match"), etag) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rangeHeader := req.Header.Get("Range")
+	// a date or a different etag means the client holds another content, the whole piece should be sent
+	if ifRange := req.Header.Get("If-Range"); ifRange != "" && ifRange != etag {
+		rangeHeader = ""
+	}
+
+	ranges, err := parseRange(rangeHeader, size)
+	if err != nil {
+		header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(rw, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	var total int64
+	for _, r := range ranges {
+		total += r.Size
+	}
+
+	// it is cheaper to send the whole piece in this case
+	if total > size {
+		ranges = nil
+	}
+
+	switch len(ranges) {
+	case 0:
+		p.serveFull(rw, req, src, size)
+
+	case 1:
+		header.Set("Content-Type", contentTypeOctetStream)
+		header.Set("Content-Range", contentRange(ranges[0], size))
+		header.Set("Content-Length", strconv.FormatInt(ranges[0].Size, 10))
+		if req.Method == http.MethodHead {
+			rw.WriteHeader(http.StatusPartialContent)
+			return
+		}
+
+		results, ok := openChunks(rw, req, src, ranges)
+		if !ok {
+			return
+		}
+
+		defer closeChunks(results)
+
+		rw.WriteHeader(http.StatusPartialContent)
+		if _, err := io.Copy(rw, results[0]); err != nil {
+			log.Warnw("transfer piece data", "path", path, "range", header.Get("Content-Range"), "err", err)
+		}
+
+	default:
+		p.serveMultiRange(rw, req, src, ranges, size)
+	}
+}
+
+func (p *Proxy) serveFull(rw http.ResponseWriter, req *http.Request, src source, size int64) {
+	path := req.URL.Path
+	header := rw.Header()
+	header.Set("Content-Type", contentTypeOctetStream)
+	header.Set("Content-Length", strconv.FormatInt(size, 10))
+	if req.Method == http.MethodHead {
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	r, err := src.open(req.Context(), size)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("open piece %s: %s", path, err), http.StatusInternalServerError)
+		return
+	}
+
+	defer r.Close()
+
+	rw.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(rw, r); err != nil {
+		log.Warnw("transfer piece data", "path", path, "err", err)
+	}
+}
+
+// serveMultiRange sends the ranges as a multipart/byteranges body
+func (p *Proxy) serveMultiRange(rw http.ResponseWriter, req *http.Request, src source, ranges []objstore.Range, size int64) {
+	path := req.URL.Path
+
+	// write the multipart skeleton into a counter first to get the content length
+	var counter countingWriter
+	mw := multipart.NewWriter(&counter)
+	for _, r := range ranges {
+		if _, err := mw.CreatePart(partHeader(r, size)); err != nil {
+			http.Error(rw, fmt.Sprintf("build multipart body: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		counter += countingWriter(r.Size)
+	}
+
+	if err := mw.Close(); err != nil {
+		http.Error(rw, fmt.Sprintf("build multipart body: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	boundary := mw.Boundary()
+	header := rw.Header()
+	header.Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+	header.Set("Content-Length", strconv.FormatInt(int64(counter), 10))
+	if req.Method == http.MethodHead {
+		rw.WriteHeader(http.StatusPartialContent)
+		return
+	}
+
+	results, ok := openChunks(rw, req, src, ranges)
+	if !ok {
+		return
+	}
+
+	defer closeChunks(results)
+
+	rw.WriteHeader(http.StatusPartialContent)
+
+	mw = multipart.NewWriter(rw)
+	if err := mw.SetBoundary(boundary); err != nil {
+		log.Warnw("set multipart boundary", "path", path, "err", err)
+		return
+	}
+
+	for i, r := range ranges {
+		pw, err := mw.CreatePart(partHeader(r, size))
+		if err != nil {
+			log.Warnw("create multipart part", "path", path, "err", err)
+			return
+		}
+
+		if _, err := io.Copy(pw, results[i]); err != nil {
+			log.Warnw("transfer piece data", "path", path, "range", contentRange(r, size), "err", err)
+			return
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		log.Warnw("finish multipart body", "path", path, "err", err)
+	}
+}
+
+// openChunks opens all the ranges, an error response will be written if any of them fails
+func openChunks(rw http.ResponseWriter, req *http.Request, src source, ranges []objstore.Range) ([]objstore.ReaderResult, bool) {
+	path := req.URL.Path
+	results, err := src.openChunks(req.Context(), ranges)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("open piece %s: %s", path, err), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	for i := range results {
+		if results[i].Err != nil {
+			closeChunks(results)
+			http.Error(rw, fmt.Sprintf("open piece %s at offset %d: %s", path, ranges[i].Offset, results[i].Err), http.StatusInternalServerError)
+			return nil, false
+		}
+	}
+
+	return results, true
+}
+
+// source is where the piece data is read from
+type source interface {
+	open(ctx context.Context, size int64) (io.ReadCloser, error)
+	openChunks(ctx context.Context, ranges []objstore.Range) ([]objstore.ReaderResult, error)
+}
+
+// objectSource is a piece file in the piece store
+type objectSource struct {
+	store objstore.Store
+	path  string
+}
+
+func (s objectSource) open(ctx context.Context, size int64) (io.ReadCloser, error) {
+	return s.store.Get(ctx, s.path)
+}
+
+func (s objectSource) openChunks(ctx context.Context, ranges []objstore.Range) ([]objstore.ReaderResult, error) {
+	return s.store.GetChunks(ctx, s.path, ranges)
+}
+
+// unsealedSource is a piece in the unsealed file of a sector, which is fr32 padded
+type unsealedSource struct {
+	piece *api.UnsealedPiece
+}
+
+func (s unsealedSource) open(ctx context.Context, size int64) (io.ReadCloser, error) {
+	results, err := s.openChunks(ctx, []objstore.Range{{Offset: 0, Size: size}})
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0].ReadCloser, results[0].Err
+}
+
+func (s unsealedSource) openChunks(ctx context.Context, ranges []objstore.Range) ([]objstore.ReaderResult, error) {
+	padded := make([]objstore.Range, len(ranges))
+	for i, r := range ranges {
+		offset, size := paddedRange(r.Offset, r.Size)
+		padded[i] = objstore.Range{
+			Offset: int64(s.piece.Offset) + offset,
+			Size:   size,
+		}
+	}
+
+	results, err := s.piece.Store.GetChunks(ctx, s.piece.Path, padded)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].ReadCloser = newUnpadReader(results[i].ReadCloser, ranges[i].Offset, ranges[i].Size)
+		}
+	}
+
+	return results, nil
+}
+
+func closeChunks(results []objstore.ReaderResult) {
+	for i := range results {
+		if results[i].Err == nil && results[i].ReadCloser != nil {
+			results[i].Close()
+		}
+	}
+}
+
+func partHeader(r objstore.Range, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {contentRange(r, size)},
+		"Content-Type":  {contentTypeOctetStream},
+	}
+}
+
+// etagMatch checks the If-None-Match header, weak comparison is used as per RFC 7232
+func etagMatch(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
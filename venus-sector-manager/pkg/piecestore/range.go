@@ -0,0 +1,109 @@
+package piecestore
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+// too many ranges in one request is more likely an abuse than a real need
+const maxRanges = 32
+
+var errNoOverlap = errors.New("invalid range: failed to overlap")
+
+// parseRange parses a Range header string as per RFC 7233, an empty header results in nil ranges.
+// errNoOverlap is returned if none of the ranges overlaps the content.
+func parseRange(s string, size int64) ([]objstore.Range, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) {
+		return nil, errors.New("invalid range")
+	}
+
+	var ranges []objstore.Range
+	noOverlap := false
+	for _, ra := range strings.Split(s[len(b):], ",") {
+		ra = strings.TrimSpace(ra)
+		if ra == "" {
+			continue
+		}
+
+		i := strings.Index(ra, "-")
+		if i < 0 {
+			return nil, errors.New("invalid range")
+		}
+
+		start, end := strings.TrimSpace(ra[:i]), strings.TrimSpace(ra[i+1:])
+		var r objstore.Range
+		if start == "" {
+			// suffix-length, the last n bytes
+			if end == "" || end[0] == '-' {
+				return nil, errors.New("invalid range")
+			}
+
+			n, err := strconv.ParseInt(end, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errors.New("invalid range")
+			}
+
+			if n == 0 {
+				noOverlap = true
+				continue
+			}
+
+			if n > size {
+				n = size
+			}
+
+			r.Offset = size - n
+			r.Size = n
+		} else {
+			offset, err := strconv.ParseInt(start, 10, 64)
+			if err != nil || offset < 0 {
+				return nil, errors.New("invalid range")
+			}
+
+			if offset >= size {
+				noOverlap = true
+				continue
+			}
+
+			r.Offset = offset
+			if end == "" {
+				r.Size = size - offset
+			} else {
+				last, err := strconv.ParseInt(end, 10, 64)
+				if err != nil || offset > last {
+					return nil, errors.New("invalid range")
+				}
+
+				if last >= size {
+					last = size - 1
+				}
+
+				r.Size = last - offset + 1
+			}
+		}
+
+		ranges = append(ranges, r)
+		if len(ranges) > maxRanges {
+			return nil, fmt.Errorf("too many ranges, at most %d allowed", maxRanges)
+		}
+	}
+
+	if noOverlap && len(ranges) == 0 {
+		return nil, errNoOverlap
+	}
+
+	return ranges, nil
+}
+
+func contentRange(r objstore.Range, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Offset, r.Offset+r.Size-1, size)
+}
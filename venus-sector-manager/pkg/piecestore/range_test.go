@@ -0,0 +1,92 @@
+package piecestore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ipfs-force-community/venus-cluster/venus-sector-manager/pkg/objstore"
+)
+
+func TestParseRange(t *testing.T) {
+	const size = 1000
+
+	cases := []struct {
+		header   string
+		expected []objstore.Range
+		err      error
+		invalid  bool
+	}{
+		{header: "", expected: nil},
+		{header: "bytes=0-499", expected: []objstore.Range{{Offset: 0, Size: 500}}},
+		{header: "bytes=500-", expected: []objstore.Range{{Offset: 500, Size: 500}}},
+		{header: "bytes=-200", expected: []objstore.Range{{Offset: 800, Size: 200}}},
+		{header: "bytes=900-1999", expected: []objstore.Range{{Offset: 900, Size: 100}}},
+		{header: "bytes=-2000", expected: []objstore.Range{{Offset: 0, Size: 1000}}},
+		{header: "bytes=999-999", expected: []objstore.Range{{Offset: 999, Size: 1}}},
+		{header: "bytes=0-0, 10-19, -5", expected: []objstore.Range{{Offset: 0, Size: 1}, {Offset: 10, Size: 10}, {Offset: 995, Size: 5}}},
+		{header: "bytes= 1 - 2 ,,", expected: []objstore.Range{{Offset: 1, Size: 2}}},
+
+		// the unsatisfiable ones are skipped if any other one overlaps
+		{header: "bytes=1000-, 0-9", expected: []objstore.Range{{Offset: 0, Size: 10}}},
+		{header: "bytes=1000-", err: errNoOverlap},
+		{header: "bytes=-0", err: errNoOverlap},
+		{header: "bytes=1000-1999, -0", err: errNoOverlap},
+
+		{header: "items=0-9", invalid: true},
+		{header: "bytes=10", invalid: true},
+		{header: "bytes=10-5", invalid: true},
+		{header: "bytes=a-9", invalid: true},
+		{header: "bytes=0-b", invalid: true},
+		{header: "bytes=-", invalid: true},
+		{header: "bytes=--5", invalid: true},
+		{header: "bytes=-1-5", invalid: true},
+		{header: "bytes=" + strings.Repeat("0-0,", maxRanges+1), invalid: true},
+	}
+
+	for _, c := range cases {
+		got, err := parseRange(c.header, size)
+		switch {
+		case c.err != nil:
+			if !errors.Is(err, c.err) {
+				t.Errorf("%q: got error %v, expected %v", c.header, err, c.err)
+			}
+
+		case c.invalid:
+			if err == nil || errors.Is(err, errNoOverlap) {
+				t.Errorf("%q: got %v, %v, expected an invalid range error", c.header, got, err)
+			}
+
+		case err != nil:
+			t.Errorf("%q: unexpected error: %s", c.header, err)
+
+		case len(got) != len(c.expected):
+			t.Errorf("%q: got %v, expected %v", c.header, got, c.expected)
+
+		default:
+			for i := range got {
+				if got[i] != c.expected[i] {
+					t.Errorf("%q: got %v, expected %v", c.header, got, c.expected)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestContentRange(t *testing.T) {
+	cases := []struct {
+		rg       objstore.Range
+		size     int64
+		expected string
+	}{
+		{objstore.Range{Offset: 0, Size: 500}, 1000, "bytes 0-499/1000"},
+		{objstore.Range{Offset: 999, Size: 1}, 1000, "bytes 999-999/1000"},
+	}
+
+	for _, c := range cases {
+		if got := contentRange(c.rg, c.size); got != c.expected {
+			t.Errorf("%v of %d: got %q, expected %q", c.rg, c.size, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package rpcauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/gbrlsnchs/jwt/v3"
+)
+
+const (
+	PermRead   auth.Permission = "read"
+	PermWorker auth.Permission = "worker"
+	PermSign   auth.Permission = "sign"
+	PermAdmin  auth.Permission = "admin"
+)
+
+// AllPermissions are ordered from the lowest to the highest, each one implies all the lower ones
+var AllPermissions = []auth.Permission{PermRead, PermWorker, PermSign, PermAdmin}
+
+// DefaultPermissions are granted to the requests without a token
+var DefaultPermissions = []auth.Permission{PermRead}
+
+const (
+	SecretFileName = "jwt-secret"
+	TokenFileName  = "token"
+
+	secretSize = 32
+)
+
+// Expand returns the given permission along with all the ones it implies
+func Expand(perm auth.Permission) ([]auth.Permission, error) {
+	for i := range AllPermissions {
+		if AllPermissions[i] == perm {
+			return AllPermissions[:i+1], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown permission %q", perm)
+}
+
+// Payload follows the layout of the tokens issued by venus-auth, with the optional registered claims, e.g. exp
+type Payload struct {
+	jwt.Payload
+	Name  string          `json:"name"`
+	Perm  auth.Permission `json:"perm"`
+	Extra string          `json:"ext"`
+}
+
+func NewSigner(secret []byte) *Signer {
+	return &Signer{
+		alg: jwt.NewHS256(secret),
+	}
+}
+
+// Signer issues and verifies the HS256 signed jwt tokens
+type Signer struct {
+	alg *jwt.HMACSHA
+}
+
+func (s *Signer) Sign(payload Payload) (string, error) {
+	if _, err := Expand(payload.Perm); err != nil {
+		return "", err
+	}
+
+	token, err := jwt.Sign(payload, s.alg)
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+
+	return string(token), nil
+}
+
+// Verify is used as the auth.Handler.Verify
+func (s *Signer) Verify(ctx context.Context, token string) ([]auth.Permission, error) {
+	var payload Payload
+	if _, err := jwt.Verify(
+		[]byte(token),
+		s.alg,
+		&payload,
+		jwt.ValidateHeader,
+		jwt.ValidatePayload(&payload.Payload, expirationValidator(time.Now())),
+	); err != nil {
+		return nil, fmt.Errorf("verify token: %w", err)
+	}
+
+	return Expand(payload.Perm)
+}
+
+// expirationValidator rejects the expired tokens, unlike jwt.ExpirationTimeValidator, tokens without exp never expire
+func expirationValidator(now time.Time) jwt.Validator {
+	return func(pl *jwt.Payload) error {
+		if pl.ExpirationTime != nil && now.After(pl.ExpirationTime.Time) {
+			return jwt.ErrExpValidation
+		}
+
+		return nil
+	}
+}
+
+// LoadSigner reads the secret from the given dir
+func LoadSigner(dir string) (*Signer, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, SecretFileName))
+	if err != nil {
+		return nil, fmt.Errorf("read secret: %w", err)
+	}
+
+	secret, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decode secret: %w", err)
+	}
+
+	return NewSigner(secret), nil
+}
+
+// Init generates the secret for the given dir if it does not exist yet, and writes an admin token along with it.
+// An existing token file is kept as long as it is still valid for the secret in use.
+func Init(dir string) (*Signer, error) {
+	secretPath := filepath.Join(dir, SecretFileName)
+	_, err := os.Stat(secretPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("stat secret: %w", err)
+	}
+
+	if os.IsNotExist(err) {
+		secret := make([]byte, secretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("generate secret: %w", err)
+		}
+
+		if err := ioutil.WriteFile(secretPath, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+			return nil, fmt.Errorf("write secret: %w", err)
+		}
+	}
+
+	signer, err := LoadSigner(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := LoadToken(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != "" {
+		if _, err := signer.Verify(context.Background(), existing); err == nil {
+			return signer, nil
+		}
+	}
+
+	token, err := signer.Sign(Payload{
+		Name: "local",
+		Perm: PermAdmin,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign admin token: %w", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, TokenFileName), []byte(token), 0600); err != nil {
+		return nil, fmt.Errorf("write admin token: %w", err)
+	}
+
+	return signer, nil
+}
+
+// LoadToken reads the local admin token from the given dir, an empty string will be returned if it does not exist
+func LoadToken(dir string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, TokenFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("read token: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Protect wraps the http handler, the requests will be rejected unless their tokens grant the permission returned by need.
+// Unlike the rpc calls, no permission is granted to the requests without a token.
+func Protect(signer *Signer, next http.Handler, need func(*http.Request) auth.Permission) http.Handler {
+	return &auth.Handler{
+		Verify: signer.Verify,
+		Next: func(rw http.ResponseWriter, req *http.Request) {
+			if !auth.HasPerm(req.Context(), nil, need(req)) {
+				http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(rw, req)
+		},
+	}
+}
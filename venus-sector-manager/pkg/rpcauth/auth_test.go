@@ -0,0 +1,192 @@
+package rpcauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/gbrlsnchs/jwt/v3"
+)
+
+func TestSignerPermissions(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+
+	cases := []struct {
+		perm     auth.Permission
+		expected []auth.Permission
+	}{
+		{PermRead, []auth.Permission{PermRead}},
+		{PermWorker, []auth.Permission{PermRead, PermWorker}},
+		{PermSign, []auth.Permission{PermRead, PermWorker, PermSign}},
+		{PermAdmin, []auth.Permission{PermRead, PermWorker, PermSign, PermAdmin}},
+	}
+
+	for _, c := range cases {
+		token, err := signer.Sign(Payload{Name: "test", Perm: c.perm})
+		if err != nil {
+			t.Fatalf("sign token with %s: %s", c.perm, err)
+		}
+
+		perms, err := signer.Verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("verify token with %s: %s", c.perm, err)
+		}
+
+		if len(perms) != len(c.expected) {
+			t.Fatalf("token with %s: got %v, expected %v", c.perm, perms, c.expected)
+		}
+
+		for i := range perms {
+			if perms[i] != c.expected[i] {
+				t.Fatalf("token with %s: got %v, expected %v", c.perm, perms, c.expected)
+			}
+		}
+	}
+
+	if _, err := signer.Sign(Payload{Name: "test", Perm: "root"}); err == nil {
+		t.Fatal("expected an error for the unknown permission")
+	}
+}
+
+func TestSignerVerify(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	now := time.Now()
+
+	sign := func(s *Signer, payload Payload) string {
+		token, err := s.Sign(payload)
+		if err != nil {
+			t.Fatalf("sign token: %s", err)
+		}
+
+		return token
+	}
+
+	valid := sign(signer, Payload{Name: "test", Perm: PermWorker})
+	parts := strings.Split(valid, ".")
+
+	// signed by venus-auth with the same secret, perm: sign
+	venusAuth, err := jwt.Sign(map[string]string{"name": "venus-auth", "perm": "sign", "ext": ""}, jwt.NewHS256([]byte("secret")))
+	if err != nil {
+		t.Fatalf("sign venus-auth token: %s", err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid", valid, true},
+		{"venus-auth", string(venusAuth), true},
+		{"not expired", sign(signer, Payload{Payload: jwt.Payload{ExpirationTime: jwt.NumericDate(now.Add(time.Hour))}, Perm: PermRead}), true},
+		{"expired", sign(signer, Payload{Payload: jwt.Payload{ExpirationTime: jwt.NumericDate(now.Add(-time.Hour))}, Perm: PermRead}), false},
+		{"other secret", sign(NewSigner([]byte("other")), Payload{Name: "test", Perm: PermWorker}), false},
+		{"tampered payload", parts[0] + "." + strings.Split(sign(signer, Payload{Name: "test", Perm: PermAdmin}), ".")[1] + "." + parts[2], false},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), false},
+		{"none alg", "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." + parts[1] + ".", false},
+		{"malformed", "not-a-token", false},
+	}
+
+	for _, c := range cases {
+		_, err := signer.Verify(context.Background(), c.token)
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", c.name, err)
+		}
+
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+
+	signer, err := Init(dir)
+	if err != nil {
+		t.Fatalf("init: %s", err)
+	}
+
+	token, err := LoadToken(dir)
+	if err != nil {
+		t.Fatalf("load token: %s", err)
+	}
+
+	perms, err := signer.Verify(context.Background(), token)
+	if err != nil || len(perms) != len(AllPermissions) {
+		t.Fatalf("expected an admin token, got %v, %v", perms, err)
+	}
+
+	// the existing secret & token are kept
+	if _, err := Init(dir); err != nil {
+		t.Fatalf("init again: %s", err)
+	}
+
+	again, err := LoadToken(dir)
+	if err != nil {
+		t.Fatalf("load token again: %s", err)
+	}
+
+	if again != token {
+		t.Fatal("token changed after init again")
+	}
+}
+
+func TestProtect(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	hdl := Protect(signer, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), func(req *http.Request) auth.Permission {
+		if req.Method == http.MethodGet {
+			return PermRead
+		}
+
+		return PermAdmin
+	})
+
+	sign := func(perm auth.Permission) string {
+		token, err := signer.Sign(Payload{Perm: perm})
+		if err != nil {
+			t.Fatalf("sign token: %s", err)
+		}
+
+		return token
+	}
+
+	cases := []struct {
+		method string
+		token  string
+		code   int
+	}{
+		{http.MethodGet, "", http.StatusUnauthorized},
+		{http.MethodGet, sign(PermRead), http.StatusOK},
+		{http.MethodPut, sign(PermSign), http.StatusUnauthorized},
+		{http.MethodPut, sign(PermAdmin), http.StatusOK},
+		{http.MethodPut, signWith(t, "other", PermAdmin), http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/", nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		rec := httptest.NewRecorder()
+		hdl.ServeHTTP(rec, req)
+		if rec.Code != c.code {
+			t.Errorf("%s with token %q: got status %d, expected %d", c.method, c.token, rec.Code, c.code)
+		}
+	}
+}
+
+func signWith(t *testing.T, secret string, perm auth.Permission) string {
+	token, err := NewSigner([]byte(secret)).Sign(Payload{Perm: perm})
+	if err != nil {
+		t.Fatalf("sign token: %s", err)
+	}
+
+	return token
+}
@@ -0,0 +1,127 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DialURL prepares the endpoint for the jsonrpc clients.
+// The jsonrpc clients always dial with the package level websocket.DefaultDialer & http.DefaultClient, and take
+// no option for another one, so for the wss:// & https:// endpoints, a local forwarder using a dedicated tls dialer
+// is started, and the returned url points to it. The returned header keeps the original host for the websocket handshake.
+// The raw url will be returned as it is if conf is nil or the endpoint is not a tls one.
+func DialURL(ctx context.Context, raw string, conf *tls.Config) (string, http.Header, error) {
+	if conf == nil {
+		return raw, nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw, nil, nil
+	}
+
+	var scheme string
+	switch u.Scheme {
+	case "wss":
+		scheme = "ws"
+	case "https":
+		scheme = "http"
+	default:
+		return raw, nil, nil
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	local, err := Forward(ctx, addr, conf)
+	if err != nil {
+		return "", nil, fmt.Errorf("forward to %s: %w", addr, err)
+	}
+
+	header := http.Header{}
+	header.Set("Host", u.Host)
+
+	u.Scheme = scheme
+	u.Host = local
+	return u.String(), header, nil
+}
+
+// Forward listens on a loopback port, and forwards each accepted connection to addr over tls.
+// The listener will be closed once the ctx is done.
+func Forward(ctx context.Context, addr string, conf *tls.Config) (string, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+
+	conf = conf.Clone()
+	if conf.ServerName == "" {
+		conf.ServerName = host
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		Config: conf,
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("listen: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go forward(ctx, dialer, addr, conn)
+		}
+	}()
+
+	return ln.Addr().String(), nil
+}
+
+func forward(ctx context.Context, dialer *tls.Dialer, addr string, local net.Conn) {
+	defer local.Close()
+
+	remote, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return
+	}
+
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local) // nolint: errcheck
+		done <- struct{}{}
+	}()
+
+	go func() {
+		io.Copy(local, remote) // nolint: errcheck
+		done <- struct{}{}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-done:
+	}
+}
@@ -0,0 +1,73 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDialURL(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.Path)) // nolint: errcheck
+	}))
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	conf := &tls.Config{RootCAs: pool}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	unchanged := []string{"/ip4/127.0.0.1/tcp/3453", "ws://127.0.0.1:3453/rpc/v1", "http://127.0.0.1:3453"}
+	for _, raw := range unchanged {
+		got, header, err := DialURL(ctx, raw, conf)
+		if err != nil || got != raw || header != nil {
+			t.Fatalf("%s: expected to be unchanged, got %s, %v, %v", raw, got, header, err)
+		}
+	}
+
+	if got, _, err := DialURL(ctx, ts.URL, nil); err != nil || got != ts.URL {
+		t.Fatalf("expected to be unchanged without tls config, got %s, %v", got, err)
+	}
+
+	local, header, err := DialURL(ctx, ts.URL+"/rpc/v1", conf)
+	if err != nil {
+		t.Fatalf("dial url: %s", err)
+	}
+
+	if !strings.HasPrefix(local, "http://127.0.0.1:") || !strings.HasSuffix(local, "/rpc/v1") {
+		t.Fatalf("unexpected local url %s", local)
+	}
+
+	if host := header.Get("Host"); host != strings.TrimPrefix(ts.URL, "https://") {
+		t.Fatalf("unexpected host header %s", host)
+	}
+
+	resp, err := http.Get(local)
+	if err != nil {
+		t.Fatalf("request through the forwarder: %s", err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "/rpc/v1" {
+		t.Fatalf("unexpected response %q, %v", body, err)
+	}
+
+	// the server certificate is not trusted
+	untrusted, _, err := DialURL(ctx, ts.URL, &tls.Config{RootCAs: x509.NewCertPool()})
+	if err != nil {
+		t.Fatalf("dial url: %s", err)
+	}
+
+	if resp, err := http.Get(untrusted); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the request to fail with the untrusted certificate")
+	}
+}
@@ -0,0 +1,104 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// ServerConfig loads the certificate pair for the http server, nil will be returned if certFile is empty.
+// Client certificates will be required and verified if clientCAFile is set.
+func ServerConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate pair: %w", err)
+	}
+
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pool, err := loadCertPool(x509.NewCertPool(), clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client ca: %w", err)
+		}
+
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return conf, nil
+}
+
+// ClientConfig builds the config for connecting to the tls servers,
+// the CAs in caFile will be trusted along with the system ones.
+// nil will be returned if neither caFile nor certFile is set, the system defaults will be used then.
+func ClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" {
+		return nil, nil
+	}
+
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		sys, err := x509.SystemCertPool()
+		if err != nil || sys == nil {
+			sys = x509.NewCertPool()
+		}
+
+		pool, err := loadCertPool(sys, caFile)
+		if err != nil {
+			return nil, fmt.Errorf("load ca: %w", err)
+		}
+
+		conf.RootCAs = pool
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate pair: %w", err)
+		}
+
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// HTTPClient returns a client with a dedicated transport using the given config,
+// http.DefaultClient will be returned if conf is nil.
+func HTTPClient(conf *tls.Config) *http.Client {
+	if conf == nil {
+		return http.DefaultClient
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = conf.Clone()
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+func loadCertPool(pool *x509.CertPool, caFile string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid certificate found in %s", caFile)
+	}
+
+	return pool, nil
+}
@@ -118,6 +118,8 @@ pub struct RPCClient {
     /// jsonrpc endpoint
     pub addr: String,
     pub headers: Option<HashMap<String, String>>,
+    /// token for the sealer rpc, required by the methods beyond the `read` permission when auth is enabled
+    pub token: Option<String>,
 }
 
 #[derive(Debug, Default, Serialize, Deserialize)]
@@ -152,8 +152,19 @@ pub fn start_deamon(cfg_path: String) -> Result<()> {
         "rpc dial info"
     );
 
+    // the http transport takes no custom headers, so the token is passed as the query parameter
+    let connect_addr = match cfg.sector_manager.rpc_client.token.as_ref() {
+        Some(token) => {
+            let mut u = Url::parse(&dial_addr).context("parse rpc url")?;
+            u.query_pairs_mut().append_pair("token", token);
+            u.to_string()
+        }
+
+        None => dial_addr.clone(),
+    };
+
     let rpc_client = runtime
-        .block_on(async { http::connect(&dial_addr).await })
+        .block_on(async { http::connect(&connect_addr).await })
         .map_err(|e| anyhow!("jsonrpc connect to {}: {:?}", &dial_addr, e))?;
 
     let instance = if let Some(name) = cfg.worker.as_ref().and_then(|s| s.name.as_ref()).cloned() {